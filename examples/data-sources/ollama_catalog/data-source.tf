@@ -0,0 +1,17 @@
+# catalog.yaml:
+#
+# roles:
+#   chat-default:
+#     prod:    { model: "llama3:70b", digest: "sha256:..." }
+#     default: { model: "llama3:8b" }
+#   embed-small:
+#     default: { model: "nomic-embed-text" }
+data "ollama_catalog" "models" {
+  file        = "${path.module}/catalog.yaml"
+  environment = var.environment
+  roles       = ["chat-default", "embed-small"]
+}
+
+resource "ollama_model" "chat" {
+  name = data.ollama_catalog.models.models["chat-default"]
+}
@@ -0,0 +1,13 @@
+data "ollama_determinism_check" "fleet" {
+  hosts = [
+    "http://gpu-1:11434",
+    "http://gpu-2:11434",
+    "http://gpu-3:11434",
+  ]
+  model   = "llama3"
+  prompts = ["Name the capital of France.", "Count from 1 to 10."]
+}
+
+output "deviating_hosts" {
+  value = data.ollama_determinism_check.fleet.deviating_hosts
+}
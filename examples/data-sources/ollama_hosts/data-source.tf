@@ -0,0 +1,23 @@
+# inventory.yaml:
+#
+# hosts:
+#   - url: http://gpu-1:11434
+#     labels: { pool: embeddings, zone: eu-west-1a }
+#   - url: http://gpu-2:11434
+#     labels: { pool: embeddings, zone: eu-west-1b }
+data "ollama_hosts" "embeddings" {
+  inventory_file = "${path.module}/inventory.yaml"
+  labels         = { pool = "embeddings" }
+  health_check   = true
+}
+
+data "ollama_hosts" "autoscaled" {
+  srv          = "_ollama._tcp.inference.example.com"
+  health_check = true
+}
+
+resource "ollama_embedding_evaluation" "candidate" {
+  model        = "mxbai-embed-large"
+  dataset_file = "${path.module}/retrieval.json"
+  hosts        = data.ollama_hosts.embeddings.hosts
+}
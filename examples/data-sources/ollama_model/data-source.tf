@@ -1,2 +1,11 @@
 data "ollama_model" "this" {
-}
\ No newline at end of file
+}
+
+# Classify the license of every model, e.g. to list those that need review.
+data "ollama_model" "licensed" {
+  classify_licenses = true
+}
+
+output "unclassified_models" {
+  value = [for m in data.ollama_model.licensed.models : m.name if m.license.spdx_id == "NOASSERTION"]
+}
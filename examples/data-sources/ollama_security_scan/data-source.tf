@@ -0,0 +1,16 @@
+data "ollama_security_scan" "candidate" {
+  path               = "${path.module}/artifacts/mistral-7b-instruct"
+  severity_threshold = "medium"
+  enforce            = true
+}
+
+resource "ollama_custom_model" "reviewer" {
+  name = "reviewer"
+  from = "./artifacts/reviewer.gguf"
+
+  security_scan = {
+    severity_threshold = "high"
+  }
+
+  depends_on = [data.ollama_security_scan.candidate]
+}
@@ -0,0 +1,12 @@
+resource "ollama_batch_inference" "sentiment" {
+  model           = "llama3"
+  input_file      = "${path.module}/reviews.jsonl"
+  output_file     = "${path.module}/reviews.labeled.jsonl"
+  prompt_template = "Answer with positive, negative or neutral. Review: {{ .text }}"
+  keep_alive      = "30m"
+
+  options = {
+    temperature = "0"
+    seed        = "42"
+  }
+}
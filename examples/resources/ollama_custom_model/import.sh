@@ -0,0 +1,10 @@
+# Models created by hand with `ollama create` can be imported by name.
+terraform import ollama_custom_model.this mario
+
+# Models created from a local GGUF file are imported with the path of the
+# file, so that `from` keeps naming it.
+terraform import ollama_custom_model.this mario,./mario.gguf
+
+# Adapters follow the path of the file, or an empty path for a model created
+# from another model, so that `adapters` keeps naming the local files.
+terraform import ollama_custom_model.this mario,,./mario.safetensors
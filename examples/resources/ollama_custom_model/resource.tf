@@ -0,0 +1,14 @@
+resource "ollama_custom_model" "this" {
+  name   = "mario"
+  from   = "llama3"
+  system = "You are Mario from Super Mario Bros."
+
+  parameters = {
+    temperature = "1"
+  }
+}
+
+resource "ollama_custom_model" "from_modelfile" {
+  name      = "luigi"
+  modelfile = file("${path.module}/Modelfile")
+}
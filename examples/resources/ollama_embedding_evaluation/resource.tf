@@ -0,0 +1,14 @@
+resource "ollama_embedding_evaluation" "candidate" {
+  model          = "mxbai-embed-large"
+  baseline_model = "nomic-embed-text"
+  dataset_file   = "${path.module}/retrieval.json"
+  k              = 5
+
+  hosts = [
+    "http://gpu-1:11434",
+    "http://gpu-2:11434",
+  ]
+
+  min_recall_at_k = 0.8
+  max_regression  = 0.02
+}
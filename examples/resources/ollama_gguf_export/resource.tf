@@ -0,0 +1,6 @@
+# Writes export/llama3.gguf, export/llama3.template and export/llama3.params.json
+resource "ollama_gguf_export" "llama3" {
+  model       = "llama3"
+  output_file = "${path.module}/export/llama3.gguf"
+  hardlink    = true
+}
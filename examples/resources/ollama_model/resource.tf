@@ -1,3 +1,9 @@
 resource "ollama_model" "this" {
   name = "mistral:7b"
-}
\ No newline at end of file
+}
+
+# Keep the three most recent versions of a moving tag for rollback.
+resource "ollama_model" "snapshotted" {
+  name               = "llama3:latest"
+  snapshot_retention = 3
+}
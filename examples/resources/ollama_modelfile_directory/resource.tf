@@ -0,0 +1,5 @@
+# models/reviewer.Modelfile becomes team/reviewer, models/summarizer.Modelfile team/summarizer
+resource "ollama_modelfile_directory" "team" {
+  directory = "${path.module}/models"
+  namespace = "team"
+}
@@ -0,0 +1,249 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+// Package debugbundle implements the debug-bundle subcommand, which collects
+// diagnostics about the provider and an Ollama host into a tarball that can
+// be attached to bug reports.
+package debugbundle
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-provider-ollama/internal/provider"
+	"github.com/ollama/ollama/api"
+)
+
+const defaultLogLines = 2000
+
+// Options configures which diagnostics are collected.
+type Options struct {
+	ProviderVersion string
+	Host            string
+	Models          []string
+	LogFile         string
+	LogLines        int
+	Output          string
+}
+
+// Summary is written to summary.json in the bundle.
+type Summary struct {
+	ProviderVersion    string    `json:"provider_version"`
+	GoVersion          string    `json:"go_version"`
+	Platform           string    `json:"platform"`
+	Host               string    `json:"host"`
+	ServerVersion      string    `json:"server_version,omitempty"`
+	HeartbeatLatencyMs int64     `json:"heartbeat_latency_ms,omitempty"`
+	CollectedAt        time.Time `json:"collected_at"`
+	Errors             []string  `json:"errors,omitempty"`
+}
+
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// Run parses the subcommand arguments, writes the bundle and prints its path.
+func Run(ctx context.Context, providerVersion string, args []string) error {
+	opts := Options{ProviderVersion: providerVersion}
+
+	var models stringList
+	fs := flag.NewFlagSet("debug-bundle", flag.ContinueOnError)
+	fs.StringVar(&opts.Host, "host", os.Getenv("OLLAMA_HOST"), "Ollama host to collect diagnostics from")
+	fs.Var(&models, "model", "model to include `ollama show` output for, can be repeated")
+	fs.StringVar(&opts.LogFile, "log-file", os.Getenv("TF_LOG_PATH"), "Terraform log file to include the tail of")
+	fs.IntVar(&opts.LogLines, "log-lines", defaultLogLines, "number of log lines to include")
+	fs.StringVar(&opts.Output, "output", fmt.Sprintf("ollama-debug-%s.tar.gz", time.Now().UTC().Format("20060102T150405Z")), "path of the bundle to write")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	opts.Models = models
+
+	if opts.LogLines < 1 {
+		fs.Usage()
+		return fmt.Errorf("-log-lines must be at least 1, got %d", opts.LogLines)
+	}
+	if opts.Host == "" {
+		return fmt.Errorf("-host or OLLAMA_HOST must be set")
+	}
+
+	if err := Write(ctx, opts); err != nil {
+		return err
+	}
+
+	fmt.Println(opts.Output)
+	return nil
+}
+
+// Write collects the diagnostics described by opts into a gzipped tarball at
+// opts.Output. Failures to collect individual items are recorded in the
+// summary rather than aborting the bundle.
+func Write(ctx context.Context, opts Options) error {
+	summary := Summary{
+		ProviderVersion: opts.ProviderVersion,
+		GoVersion:       runtime.Version(),
+		Platform:        runtime.GOOS + "/" + runtime.GOARCH,
+		Host:            Redact(opts.Host),
+		CollectedAt:     time.Now().UTC(),
+	}
+
+	files := map[string][]byte{}
+	addJSON := func(name string, v any) {
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			summary.Errors = append(summary.Errors, fmt.Sprintf("%s: %s", name, err))
+			return
+		}
+		files[name] = []byte(Redact(string(data)))
+	}
+
+	client, _, err := provider.NewClient(opts.Host)
+	if err != nil {
+		summary.Errors = append(summary.Errors, fmt.Sprintf("client: %s", err))
+	} else {
+		collectServer(ctx, client, opts.Models, &summary, addJSON)
+	}
+
+	if opts.LogFile != "" {
+		tail, err := tailFile(opts.LogFile, opts.LogLines)
+		if err != nil {
+			summary.Errors = append(summary.Errors, fmt.Sprintf("log: %s", err))
+		} else {
+			files["provider.log"] = []byte(Redact(tail))
+		}
+	}
+
+	addJSON("summary.json", summary)
+
+	return writeTarball(opts.Output, files)
+}
+
+func collectServer(ctx context.Context, client *api.Client, models []string, summary *Summary, addJSON func(string, any)) {
+	start := time.Now()
+	if err := client.Heartbeat(ctx); err != nil {
+		summary.Errors = append(summary.Errors, fmt.Sprintf("heartbeat: %s", err))
+		return
+	}
+	summary.HeartbeatLatencyMs = time.Since(start).Milliseconds()
+
+	if v, err := client.Version(ctx); err != nil {
+		summary.Errors = append(summary.Errors, fmt.Sprintf("version: %s", err))
+	} else {
+		summary.ServerVersion = v
+	}
+
+	if list, err := client.List(ctx); err != nil {
+		summary.Errors = append(summary.Errors, fmt.Sprintf("list: %s", err))
+	} else {
+		addJSON("models.json", list)
+	}
+
+	for _, m := range models {
+		show, err := client.Show(ctx, &api.ShowRequest{Model: m})
+		if err != nil {
+			summary.Errors = append(summary.Errors, fmt.Sprintf("show %s: %s", m, err))
+			continue
+		}
+		addJSON("show/"+fileName(m)+".json", show)
+	}
+}
+
+var redactions = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	{regexp.MustCompile(`(?i)(bearer|basic)\s+[A-Za-z0-9._~+/=-]+`), "$1 REDACTED"},
+	{regexp.MustCompile(`(?i)("?(?:authorization|password|passwd|secret|token|api[_-]?key)"?\s*[:=]\s*)("[^"]*"|[^\s,}]+)`), "${1}REDACTED"},
+	{regexp.MustCompile(`(://)[^/@\s:]+:[^/@\s]+@`), "${1}REDACTED@"},
+}
+
+// Redact masks credentials such as bearer tokens, passwords, API keys and
+// userinfo in URLs.
+func Redact(s string) string {
+	for _, r := range redactions {
+		s = r.re.ReplaceAllString(s, r.repl)
+	}
+	return s
+}
+
+func tailFile(path string, n int) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	lines := make([]string, 0, n)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(lines) == n {
+			lines = lines[1:]
+		}
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+
+	return strings.Join(lines, "\n") + "\n", nil
+}
+
+func fileName(model string) string {
+	return strings.NewReplacer("/", "_", ":", "_", "\\", "_").Replace(model)
+}
+
+func writeTarball(path string, files map[string][]byte) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	// summary.json first, the rest in a stable order
+	names := []string{"summary.json"}
+	for name := range files {
+		if name != "summary.json" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names[1:])
+
+	now := time.Now()
+	for _, name := range names {
+		data := files[name]
+		hdr := &tar.Header{Name: name, Mode: 0o600, Size: int64(len(data)), ModTime: now}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
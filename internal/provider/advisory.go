@@ -0,0 +1,288 @@
+package provider
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/ollama/ollama/api"
+)
+
+// defaultAdvisoryErrorSeverity is the lowest severity that fails the plan.
+const defaultAdvisoryErrorSeverity = severityCritical
+
+// advisory describes a known vulnerability of the Ollama server. A version is
+// affected if it is at least introduced and below fixed; an empty bound is
+// open.
+type advisory struct {
+	ID          string `json:"id"`
+	Introduced  string `json:"introduced,omitempty"`
+	Fixed       string `json:"fixed,omitempty"`
+	Severity    string `json:"severity"`
+	Description string `json:"description"`
+}
+
+// advisoryDatabase checks the version of the Ollama host against a local
+// advisory file:
+//
+//	{
+//	  "advisories": [
+//	    {"id": "CVE-2024-37032", "fixed": "0.1.34", "severity": "critical", "description": "Path traversal via digest"}
+//	  ]
+//	}
+//
+// The version of a host is read once per run, when the first resource or data
+// source that uses it is planned or read. Advisories at or above errorSeverity
+// fail the plan, others are warnings.
+type advisoryDatabase struct {
+	advisories    []advisory
+	errorSeverity string
+	client        *api.Client
+	host          string
+
+	mu sync.Mutex
+	// checked holds the hosts whose advisories were reported.
+	checked map[string]bool
+}
+
+func loadAdvisoryDatabase(file, errorSeverity string, client *api.Client, host string) (*advisoryDatabase, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var db struct {
+		Advisories []advisory `json:"advisories"`
+	}
+	if err := json.Unmarshal(b, &db); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", file, err)
+	}
+
+	for i, a := range db.Advisories {
+		if a.ID == "" {
+			return nil, fmt.Errorf("advisory %d has no id", i)
+		}
+		if _, ok := severityRank[a.Severity]; !ok {
+			return nil, fmt.Errorf("advisory %s has invalid severity %q, expected low, medium, high or critical", a.ID, a.Severity)
+		}
+		for _, v := range []string{a.Introduced, a.Fixed} {
+			if _, err := parseServerVersion(v); v != "" && err != nil {
+				return nil, fmt.Errorf("advisory %s: %w", a.ID, err)
+			}
+		}
+	}
+
+	if errorSeverity == "" {
+		errorSeverity = defaultAdvisoryErrorSeverity
+	}
+	if _, ok := severityRank[errorSeverity]; !ok {
+		return nil, fmt.Errorf("invalid error_severity %q, expected low, medium, high or critical", errorSeverity)
+	}
+
+	return &advisoryDatabase{advisories: db.Advisories, errorSeverity: errorSeverity, client: client, host: host, checked: map[string]bool{}}, nil
+}
+
+// Check adds the advisories affecting the provider host to diags. They are
+// reported to the first caller only, so a plan lists them once rather than
+// per resource. A nil database is a no-op.
+func (db *advisoryDatabase) Check(ctx context.Context, diags *diag.Diagnostics) {
+	if db == nil {
+		return
+	}
+	db.CheckHost(ctx, db.client, db.host, diags)
+}
+
+// CheckHost is Check for another host, read through client.
+func (db *advisoryDatabase) CheckHost(ctx context.Context, client *api.Client, host string, diags *diag.Diagnostics) {
+	if db == nil {
+		return
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.checked[host] {
+		return
+	}
+	db.checked[host] = true
+
+	version, err := client.Version(ctx)
+	if err != nil {
+		diags.AddWarning("Could not check Ollama server advisories", fmt.Sprintf("Reading the version of %s failed: %s", host, err))
+		return
+	}
+	db.report(ctx, host, version, diags)
+}
+
+// CheckVersion is Check for a host whose version the caller already read.
+func (db *advisoryDatabase) CheckVersion(ctx context.Context, host, version string, diags *diag.Diagnostics) {
+	if db == nil {
+		return
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.checked[host] {
+		return
+	}
+	db.checked[host] = true
+
+	db.report(ctx, host, version, diags)
+}
+
+// report adds the advisories affecting version to diags.
+func (db *advisoryDatabase) report(ctx context.Context, host, version string, diags *diag.Diagnostics) {
+	tflog.Debug(ctx, fmt.Sprintf("ollama host %s runs version %s", host, version))
+
+	for _, a := range db.Affecting(version) {
+		summary := fmt.Sprintf("Ollama server affected by %s", a.ID)
+		detail := fmt.Sprintf("%s runs Ollama %s, which is affected by %s (%s severity): %s", host, version, a.ID, a.Severity, a.Description)
+		if a.Fixed != "" {
+			detail += fmt.Sprintf(" Upgrade to %s or later.", a.Fixed)
+		}
+
+		if severityRank[a.Severity] >= severityRank[db.errorSeverity] {
+			diags.AddError(summary, detail)
+		} else {
+			diags.AddWarning(summary, detail)
+		}
+	}
+}
+
+// Affecting returns the advisories that affect version. Development builds
+// report version 0.0.0 and are not affected by anything.
+func (db *advisoryDatabase) Affecting(version string) []advisory {
+	v, err := parseServerVersion(version)
+	if err != nil || v == (serverVersion{}) {
+		return nil
+	}
+
+	var affected []advisory
+	for _, a := range db.advisories {
+		if a.Introduced != "" {
+			if introduced, _ := parseServerVersion(a.Introduced); v.Compare(introduced) < 0 {
+				continue
+			}
+		}
+		if a.Fixed != "" {
+			if fixed, _ := parseServerVersion(a.Fixed); v.Compare(fixed) >= 0 {
+				continue
+			}
+		}
+		affected = append(affected, a)
+	}
+	return affected
+}
+
+// serverVersion is an Ollama release version such as 0.1.33 or 0.3.0-rc1.
+type serverVersion struct {
+	Major, Minor, Patch int
+	Pre                 string
+}
+
+func parseServerVersion(s string) (serverVersion, error) {
+	var v serverVersion
+
+	core, pre, _ := strings.Cut(strings.TrimPrefix(strings.TrimSpace(s), "v"), "-")
+	v.Pre = pre
+
+	parts := strings.Split(core, ".")
+	if len(parts) > 3 {
+		return v, fmt.Errorf("invalid version %q", s)
+	}
+	fields := []*int{&v.Major, &v.Minor, &v.Patch}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("invalid version %q", s)
+		}
+		*fields[i] = n
+	}
+	return v, nil
+}
+
+// Compare returns -1, 0 or 1 as v is older than, the same as or newer than o.
+// A pre-release is older than its release.
+func (v serverVersion) Compare(o serverVersion) int {
+	for _, d := range []int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d != 0 {
+			if d < 0 {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	switch {
+	case v.Pre == o.Pre:
+		return 0
+	case v.Pre == "":
+		return 1
+	case o.Pre == "":
+		return -1
+	default:
+		return comparePrerelease(v.Pre, o.Pre)
+	}
+}
+
+// comparePrerelease compares pre-release tags part by part, as semver does,
+// so rc9 is older than rc10. Parts are separated by dots and at the boundaries
+// between digits and other characters. Numeric parts compare as numbers and
+// are older than other parts, which compare as strings. A tag that is a prefix
+// of the other is older.
+func comparePrerelease(a, b string) int {
+	pa, pb := prereleaseParts(a), prereleaseParts(b)
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		if c := comparePrereleasePart(pa[i], pb[i]); c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(pa), len(pb))
+}
+
+// prereleaseParts splits a pre-release tag such as "rc10.1" into
+// ["rc", "10", "1"].
+func prereleaseParts(pre string) []string {
+	var parts []string
+	for _, field := range strings.Split(pre, ".") {
+		start := 0
+		for i := 1; i <= len(field); i++ {
+			if i == len(field) || isDigit(field[i]) != isDigit(field[i-1]) {
+				parts = append(parts, field[start:i])
+				start = i
+			}
+		}
+	}
+	return parts
+}
+
+func comparePrereleasePart(a, b string) int {
+	numA, numB := a != "" && isDigit(a[0]), b != "" && isDigit(b[0])
+	switch {
+	case numA && numB:
+		// compare by length first, numbers may not fit an int
+		a, b = strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+		if c := cmp.Compare(len(a), len(b)); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	case numA:
+		return -1
+	case numB:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
@@ -0,0 +1,195 @@
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+)
+
+func TestParseServerVersion(t *testing.T) {
+	tests := map[string]struct {
+		want    serverVersion
+		wantErr bool
+	}{
+		"0.1.33":     {want: serverVersion{0, 1, 33, ""}},
+		"v0.3.0":     {want: serverVersion{0, 3, 0, ""}},
+		" 0.3.0-rc1": {want: serverVersion{0, 3, 0, "rc1"}},
+		"1.2":        {want: serverVersion{1, 2, 0, ""}},
+		"2":          {want: serverVersion{2, 0, 0, ""}},
+		"0.0.0":      {want: serverVersion{}},
+		"1.2.3.4":    {wantErr: true},
+		"1.x.0":      {wantErr: true},
+		"1.-2.0":     {wantErr: true},
+		"":           {wantErr: true},
+	}
+
+	for s, tt := range tests {
+		got, err := parseServerVersion(s)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseServerVersion(%q) error = %v, want error %v", s, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("parseServerVersion(%q) = %+v, want %+v", s, got, tt.want)
+		}
+	}
+}
+
+func TestServerVersionCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"0.1.33", "0.1.33", 0},
+		{"0.1.33", "0.1.34", -1},
+		{"0.1.34", "0.1.33", 1},
+		{"0.2.0", "0.1.99", 1},
+		{"1.0.0", "0.99.99", 1},
+		{"0.1.10", "0.1.9", 1},
+		{"0.3.0-rc1", "0.3.0", -1},
+		{"0.3.0", "0.3.0-rc1", 1},
+		{"0.3.0-rc1", "0.3.0-rc2", -1},
+		{"0.3.0-rc2", "0.3.0-rc1", 1},
+		{"0.3.0-rc9", "0.3.0-rc10", -1},
+		{"0.3.0-rc10", "0.3.0-rc9", 1},
+		{"0.3.0-rc.9", "0.3.0-rc.10", -1},
+		{"0.3.0-rc010", "0.3.0-rc10", 0},
+		{"0.3.0-rc1", "0.3.0-rc1.1", -1},
+		{"0.3.0-1", "0.3.0-alpha", -1},
+		{"0.3.0-alpha", "0.3.0-beta", -1},
+		{"0.3.0-rc99999999999999999999", "0.3.0-rc100000000000000000000", -1},
+		{"0.3.0-rc1", "0.2.9", 1},
+		{"0.3", "0.3.0", 0},
+	}
+
+	for _, tt := range tests {
+		a, err := parseServerVersion(tt.a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := parseServerVersion(tt.b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := a.Compare(b); got != tt.want {
+			t.Errorf("%s.Compare(%s) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func writeAdvisories(t *testing.T, content, errorSeverity string, host string) *advisoryDatabase {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), "advisories.json")
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	client, _, err := NewClient(host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := loadAdvisoryDatabase(file, errorSeverity, client, host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+const testAdvisories = `{"advisories": [
+	{"id": "CVE-2024-37032", "fixed": "0.1.34", "severity": "critical", "description": "Path traversal via digest"},
+	{"id": "GHSA-range", "introduced": "0.2.0", "fixed": "0.3.0", "severity": "medium", "description": "Ranged"},
+	{"id": "GHSA-open", "introduced": "0.3.5", "severity": "low", "description": "Not fixed yet"}
+]}`
+
+func TestAdvisoryDatabaseAffecting(t *testing.T) {
+	db := writeAdvisories(t, testAdvisories, "", "http://127.0.0.1:11434")
+
+	tests := map[string][]string{
+		"0.1.33":    {"CVE-2024-37032"},
+		"0.1.34":    nil,
+		"0.1.34-rc": {"CVE-2024-37032"},
+		"0.2.0-rc1": nil,
+		"0.2.0":     {"GHSA-range"},
+		"0.2.9":     {"GHSA-range"},
+		"0.3.0":     nil,
+		"0.3.5":     {"GHSA-open"},
+		"1.0.0":     {"GHSA-open"},
+		"0.0.0":     nil,
+		"dev":       nil,
+	}
+
+	for version, want := range tests {
+		got := db.Affecting(version)
+		if len(got) != len(want) {
+			t.Errorf("Affecting(%s) = %v, want %v", version, got, want)
+			continue
+		}
+		for i := range got {
+			if got[i].ID != want[i] {
+				t.Errorf("Affecting(%s) = %v, want %v", version, got, want)
+			}
+		}
+	}
+}
+
+func TestAdvisoryDatabaseCheck(t *testing.T) {
+	f, srv, _ := newFakeOllama(t)
+	f.Version = "0.1.33"
+
+	db := writeAdvisories(t, testAdvisories, severityCritical, srv.URL)
+	ctx := context.Background()
+
+	var diags diag.Diagnostics
+	db.Check(ctx, &diags)
+	if !diags.HasError() {
+		t.Fatalf("Check() of an affected host = %v, want error", diags)
+	}
+
+	// reported once per host and run
+	diags = nil
+	db.Check(ctx, &diags)
+	db.CheckVersion(ctx, srv.URL, "0.1.33", &diags)
+	if len(diags) > 0 {
+		t.Errorf("second Check() = %v, want no diagnostics", diags)
+	}
+
+	// other hosts are checked on their own
+	diags = nil
+	db.CheckVersion(ctx, "http://other:11434", "0.2.5", &diags)
+	if diags.HasError() || diags.WarningsCount() != 1 {
+		t.Errorf("CheckVersion() of a medium advisory = %v, want one warning", diags)
+	}
+
+	var nilDB *advisoryDatabase
+	nilDB.Check(ctx, &diags)
+	nilDB.CheckVersion(ctx, "http://other:11434", "0.1.0", &diags)
+}
+
+func TestLoadAdvisoryDatabaseErrors(t *testing.T) {
+	tests := map[string]struct {
+		content       string
+		errorSeverity string
+	}{
+		"invalid json":           {content: `{`},
+		"missing id":             {content: `{"advisories": [{"severity": "low"}]}`},
+		"invalid severity":       {content: `{"advisories": [{"id": "a", "severity": "urgent"}]}`},
+		"invalid version":        {content: `{"advisories": [{"id": "a", "severity": "low", "fixed": "next"}]}`},
+		"invalid error severity": {content: `{"advisories": []}`, errorSeverity: "fatal"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "advisories.json")
+			if err := os.WriteFile(file, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := loadAdvisoryDatabase(file, tt.errorSeverity, nil, ""); err == nil {
+				t.Error("loadAdvisoryDatabase() succeeded, want error")
+			}
+		})
+	}
+}
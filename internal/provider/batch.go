@@ -0,0 +1,300 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"text/template"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/ollama/ollama/api"
+)
+
+const (
+	batchModeGenerate = "generate"
+	batchModeChat     = "chat"
+
+	defaultBatchConcurrency = 4
+)
+
+// batchResult is one line of the output file.
+type batchResult struct {
+	Index  int            `json:"index"`
+	Input  map[string]any `json:"input"`
+	Output string         `json:"output"`
+}
+
+// batchCheckpoint is one line of the checkpoint file.
+type batchCheckpoint struct {
+	Key    string `json:"key"`
+	Output string `json:"output"`
+}
+
+// batchJob runs a prompt template over rows of a JSONL dataset. Finished rows
+// are appended to a checkpoint file keyed by model digest and request, so an
+// interrupted run resumes where it stopped and a re-run only processes rows
+// whose request or model changed. Rows are spread across the hosts of pool.
+type batchJob struct {
+	pool   *hostPool
+	cache  *responseCache
+	budget *inferenceBudget
+
+	model      string
+	digest     string
+	mode       string
+	system     string
+	options    map[string]any
+	keepAlive  *api.Duration
+	checkpoint string
+}
+
+// readJSONL reads one JSON object per non-empty line.
+func readJSONL(path string) ([]map[string]any, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rows []map[string]any
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var row map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &row); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, scanner.Err()
+}
+
+// writeJSONL replaces path with one JSON line per value.
+func writeJSONL[T any](path string, values []T) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for _, v := range values {
+		if err := enc.Encode(v); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// fileSHA256 returns the hex sha256 of the file at path.
+func fileSHA256(path string) (string, error) {
+	digest, _, err := fileDigest(path)
+	if err != nil {
+		return "", err
+	}
+	return digest[len("sha256:"):], nil
+}
+
+// renderPrompts applies the prompt template to every row.
+func renderPrompts(text string, rows []map[string]any) ([]string, error) {
+	tmpl, err := template.New("prompt").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	prompts := make([]string, len(rows))
+	for i, row := range rows {
+		var b bytes.Buffer
+		if err := tmpl.Execute(&b, row); err != nil {
+			return nil, fmt.Errorf("row %d: %w", i, err)
+		}
+		prompts[i] = b.String()
+	}
+
+	return prompts, nil
+}
+
+func (j *batchJob) rowKey(prompt string) string {
+	opts, _ := json.Marshal(j.options)
+
+	h := sha256.New()
+	for _, part := range []string{j.digest, j.mode, j.system, string(opts), prompt} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (j *batchJob) loadCheckpoint() (map[string]string, error) {
+	done := map[string]string{}
+
+	f, err := os.Open(j.checkpoint)
+	if errors.Is(err, fs.ErrNotExist) {
+		return done, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var c batchCheckpoint
+		// a line cut short by an interrupted run is skipped and re-run
+		if err := json.Unmarshal(scanner.Bytes(), &c); err == nil {
+			done[c.Key] = c.Output
+		}
+	}
+
+	return done, scanner.Err()
+}
+
+// Run returns the results for all rows, running only those that are not in
+// the checkpoint yet.
+func (j *batchJob) Run(ctx context.Context, rows []map[string]any, prompts []string) ([]batchResult, error) {
+	done, err := j.loadCheckpoint()
+	if err != nil {
+		return nil, fmt.Errorf("could not read checkpoint: %w", err)
+	}
+
+	keys := make([]string, len(rows))
+	var pending []int
+	for i, p := range prompts {
+		keys[i] = j.rowKey(p)
+		if _, ok := done[keys[i]]; !ok {
+			pending = append(pending, i)
+		}
+	}
+
+	tflog.Info(ctx, fmt.Sprintf("batch inference: %d rows, %d from checkpoint, %d to run", len(rows), len(rows)-len(pending), len(pending)))
+
+	if len(pending) > 0 {
+		if err := j.runPending(ctx, pending, keys, prompts, done); err != nil {
+			return nil, err
+		}
+	}
+
+	results := make([]batchResult, len(rows))
+	checkpoints := make([]batchCheckpoint, len(rows))
+	for i, row := range rows {
+		results[i] = batchResult{Index: i, Input: row, Output: done[keys[i]]}
+		checkpoints[i] = batchCheckpoint{Key: keys[i], Output: done[keys[i]]}
+	}
+
+	// compact the checkpoint down to the rows of this run
+	if err := writeJSONL(j.checkpoint, checkpoints); err != nil {
+		return nil, fmt.Errorf("could not write checkpoint: %w", err)
+	}
+
+	return results, nil
+}
+
+func (j *batchJob) runPending(ctx context.Context, pending []int, keys, prompts []string, done map[string]string) error {
+	f, err := os.OpenFile(j.checkpoint, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("could not open checkpoint: %w", err)
+	}
+	defer f.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		writeErr error
+	)
+
+	err = j.pool.Run(ctx, len(pending), func(ctx context.Context, h poolHost, n int) error {
+		i := pending[n]
+		output, err := j.infer(ctx, h, prompts[i])
+		if err != nil {
+			return fmt.Errorf("row %d: %w", i, err)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		done[keys[i]] = output
+		line, _ := json.Marshal(batchCheckpoint{Key: keys[i], Output: output})
+		if _, err := f.Write(append(line, '\n')); err != nil && writeErr == nil {
+			// not retried on another host, the checkpoint is local
+			writeErr = fmt.Errorf("could not write checkpoint: %w", err)
+			cancel()
+		}
+		return nil
+	})
+
+	if writeErr != nil {
+		return writeErr
+	}
+	return err
+}
+
+func (j *batchJob) infer(ctx context.Context, h poolHost, prompt string) (string, error) {
+	switch j.mode {
+	case batchModeChat:
+		var messages []api.Message
+		if j.system != "" {
+			messages = append(messages, api.Message{Role: "system", Content: j.system})
+		}
+		req := &api.ChatRequest{
+			Model:    j.model,
+			Messages: append(messages, api.Message{Role: "user", Content: prompt}),
+			Options:  j.options,
+		}
+
+		key, err := responseCacheKey(h.cacheHost, j.digest, req, optionSeed(j.options))
+		if err != nil {
+			return "", err
+		}
+		// keep_alive does not change the response and is not part of the key
+		req.KeepAlive = j.keepAlive
+		rsp, err := cachedCall(ctx, j.cache, key, func() (api.ChatResponse, error) {
+			return chat(ctx, h.client, j.budget, req)
+		})
+		return rsp.Message.Content, err
+	default:
+		req := &api.GenerateRequest{
+			Model:   j.model,
+			Prompt:  prompt,
+			System:  j.system,
+			Options: j.options,
+		}
+
+		key, err := responseCacheKey(h.cacheHost, j.digest, req, optionSeed(j.options))
+		if err != nil {
+			return "", err
+		}
+		req.KeepAlive = j.keepAlive
+		rsp, err := cachedCall(ctx, j.cache, key, func() (api.GenerateResponse, error) {
+			return generate(ctx, h.client, j.budget, req)
+		})
+		return rsp.Response, err
+	}
+}
@@ -0,0 +1,176 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// newTestBatchJob returns a job for llama3 on a fake server with a single
+// worker, so rows run in order.
+func newTestBatchJob(t *testing.T) (*fakeOllama, *batchJob) {
+	t.Helper()
+
+	f, srv, client := newFakeOllama(t)
+	f.AddModel("llama3")
+
+	pool, diags := newHostPool(context.Background(), types.ListNull(types.StringType), client, srv.URL, types.Int64Value(1))
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+
+	return f, &batchJob{
+		pool:       pool,
+		model:      "llama3",
+		digest:     "sha256:1111",
+		mode:       batchModeGenerate,
+		checkpoint: filepath.Join(t.TempDir(), "checkpoint.jsonl"),
+	}
+}
+
+func testBatchRows(n int) ([]map[string]any, []string) {
+	rows := make([]map[string]any, n)
+	prompts := make([]string, n)
+	for i := range rows {
+		rows[i] = map[string]any{"id": float64(i)}
+		prompts[i] = fmt.Sprintf("question %d", i)
+	}
+	return rows, prompts
+}
+
+func checkBatchResults(t *testing.T, results []batchResult, prompts []string) {
+	t.Helper()
+
+	if len(results) != len(prompts) {
+		t.Fatalf("got %d results, want %d", len(results), len(prompts))
+	}
+	for i, r := range results {
+		if r.Index != i || r.Output != "re: "+prompts[i] {
+			t.Errorf("result %d = %d %q, want %d %q", i, r.Index, r.Output, i, "re: "+prompts[i])
+		}
+	}
+}
+
+func TestBatchJobResume(t *testing.T) {
+	f, job := newTestBatchJob(t)
+	rows, prompts := testBatchRows(5)
+
+	// the budget interrupts the first run after two rows
+	job.budget = newInferenceBudget(2, 0, 0)
+	_, err := job.Run(context.Background(), rows, prompts)
+	var exceeded *errBudgetExceeded
+	if !errors.As(err, &exceeded) {
+		t.Fatalf("Run() error = %v, want the budget to interrupt it", err)
+	}
+
+	// a line cut short by the interruption is skipped
+	cp, err := os.OpenFile(job.checkpoint, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cp.WriteString(`{"key":"`); err != nil {
+		t.Fatal(err)
+	}
+	cp.Close()
+
+	job.budget = nil
+	results, err := job.Run(context.Background(), rows, prompts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkBatchResults(t, results, prompts)
+
+	if got := f.Calls("/api/generate"); got != 5 {
+		t.Errorf("generated %d times, want 5 with the first two rows resumed from the checkpoint", got)
+	}
+
+	done, err := job.loadCheckpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(done) != 5 {
+		t.Errorf("checkpoint has %d rows, want 5", len(done))
+	}
+}
+
+func TestBatchJobRerun(t *testing.T) {
+	tests := map[string]struct {
+		change  func(job *batchJob, prompts []string)
+		wantRun int
+	}{
+		"unchanged rows are not sent again": {
+			change:  func(job *batchJob, prompts []string) {},
+			wantRun: 0,
+		},
+		"changed prompts rerun": {
+			change: func(job *batchJob, prompts []string) {
+				prompts[1] = "another question"
+				prompts[3] = "yet another question"
+			},
+			wantRun: 2,
+		},
+		"digest change reruns every row": {
+			change:  func(job *batchJob, prompts []string) { job.digest = "sha256:2222" },
+			wantRun: 4,
+		},
+		"system change reruns every row": {
+			change:  func(job *batchJob, prompts []string) { job.system = "Answer briefly." },
+			wantRun: 4,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			f, job := newTestBatchJob(t)
+			rows, prompts := testBatchRows(4)
+
+			if _, err := job.Run(context.Background(), rows, prompts); err != nil {
+				t.Fatal(err)
+			}
+
+			tt.change(job, prompts)
+			results, err := job.Run(context.Background(), rows, prompts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkBatchResults(t, results, prompts)
+
+			if got := f.Calls("/api/generate") - 4; got != tt.wantRun {
+				t.Errorf("second run generated %d rows, want %d", got, tt.wantRun)
+			}
+		})
+	}
+}
+
+func TestBatchJobCompactsCheckpoint(t *testing.T) {
+	_, job := newTestBatchJob(t)
+	rows, prompts := testBatchRows(4)
+
+	if _, err := job.Run(context.Background(), rows, prompts); err != nil {
+		t.Fatal(err)
+	}
+
+	// the dataset shrinks to two rows, one of them changed
+	prompts = []string{prompts[0], "another question"}
+	if _, err := job.Run(context.Background(), rows[:2], prompts); err != nil {
+		t.Fatal(err)
+	}
+
+	done, err := job.loadCheckpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(done) != 2 {
+		t.Errorf("checkpoint has %d rows, want only the 2 of the last run", len(done))
+	}
+	for _, p := range prompts {
+		if got := done[job.rowKey(p)]; got != "re: "+p {
+			t.Errorf("checkpoint output for %q = %q, want %q", p, got, "re: "+p)
+		}
+	}
+}
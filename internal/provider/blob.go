@@ -0,0 +1,252 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/ollama/ollama/api"
+)
+
+const (
+	defaultUploadConcurrency = 4
+	defaultUploadAttempts    = 5
+
+	// uploadProgressStep is the fraction of a blob after which progress is logged.
+	uploadProgressStep = 0.05
+)
+
+// blobUploader uploads local files to the blob store of an Ollama server.
+//
+// Files are streamed from disk, never buffered in memory. Before uploading, the
+// server is asked with a HEAD request whether it already has the blob, so
+// unchanged weights are only transferred once. Uploads are not resumable: the
+// blob endpoint does not accept partial bodies, so a failed upload is retried
+// from the first byte of the file unless the server reports that it kept the
+// blob after all.
+type blobUploader struct {
+	client *api.Client
+	base   *url.URL
+	http   *http.Client
+
+	concurrency int
+	attempts    int
+}
+
+func newBlobUploader(client *api.Client, base *url.URL, httpClient *http.Client) *blobUploader {
+	return &blobUploader{
+		client:      client,
+		base:        base,
+		http:        httpClient,
+		concurrency: defaultUploadConcurrency,
+		attempts:    defaultUploadAttempts,
+	}
+}
+
+// UploadFiles uploads the given files in parallel, at most u.concurrency at a
+// time, and returns the blob digest for every path.
+func (u *blobUploader) UploadFiles(ctx context.Context, paths []string) (map[string]string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+		digests  = make(map[string]string, len(paths))
+		sem      = make(chan struct{}, max(u.concurrency, 1))
+	)
+
+	for _, p := range paths {
+		wg.Add(1)
+		go func(p string) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				return
+			}
+
+			digest, err := u.UploadFile(ctx, p)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				return
+			}
+			digests[p] = digest
+		}(p)
+	}
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return digests, nil
+}
+
+// UploadFile uploads a single file unless the server already has it and
+// returns its blob digest.
+func (u *blobUploader) UploadFile(ctx context.Context, path string) (string, error) {
+	digest, size, err := fileDigest(path)
+	if err != nil {
+		return "", fmt.Errorf("could not hash %s: %w", path, err)
+	}
+
+	ctx = tflog.SetField(ctx, "blob_digest", digest)
+	ctx = tflog.SetField(ctx, "blob_path", path)
+
+	var lastErr error
+	for attempt := 1; attempt <= u.attempts; attempt++ {
+		exists, err := u.blobExists(ctx, digest)
+		if err != nil {
+			lastErr = err
+		} else if exists {
+			tflog.Debug(ctx, "blob already present on server, skipping upload")
+			return digest, nil
+		} else if lastErr = u.upload(ctx, path, digest, size); lastErr == nil {
+			return digest, nil
+		}
+
+		var statusErr api.StatusError
+		if errors.As(lastErr, &statusErr) && statusErr.StatusCode < http.StatusInternalServerError {
+			break
+		}
+
+		if attempt < u.attempts {
+			backoff := time.Duration(1<<(attempt-1)) * time.Second
+			tflog.Warn(ctx, fmt.Sprintf("blob upload attempt %d/%d failed, retrying in %s: %s", attempt, u.attempts, backoff, lastErr))
+
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return "", ctx.Err()
+			}
+		}
+	}
+
+	return "", fmt.Errorf("could not upload %s: %w", path, lastErr)
+}
+
+func (u *blobUploader) blobExists(ctx context.Context, digest string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, u.base.JoinPath("/api/blobs", digest).String(), nil)
+	if err != nil {
+		return false, err
+	}
+
+	rsp, err := u.http.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer rsp.Body.Close()
+
+	switch rsp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, api.StatusError{StatusCode: rsp.StatusCode, Status: rsp.Status}
+	}
+}
+
+func (u *blobUploader) upload(ctx context.Context, path, digest string, size int64) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	tflog.Info(ctx, fmt.Sprintf("uploading blob (%d bytes)", size))
+
+	return u.client.CreateBlob(ctx, digest, &progressReader{ctx: ctx, r: f, total: size})
+}
+
+// fileDigest streams the file through sha256 and returns its digest in the
+// form expected by the blob endpoint, together with the file size.
+func fileDigest(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return fmt.Sprintf("sha256:%x", h.Sum(nil)), n, nil
+}
+
+// progressReader logs upload progress in steps of uploadProgressStep.
+type progressReader struct {
+	ctx    context.Context
+	r      io.Reader
+	total  int64
+	read   int64
+	logged int64
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.read += int64(n)
+
+	step := int64(float64(p.total) * uploadProgressStep)
+	if p.read-p.logged >= step || (err == io.EOF && p.logged < p.read) {
+		p.logged = p.read
+		tflog.Info(p.ctx, fmt.Sprintf("blob upload progress: %d/%d bytes (%.0f%%)", p.read, p.total, 100*float64(p.read)/float64(max(p.total, 1))))
+	}
+
+	return n, err
+}
+
+// UploadModelfileFiles uploads the local FROM and ADAPTER files of mf, with
+// relative paths resolved against dir, and points mf at the uploaded blobs.
+func (u *blobUploader) UploadModelfileFiles(ctx context.Context, mf *modelfile, dir string) error {
+	var paths []string
+	for _, p := range append([]string{mf.From}, mf.Adapters...) {
+		if isLocalModelPath(p, dir) {
+			paths = append(paths, resolveModelPath(p, dir))
+		}
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+
+	digests, err := u.UploadFiles(ctx, paths)
+	if err != nil {
+		return err
+	}
+
+	if d, ok := digests[resolveModelPath(mf.From, dir)]; ok {
+		mf.From = "@" + d
+	}
+	for i, a := range mf.Adapters {
+		if d, ok := digests[resolveModelPath(a, dir)]; ok {
+			mf.Adapters[i] = "@" + d
+		}
+	}
+
+	return nil
+}
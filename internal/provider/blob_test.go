@@ -0,0 +1,161 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeBlobServer is a blob endpoint that fails the first failures uploads with
+// status and records the bodies it received.
+type fakeBlobServer struct {
+	mu       sync.Mutex
+	blobs    map[string][]byte
+	bodies   [][]byte
+	heads    int
+	failures int
+	status   int
+}
+
+func (s *fakeBlobServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	digest := strings.TrimPrefix(r.URL.Path, "/api/blobs/")
+	switch r.Method {
+	case http.MethodHead:
+		s.heads++
+		if _, ok := s.blobs[digest]; ok {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	case http.MethodPost:
+		b, _ := io.ReadAll(r.Body)
+		s.bodies = append(s.bodies, b)
+		if s.failures > 0 {
+			s.failures--
+			http.Error(w, `{"error":"upload failed"}`, s.status)
+			return
+		}
+		s.blobs[digest] = b
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func newTestUploader(t *testing.T, s *fakeBlobServer) *blobUploader {
+	t.Helper()
+
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+
+	client, base, err := NewClient(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newBlobUploader(client, base, srv.Client())
+}
+
+func writeTestFile(t *testing.T, name string, content []byte) string {
+	t.Helper()
+
+	p := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(p, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestBlobUploaderUploadFile(t *testing.T) {
+	content := bytes.Repeat([]byte("weights"), 1024)
+
+	tests := map[string]struct {
+		existing   bool
+		failures   int
+		status     int
+		wantErr    bool
+		wantBodies int
+	}{
+		"new blob": {
+			wantBodies: 1,
+		},
+		"existing blob is skipped": {
+			existing:   true,
+			wantBodies: 0,
+		},
+		"server error is retried from the start": {
+			failures:   1,
+			status:     http.StatusInternalServerError,
+			wantBodies: 2,
+		},
+		"client error is not retried": {
+			failures:   1,
+			status:     http.StatusBadRequest,
+			wantErr:    true,
+			wantBodies: 1,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := writeTestFile(t, "model.gguf", content)
+			digest, _, err := fileDigest(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			s := &fakeBlobServer{blobs: map[string][]byte{}, failures: tt.failures, status: tt.status}
+			if tt.existing {
+				s.blobs[digest] = content
+			}
+
+			got, err := newTestUploader(t, s).UploadFile(context.Background(), p)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UploadFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != digest {
+				t.Errorf("UploadFile() = %s, want %s", got, digest)
+			}
+
+			if len(s.bodies) != tt.wantBodies {
+				t.Fatalf("got %d uploads, want %d", len(s.bodies), tt.wantBodies)
+			}
+			for i, b := range s.bodies {
+				if !bytes.Equal(b, content) {
+					t.Errorf("upload %d sent %d bytes, want the whole file of %d bytes", i, len(b), len(content))
+				}
+			}
+		})
+	}
+}
+
+func TestBlobUploaderUploadModelfileFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "model.gguf"), []byte("model"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "adapter.gguf"), []byte("adapter"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &fakeBlobServer{blobs: map[string][]byte{}}
+	mf := &modelfile{From: "./model.gguf", Adapters: []string{"./adapter.gguf"}}
+	if err := newTestUploader(t, s).UploadModelfileFiles(context.Background(), mf, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ref := range append([]string{mf.From}, mf.Adapters...) {
+		if _, ok := s.blobs[strings.TrimPrefix(ref, "@")]; !strings.HasPrefix(ref, "@sha256:") || !ok {
+			t.Errorf("reference %q does not point at an uploaded blob", ref)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// inferenceBudget limits the inference calls of a run. It is shared by all
+// resources and data sources of a provider instance, so it covers one plan or
+// one apply. Zero limits are not enforced. Responses served from the response
+// cache do not count against the budget.
+type inferenceBudget struct {
+	maxRequests int64
+	maxTokens   int64
+	maxWallTime time.Duration
+	start       time.Time
+
+	mu       sync.Mutex
+	requests int64
+	tokens   int64
+	// logged is the request count of the last usage summary.
+	logged int64
+}
+
+// errBudgetExceeded is returned for calls made after the budget ran out.
+type errBudgetExceeded struct {
+	reason string
+}
+
+func (e *errBudgetExceeded) Error() string {
+	return "inference budget exceeded: " + e.reason
+}
+
+func newInferenceBudget(maxRequests, maxTokens int64, maxWallTime time.Duration) *inferenceBudget {
+	return &inferenceBudget{
+		maxRequests: maxRequests,
+		maxTokens:   maxTokens,
+		maxWallTime: maxWallTime,
+		start:       time.Now(),
+	}
+}
+
+// Reserve accounts for one request, or fails if any limit is reached. A nil
+// budget allows everything.
+func (b *inferenceBudget) Reserve(ctx context.Context) error {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var reason string
+	switch elapsed := time.Since(b.start); {
+	case b.maxRequests > 0 && b.requests >= b.maxRequests:
+		reason = fmt.Sprintf("all %d requests used", b.maxRequests)
+	case b.maxTokens > 0 && b.tokens >= b.maxTokens:
+		reason = fmt.Sprintf("%d of %d predicted tokens used", b.tokens, b.maxTokens)
+	case b.maxWallTime > 0 && elapsed >= b.maxWallTime:
+		reason = fmt.Sprintf("wall time of %s used up", b.maxWallTime)
+	}
+	if reason != "" {
+		tflog.Warn(ctx, fmt.Sprintf("inference budget exceeded: %s", b.usage()))
+		return &errBudgetExceeded{reason: reason}
+	}
+
+	b.requests++
+	return nil
+}
+
+// Record adds the tokens a finished request predicted and logs the usage.
+func (b *inferenceBudget) Record(ctx context.Context, tokens int) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens += int64(tokens)
+	tflog.Debug(ctx, fmt.Sprintf("inference budget: %s", b.usage()))
+}
+
+// LogUsage logs the usage of the run so far at Info level. Operations call it
+// when they finished their inference calls, so the last summary in the log is
+// the total of the run. Nothing is logged if no request was made since the
+// last summary.
+func (b *inferenceBudget) LogUsage(ctx context.Context) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.requests == b.logged {
+		return
+	}
+	b.logged = b.requests
+	tflog.Info(ctx, fmt.Sprintf("inference budget used by this run: %s", b.usage()))
+}
+
+// usage describes what was used so far, e.g.
+// "12/100 requests, 3456/100000 predicted tokens, 1m2s/10m0s wall time".
+func (b *inferenceBudget) usage() string {
+	limit := func(used, max int64) string {
+		if max <= 0 {
+			return fmt.Sprintf("%d", used)
+		}
+		return fmt.Sprintf("%d/%d", used, max)
+	}
+
+	wall := time.Since(b.start).Round(time.Second).String()
+	if b.maxWallTime > 0 {
+		wall += "/" + b.maxWallTime.String()
+	}
+
+	return fmt.Sprintf("%s requests, %s predicted tokens, %s wall time", limit(b.requests, b.maxRequests), limit(b.tokens, b.maxTokens), wall)
+}
@@ -0,0 +1,137 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestInferenceBudget(t *testing.T) {
+	tests := map[string]struct {
+		budget *inferenceBudget
+		tokens int
+		// allowed is the number of requests that are reserved before the
+		// budget is exceeded, or -1 if it never is.
+		allowed int
+	}{
+		"no limits": {
+			budget:  newInferenceBudget(0, 0, 0),
+			tokens:  1000,
+			allowed: -1,
+		},
+		"request limit": {
+			budget:  newInferenceBudget(3, 0, 0),
+			tokens:  1000,
+			allowed: 3,
+		},
+		"token limit": {
+			budget:  newInferenceBudget(0, 250, 0),
+			tokens:  100,
+			allowed: 3,
+		},
+		"token limit reached exactly": {
+			budget:  newInferenceBudget(0, 200, 0),
+			tokens:  100,
+			allowed: 2,
+		},
+		"request limit before token limit": {
+			budget:  newInferenceBudget(2, 1000, 0),
+			tokens:  100,
+			allowed: 2,
+		},
+		"wall time used up": {
+			budget:  &inferenceBudget{maxWallTime: time.Minute, start: time.Now().Add(-time.Hour)},
+			allowed: 0,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			for i := 0; i < 10; i++ {
+				err := tt.budget.Reserve(ctx)
+				if tt.allowed < 0 || i < tt.allowed {
+					if err != nil {
+						t.Fatalf("Reserve() #%d = %v, want nil", i+1, err)
+					}
+					tt.budget.Record(ctx, tt.tokens)
+					continue
+				}
+
+				var exceeded *errBudgetExceeded
+				if !errors.As(err, &exceeded) {
+					t.Fatalf("Reserve() #%d = %v, want errBudgetExceeded", i+1, err)
+				}
+			}
+
+			want := tt.allowed
+			if want < 0 {
+				want = 10
+			}
+			if tt.budget.requests != int64(want) {
+				t.Errorf("requests = %d, want %d", tt.budget.requests, want)
+			}
+		})
+	}
+}
+
+func TestInferenceBudgetNil(t *testing.T) {
+	var b *inferenceBudget
+	ctx := context.Background()
+
+	if err := b.Reserve(ctx); err != nil {
+		t.Errorf("Reserve() on nil budget = %v, want nil", err)
+	}
+	b.Record(ctx, 100)
+	b.LogUsage(ctx)
+}
+
+func TestInferenceBudgetConcurrent(t *testing.T) {
+	ctx := context.Background()
+	b := newInferenceBudget(50, 0, 0)
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		reserved int
+	)
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := b.Reserve(ctx); err != nil {
+				return
+			}
+			b.Record(ctx, 10)
+			b.LogUsage(ctx)
+
+			mu.Lock()
+			reserved++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if reserved != 50 {
+		t.Errorf("reserved %d requests, want 50", reserved)
+	}
+	if b.tokens != 500 {
+		t.Errorf("tokens = %d, want 500", b.tokens)
+	}
+}
+
+func TestInferenceBudgetUsage(t *testing.T) {
+	b := &inferenceBudget{maxRequests: 100, maxWallTime: 10 * time.Minute, start: time.Now(), requests: 12, tokens: 3456}
+	if got, want := b.usage(), "12/100 requests, 3456 predicted tokens, 0s/10m0s wall time"; got != want {
+		t.Errorf("usage() = %q, want %q", got, want)
+	}
+
+	ctx := context.Background()
+	b.LogUsage(ctx)
+	if b.logged != 12 {
+		t.Errorf("logged = %d after a summary, want 12", b.logged)
+	}
+}
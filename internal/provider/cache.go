@@ -0,0 +1,264 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+const (
+	defaultCacheTTL     = 24 * time.Hour
+	defaultCacheMaxSize = 512 * 1024 * 1024
+)
+
+// responseCache is an on-disk cache for inference responses. Entries are
+// keyed by host, model digest, request payload and seed, expire after ttl and
+// the oldest entries are evicted once the cache grows beyond maxSize bytes.
+// Only ollama_batch_inference and ollama_embedding_evaluation go through it.
+//
+// The size of the directory is measured once when the cache is created and
+// then tracked in memory, so only a Put that pushes it beyond maxSize walks
+// the directory. Entries written by other provider instances sharing the
+// directory are counted by the next walk.
+type responseCache struct {
+	dir     string
+	ttl     time.Duration
+	maxSize int64
+
+	mu   sync.Mutex
+	size int64
+}
+
+func newResponseCache(dir string, ttl time.Duration, maxSize int64) (*responseCache, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	c := &responseCache{dir: dir, ttl: ttl, maxSize: maxSize}
+
+	_, size, err := c.entries()
+	if err != nil {
+		return nil, err
+	}
+	c.size = size
+
+	return c, nil
+}
+
+// responseCacheKey derives the cache key for an inference request. The model
+// digest rather than its name is used, so re-pulling a tag invalidates entries.
+func responseCacheKey(host, digest string, payload any, seed int64) (string, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	for _, part := range []string{host, digest, string(body), fmt.Sprint(seed)} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (c *responseCache) path(key string) string {
+	return filepath.Join(c.dir, key[:2], key+".json")
+}
+
+// Get decodes the entry for key into v and reports whether a fresh entry existed.
+func (c *responseCache) Get(key string, v any) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p := c.path(key)
+	info, err := os.Stat(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if c.ttl > 0 && time.Since(info.ModTime()) > c.ttl {
+		if err := os.Remove(p); errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		c.size -= info.Size()
+		return false, nil
+	}
+
+	data, err := os.ReadFile(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		// evicted by another provider instance sharing the directory
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		// a corrupt entry is a miss, it is overwritten by the next Put
+		return false, nil //nolint:nilerr
+	}
+
+	return true, nil
+}
+
+// Put stores v under key and evicts the oldest entries beyond maxSize.
+func (c *responseCache) Put(key string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(p), 0o700); err != nil {
+		return err
+	}
+
+	var replaced int64
+	if info, err := os.Stat(p); err == nil {
+		replaced = info.Size()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	c.size += int64(len(data)) - replaced
+	if c.maxSize <= 0 || c.size <= c.maxSize {
+		return nil
+	}
+
+	return c.evict()
+}
+
+// cacheEntry is a cache file found by entries.
+type cacheEntry struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// entries walks the cache directory and returns its entries and their total
+// size.
+func (c *responseCache) entries() ([]cacheEntry, int64, error) {
+	var (
+		entries []cacheEntry
+		total   int64
+	)
+	err := filepath.WalkDir(c.dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		// entries being written, possibly by another provider instance
+		if strings.HasPrefix(d.Name(), ".tmp-") {
+			return nil
+		}
+		info, err := d.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		entries = append(entries, cacheEntry{path: p, size: info.Size(), modTime: info.ModTime()})
+		total += info.Size()
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return entries, total, nil
+}
+
+// evict removes the oldest entries until the cache fits into maxSize and
+// resets the tracked size to what is left.
+func (c *responseCache) evict() error {
+	if c.maxSize <= 0 {
+		return nil
+	}
+
+	entries, total, err := c.entries()
+	if err != nil {
+		return err
+	}
+	defer func() { c.size = total }()
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].modTime.Before(entries[j].modTime) })
+
+	for _, e := range entries {
+		if total <= c.maxSize {
+			break
+		}
+		if err := os.Remove(e.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		total -= e.size
+	}
+
+	return nil
+}
+
+// cachedCall serves fn from the cache when c holds a fresh entry for key and
+// stores the result of fn otherwise. A nil cache always calls fn. Cache errors
+// are logged and never fail the call.
+func cachedCall[T any](ctx context.Context, c *responseCache, key string, fn func() (T, error)) (T, error) {
+	if c == nil {
+		return fn()
+	}
+
+	var cached T
+	hit, err := c.Get(key, &cached)
+	if err != nil {
+		tflog.Warn(ctx, fmt.Sprintf("could not read response cache: %s", err))
+	}
+	if hit {
+		tflog.Debug(ctx, fmt.Sprintf("response cache hit: %s", key))
+		return cached, nil
+	}
+
+	rsp, err := fn()
+	if err != nil {
+		return rsp, err
+	}
+
+	if err := c.Put(key, rsp); err != nil {
+		tflog.Warn(ctx, fmt.Sprintf("could not write response cache: %s", err))
+	}
+
+	return rsp, nil
+}
@@ -0,0 +1,118 @@
+package provider
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testCacheKey(t *testing.T, payload string) string {
+	t.Helper()
+
+	key, err := responseCacheKey("http://127.0.0.1:11434", "sha256:abc", payload, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestResponseCacheGetPut(t *testing.T) {
+	c, err := newResponseCache(t.TempDir(), time.Hour, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := testCacheKey(t, "hello")
+
+	var got string
+	if hit, err := c.Get(key, &got); err != nil || hit {
+		t.Fatalf("Get() on empty cache = %v, %v, want miss", hit, err)
+	}
+
+	if err := c.Put(key, "world"); err != nil {
+		t.Fatal(err)
+	}
+	if hit, err := c.Get(key, &got); err != nil || !hit || got != "world" {
+		t.Fatalf("Get() = %q, %v, %v, want hit with %q", got, hit, err, "world")
+	}
+}
+
+func TestResponseCacheTTL(t *testing.T) {
+	c, err := newResponseCache(t.TempDir(), time.Hour, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := testCacheKey(t, "hello")
+	if err := c.Put(key, "world"); err != nil {
+		t.Fatal(err)
+	}
+
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(c.path(key), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	var got string
+	if hit, err := c.Get(key, &got); err != nil || hit {
+		t.Fatalf("Get() of expired entry = %v, %v, want miss", hit, err)
+	}
+	if _, err := os.Stat(c.path(key)); !os.IsNotExist(err) {
+		t.Errorf("expired entry was not removed: %v", err)
+	}
+	if c.size != 0 {
+		t.Errorf("size = %d after removing the only entry, want 0", c.size)
+	}
+}
+
+func TestResponseCacheEvict(t *testing.T) {
+	dir := t.TempDir()
+	value := strings.Repeat("x", 98) // 100 bytes once JSON encoded
+
+	c, err := newResponseCache(dir, 0, 250)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := []string{testCacheKey(t, "a"), testCacheKey(t, "b"), testCacheKey(t, "c")}
+	for i, key := range keys {
+		if err := c.Put(key, value); err != nil {
+			t.Fatal(err)
+		}
+		mod := time.Now().Add(time.Duration(i-len(keys)) * time.Minute)
+		if err := os.Chtimes(c.path(key), mod, mod); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got string
+	if hit, _ := c.Get(keys[0], &got); hit {
+		t.Error("oldest entry was not evicted")
+	}
+	for _, key := range keys[1:] {
+		if hit, _ := c.Get(key, &got); !hit {
+			t.Errorf("entry %s was evicted, want only the oldest evicted", key)
+		}
+	}
+	if c.size != 200 {
+		t.Errorf("size = %d, want 200", c.size)
+	}
+
+	// overwriting an entry does not grow the cache
+	if err := c.Put(keys[1], value); err != nil {
+		t.Fatal(err)
+	}
+	if c.size != 200 {
+		t.Errorf("size = %d after overwriting an entry, want 200", c.size)
+	}
+
+	// a new instance picks up the size of the existing entries
+	reopened, err := newResponseCache(dir, 0, 250)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened.size != 200 {
+		t.Errorf("size of reopened cache = %d, want 200", reopened.size)
+	}
+}
@@ -0,0 +1,120 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	modelNamePartPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]{0,79}$`)
+	modelHostPattern     = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*(:[0-9]{1,5})?$`)
+)
+
+// validateModelName checks name against the model name grammar
+// [host/][namespace/]model[:tag].
+func validateModelName(name string) error {
+	rest, tag := name, ""
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		rest, tag = name[:i], name[i+1:]
+		if !modelNamePartPattern.MatchString(tag) {
+			return fmt.Errorf("invalid tag %q in model name %q", tag, name)
+		}
+	}
+
+	parts := strings.Split(rest, "/")
+	if len(parts) > 3 {
+		return fmt.Errorf("model name %q has more than three path parts", name)
+	}
+	for i, p := range parts {
+		pattern := modelNamePartPattern
+		if len(parts) == 3 && i == 0 {
+			pattern = modelHostPattern
+		}
+		if !pattern.MatchString(p) {
+			return fmt.Errorf("invalid part %q in model name %q", p, name)
+		}
+	}
+
+	return nil
+}
+
+// catalogEntry is the model a role resolves to in one environment.
+type catalogEntry struct {
+	Model  string `json:"model" yaml:"model"`
+	Digest string `json:"digest,omitempty" yaml:"digest,omitempty"`
+}
+
+// modelCatalog maps logical roles and environments to concrete models:
+//
+//	roles:
+//	  chat-default:
+//	    prod:    {model: "llama3:70b", digest: "sha256:..."}
+//	    default: {model: "llama3:8b"}
+type modelCatalog struct {
+	Roles map[string]map[string]catalogEntry `json:"roles" yaml:"roles"`
+}
+
+// loadCatalog reads a catalog from a JSON or YAML file, chosen by extension,
+// and validates every entry.
+func loadCatalog(file string) (*modelCatalog, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var c modelCatalog
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, &c)
+	default:
+		err = json.Unmarshal(b, &c)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", file, err)
+	}
+
+	var problems []string
+	for _, role := range sortedKeys(c.Roles) {
+		for _, env := range sortedKeys(c.Roles[role]) {
+			e := c.Roles[role][env]
+			if err := validateModelName(e.Model); err != nil {
+				problems = append(problems, fmt.Sprintf("%s/%s: %s", role, env, err))
+			}
+			if e.Digest != "" && !digestPattern.MatchString(e.Digest) {
+				problems = append(problems, fmt.Sprintf("%s/%s: invalid digest %q, expected 64 hex characters with an optional sha256: prefix", role, env, e.Digest))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid catalog entries:\n  %s", strings.Join(problems, "\n  "))
+	}
+
+	return &c, nil
+}
+
+// Resolve returns the entry of role for env, or for fallback if the role has
+// no entry for env, together with the environment that matched.
+func (c *modelCatalog) Resolve(role, env, fallback string) (catalogEntry, string, bool) {
+	for _, e := range []string{env, fallback} {
+		if entry, ok := c.Roles[role][e]; ok && e != "" {
+			return entry, e, true
+		}
+	}
+	return catalogEntry{}, "", false
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,122 @@
+package provider
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateModelName(t *testing.T) {
+	tests := map[string]bool{
+		"llama3":                                 true,
+		"llama3:8b":                              true,
+		"llama3:8b-instruct-q4_K_M":              true,
+		"library/llama3:latest":                  true,
+		"registry.ollama.ai/library/llama3:8b":   true,
+		"localhost:5000/team/model:v1.2":         true,
+		"hf.co/bartowski/Llama-3.2-1B-GGUF:Q4_0": true,
+		"":                                       false,
+		":8b":                                    false,
+		"llama3:":                                false,
+		"llama3:8b:q4":                           false,
+		"-llama3":                                false,
+		"llama 3":                                false,
+		"a/b/c/d":                                false,
+		"team//model":                            false,
+		"/model":                                 false,
+		"model/":                                 false,
+		"localhost:123456/team/model":            false,
+		"team:5000/model":                        false,
+		strings.Repeat("a", 80):                  true,
+		strings.Repeat("a", 81):                  false,
+	}
+
+	for name, valid := range tests {
+		if err := validateModelName(name); (err == nil) != valid {
+			t.Errorf("validateModelName(%q) = %v, want valid %v", name, err, valid)
+		}
+	}
+}
+
+func TestLoadCatalog(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("ab", 32)
+
+	tests := map[string]struct {
+		file    string
+		content string
+		wantErr string
+	}{
+		"yaml": {
+			file: "catalog.yaml",
+			content: `roles:
+  chat-default:
+    prod: {model: "llama3:70b", digest: "` + digest + `"}
+    default: {model: "llama3:8b"}
+`,
+		},
+		"json": {
+			file:    "catalog.json",
+			content: `{"roles": {"chat-default": {"prod": {"model": "llama3:70b", "digest": "` + digest + `"}, "default": {"model": "llama3:8b"}}}}`,
+		},
+		"yml extension": {
+			file:    "catalog.yml",
+			content: "roles:\n  chat-default:\n    prod: {model: llama3:70b, digest: \"" + digest + "\"}\n    default: {model: llama3:8b}\n",
+		},
+		"invalid model": {
+			file:    "catalog.yaml",
+			content: "roles:\n  chat:\n    prod: {model: \"llama 3\"}\n",
+			wantErr: "chat/prod: invalid part",
+		},
+		"invalid digest": {
+			file:    "catalog.yaml",
+			content: "roles:\n  chat:\n    prod: {model: llama3, digest: sha256:abc}\n",
+			wantErr: "chat/prod: invalid digest",
+		},
+		"all problems are reported": {
+			file:    "catalog.json",
+			content: `{"roles": {"a": {"prod": {"model": ""}}, "b": {"dev": {"model": "x:"}}}}`,
+			wantErr: "a/prod: invalid part \"\" in model name \"\"\n  b/dev: invalid tag",
+		},
+		"yaml in a json file": {
+			file:    "catalog.json",
+			content: "roles: {}\n",
+			wantErr: "could not parse",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(file, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			c, err := loadCatalog(file)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("loadCatalog() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			entry, env, ok := c.Resolve("chat-default", "prod", "default")
+			if !ok || env != "prod" || entry.Model != "llama3:70b" || entry.Digest != digest {
+				t.Errorf("Resolve(prod) = %+v, %q, %v", entry, env, ok)
+			}
+			entry, env, ok = c.Resolve("chat-default", "staging", "default")
+			if !ok || env != "default" || entry.Model != "llama3:8b" {
+				t.Errorf("Resolve(staging) = %+v, %q, %v, want the default entry", entry, env, ok)
+			}
+			if _, _, ok := c.Resolve("chat-default", "staging", ""); ok {
+				t.Error("Resolve(staging) without fallback found an entry")
+			}
+			if _, _, ok := c.Resolve("embedding", "prod", "default"); ok {
+				t.Error("Resolve() of an unknown role found an entry")
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/attr/xattr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ basetypes.StringTypable                    = DigestType{}
+	_ basetypes.StringValuableWithSemanticEquals = DigestValue{}
+	_ xattr.ValidateableAttribute                = DigestValue{}
+)
+
+// digestPattern matches the spellings of a sha256 digest: sha256:<hex> as in
+// manifests, sha256-<hex> as in blob file names, and bare hex as listed by the
+// server.
+var digestPattern = regexp.MustCompile(`^(?i)(sha256[:-])?[0-9a-f]{64}$`)
+
+// normalizeDigest returns the lowercase hex of a digest in any spelling.
+func normalizeDigest(digest string) string {
+	digest = strings.ToLower(digest)
+	if rest, ok := strings.CutPrefix(digest, "sha256:"); ok {
+		return rest
+	}
+	return strings.TrimPrefix(digest, "sha256-")
+}
+
+// DigestType is a string attribute holding a model or blob digest. Spellings
+// of the same digest are semantically equal.
+type DigestType struct {
+	basetypes.StringType
+}
+
+func (t DigestType) Equal(o attr.Type) bool {
+	other, ok := o.(DigestType)
+	if !ok {
+		return false
+	}
+	return t.StringType.Equal(other.StringType)
+}
+
+func (t DigestType) String() string {
+	return "DigestType"
+}
+
+func (t DigestType) ValueFromString(ctx context.Context, in basetypes.StringValue) (basetypes.StringValuable, diag.Diagnostics) {
+	return DigestValue{StringValue: in}, nil
+}
+
+func (t DigestType) ValueFromTerraform(ctx context.Context, in tftypes.Value) (attr.Value, error) {
+	attrValue, err := t.StringType.ValueFromTerraform(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+
+	stringValue, ok := attrValue.(basetypes.StringValue)
+	if !ok {
+		return nil, fmt.Errorf("unexpected value type of %T", attrValue)
+	}
+
+	return DigestValue{StringValue: stringValue}, nil
+}
+
+func (t DigestType) ValueType(ctx context.Context) attr.Value {
+	return DigestValue{}
+}
+
+// DigestValue is a value of DigestType.
+type DigestValue struct {
+	basetypes.StringValue
+}
+
+// NewDigestValue returns a known digest value.
+func NewDigestValue(digest string) DigestValue {
+	return DigestValue{StringValue: basetypes.NewStringValue(digest)}
+}
+
+// NewDigestNull returns a null digest value.
+func NewDigestNull() DigestValue {
+	return DigestValue{StringValue: basetypes.NewStringNull()}
+}
+
+// NewDigestUnknown returns an unknown digest value.
+func NewDigestUnknown() DigestValue {
+	return DigestValue{StringValue: basetypes.NewStringUnknown()}
+}
+
+func (v DigestValue) Equal(o attr.Value) bool {
+	other, ok := o.(DigestValue)
+	if !ok {
+		return false
+	}
+	return v.StringValue.Equal(other.StringValue)
+}
+
+func (v DigestValue) Type(ctx context.Context) attr.Type {
+	return DigestType{}
+}
+
+// StringSemanticEquals compares the digests without their prefix.
+func (v DigestValue) StringSemanticEquals(ctx context.Context, newValuable basetypes.StringValuable) (bool, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	newValue, ok := newValuable.(DigestValue)
+	if !ok {
+		diags.AddError(
+			"Semantic Equality Check Error",
+			fmt.Sprintf("Expected value type %T but got value type %T. Please report this to the provider developers.", v, newValuable),
+		)
+		return false, diags
+	}
+
+	return v.SameDigest(newValue.ValueString()), diags
+}
+
+// SameDigest reports whether v is digest in any spelling. A null or unknown
+// value is not the same as any digest.
+func (v DigestValue) SameDigest(digest string) bool {
+	if v.IsNull() || v.IsUnknown() {
+		return false
+	}
+	return normalizeDigest(v.ValueString()) == normalizeDigest(digest)
+}
+
+// ValidateAttribute rejects values that are not a sha256 digest.
+func (v DigestValue) ValidateAttribute(ctx context.Context, req xattr.ValidateAttributeRequest, resp *xattr.ValidateAttributeResponse) {
+	if v.IsNull() || v.IsUnknown() {
+		return
+	}
+
+	if !digestPattern.MatchString(v.ValueString()) {
+		resp.Diagnostics.AddAttributeError(
+			req.Path,
+			"Invalid digest",
+			fmt.Sprintf("Expected a sha256 digest as sha256:<hex>, sha256-<hex> or 64 hex characters, got %q.", v.ValueString()),
+		)
+	}
+}
@@ -0,0 +1,148 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"gopkg.in/yaml.v3"
+)
+
+const defaultHeartbeatTimeout = 2 * time.Second
+
+// discoveredHost is an Ollama endpoint found by discovery.
+type discoveredHost struct {
+	URL    string
+	Labels map[string]string
+
+	// Healthy and Version are set by checkHeartbeats.
+	Healthy bool
+	Version string
+}
+
+// hostInventory is a static list of Ollama endpoints:
+//
+//	hosts:
+//	  - url: http://gpu-1:11434
+//	    labels: {zone: eu-west-1a, gpu: a100}
+//	  - url: gpu-2
+type hostInventory struct {
+	Hosts []struct {
+		URL    string            `json:"url" yaml:"url"`
+		Labels map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	} `json:"hosts" yaml:"hosts"`
+}
+
+// loadInventory reads an inventory from a JSON or YAML file, chosen by
+// extension. Host URLs are normalized like the provider host, so "gpu-2"
+// becomes http://gpu-2:11434. Hosts whose normalized URL was listed before
+// are dropped, so "gpu-2" and http://gpu-2:11434 are one host.
+func loadInventory(file string) ([]discoveredHost, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var inv hostInventory
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, &inv)
+	default:
+		err = json.Unmarshal(b, &inv)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", file, err)
+	}
+
+	var hosts []discoveredHost
+	seen := map[string]bool{}
+	for i, h := range inv.Hosts {
+		u, err := parseHost(h.URL)
+		if err != nil {
+			return nil, fmt.Errorf("host %d: %w", i, err)
+		}
+		if seen[u.String()] {
+			continue
+		}
+		seen[u.String()] = true
+		hosts = append(hosts, discoveredHost{URL: u.String(), Labels: h.Labels})
+	}
+
+	return hosts, nil
+}
+
+// lookupSRVHosts resolves an SRV record such as _ollama._tcp.example.com to
+// endpoints. The resolver shuffles records of equal priority by weight, so
+// they are sorted by priority and target to keep the plan stable.
+func lookupSRVHosts(ctx context.Context, name, scheme string) ([]discoveredHost, error) {
+	_, records, err := net.DefaultResolver.LookupSRV(ctx, "", "", name)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Priority != records[j].Priority {
+			return records[i].Priority < records[j].Priority
+		}
+		if records[i].Target != records[j].Target {
+			return records[i].Target < records[j].Target
+		}
+		return records[i].Port < records[j].Port
+	})
+
+	hosts := make([]discoveredHost, 0, len(records))
+	for _, r := range records {
+		target := strings.TrimSuffix(r.Target, ".")
+		hosts = append(hosts, discoveredHost{
+			URL: scheme + "://" + net.JoinHostPort(target, strconv.Itoa(int(r.Port))),
+		})
+	}
+
+	return hosts, nil
+}
+
+// hasLabels reports whether h has every label in want with the same value.
+func (h discoveredHost) hasLabels(want map[string]string) bool {
+	for k, v := range want {
+		if got, ok := h.Labels[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
+
+// checkHeartbeats asks every host for its version and marks the hosts that
+// answer within timeout as healthy.
+func checkHeartbeats(ctx context.Context, hosts []discoveredHost, timeout time.Duration) {
+	var wg sync.WaitGroup
+	for i := range hosts {
+		wg.Add(1)
+		go func(h *discoveredHost) {
+			defer wg.Done()
+
+			client, _, err := NewClient(h.URL)
+			if err != nil {
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			if h.Version, err = client.Version(ctx); err != nil {
+				tflog.Info(ctx, fmt.Sprintf("host %s failed the heartbeat: %s", h.URL, err))
+				return
+			}
+			h.Healthy = true
+		}(&hosts[i])
+	}
+	wg.Wait()
+}
@@ -0,0 +1,123 @@
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestLoadInventory(t *testing.T) {
+	tests := map[string]struct {
+		file    string
+		content string
+		want    []discoveredHost
+	}{
+		"json": {
+			file:    "hosts.json",
+			content: `{"hosts": [{"url": "http://gpu-1:11434", "labels": {"gpu": "a100"}}, {"url": "gpu-2"}]}`,
+			want: []discoveredHost{
+				{URL: "http://gpu-1:11434", Labels: map[string]string{"gpu": "a100"}},
+				{URL: "http://gpu-2:11434"},
+			},
+		},
+		"yaml": {
+			file: "hosts.yaml",
+			content: "hosts:\n" +
+				"  - url: https://gpu-1:8443/ollama/\n" +
+				"    labels: {zone: eu-west-1a}\n",
+			want: []discoveredHost{
+				{URL: "https://gpu-1:8443/ollama", Labels: map[string]string{"zone": "eu-west-1a"}},
+			},
+		},
+		"duplicates keep the first entry": {
+			file: "hosts.yml",
+			content: "hosts:\n" +
+				"  - url: gpu-1\n" +
+				"    labels: {gpu: a100}\n" +
+				"  - url: gpu-2\n" +
+				"  - url: http://gpu-1:11434/\n" +
+				"    labels: {gpu: h100}\n",
+			want: []discoveredHost{
+				{URL: "http://gpu-1:11434", Labels: map[string]string{"gpu": "a100"}},
+				{URL: "http://gpu-2:11434"},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(file, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := loadInventory(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("loadInventory() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOllamaHostsDataSourceValidateConfig(t *testing.T) {
+	d := &OllamaHostsDataSource{}
+	ctx := context.Background()
+
+	var schemaResp datasource.SchemaResponse
+	d.Schema(ctx, datasource.SchemaRequest{}, &schemaResp)
+	s := schemaResp.Schema
+
+	config := func(srv, inventory *string, labels map[string]string) *OllamaHostsDataSourceModel {
+		l := types.MapNull(types.StringType)
+		if labels != nil {
+			l, _ = types.MapValueFrom(ctx, types.StringType, labels)
+		}
+		return &OllamaHostsDataSourceModel{
+			SRV:              types.StringPointerValue(srv),
+			Scheme:           types.StringNull(),
+			InventoryFile:    types.StringPointerValue(inventory),
+			Labels:           l,
+			HealthCheck:      types.BoolNull(),
+			HeartbeatTimeout: types.StringNull(),
+		}
+	}
+	str := func(s string) *string { return &s }
+	gpu := map[string]string{"gpu": "a100"}
+
+	tests := map[string]struct {
+		config  *OllamaHostsDataSourceModel
+		wantErr bool
+	}{
+		"srv":                    {config: config(str("_ollama._tcp.example.com"), nil, nil)},
+		"inventory":              {config: config(nil, str("hosts.yaml"), nil)},
+		"inventory with labels":  {config: config(nil, str("hosts.yaml"), gpu)},
+		"srv with labels":        {config: config(str("_ollama._tcp.example.com"), nil, gpu), wantErr: true},
+		"srv with empty labels":  {config: config(str("_ollama._tcp.example.com"), nil, map[string]string{}), wantErr: true},
+		"neither":                {config: config(nil, nil, nil), wantErr: true},
+		"both srv and inventory": {config: config(str("_ollama._tcp.example.com"), str("hosts.yaml"), nil), wantErr: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			raw := tfsdk.State{Schema: s}
+			if diags := raw.Set(ctx, tt.config); diags.HasError() {
+				t.Fatal(diags)
+			}
+
+			var resp datasource.ValidateConfigResponse
+			d.ValidateConfig(ctx, datasource.ValidateConfigRequest{Config: tfsdk.Config{Schema: s, Raw: raw.Raw}}, &resp)
+			if got := resp.Diagnostics.HasError(); got != tt.wantErr {
+				t.Errorf("ValidateConfig() error = %v, want %v: %v", got, tt.wantErr, resp.Diagnostics)
+			}
+		})
+	}
+}
@@ -3,18 +3,29 @@ package provider
 import "github.com/hashicorp/terraform-plugin-framework/types"
 
 type OllamaModelResource struct {
-	Name       types.String `tfsdk:"name"`
-	ModifiedAt types.String `tfsdk:"modified_at"`
-	Size       types.Int64  `tfsdk:"size"`
-	Digest     types.String `tfsdk:"digest"`
+	Name                  types.String `tfsdk:"name"`
+	ModifiedAt            types.String `tfsdk:"modified_at"`
+	Size                  types.Int64  `tfsdk:"size"`
+	Digest                DigestValue  `tfsdk:"digest"`
+	VerifyLoad            types.Bool   `tfsdk:"verify_load"`
+	DeleteOnVerifyFailure types.Bool   `tfsdk:"delete_on_verify_failure"`
+	SnapshotRetention     types.Int64  `tfsdk:"snapshot_retention"`
+	Snapshots             types.List   `tfsdk:"snapshots"`
 }
 
 type OllamaModel struct {
-	Name       types.String       `tfsdk:"name"`
-	ModifiedAt types.String       `tfsdk:"modified_at"`
-	Size       types.Int64        `tfsdk:"size"`
-	Digest     types.String       `tfsdk:"digest"`
-	Details    OllamaModelDetails `tfsdk:"details"`
+	Name       types.String        `tfsdk:"name"`
+	ModifiedAt types.String        `tfsdk:"modified_at"`
+	Size       types.Int64         `tfsdk:"size"`
+	Digest     DigestValue         `tfsdk:"digest"`
+	Details    OllamaModelDetails  `tfsdk:"details"`
+	License    *OllamaModelLicense `tfsdk:"license"`
+}
+
+type OllamaModelLicense struct {
+	SPDXID     types.String  `tfsdk:"spdx_id"`
+	Name       types.String  `tfsdk:"name"`
+	Confidence types.Float64 `tfsdk:"confidence"`
 }
 
 type OllamaModelDetails struct {
@@ -24,3 +35,115 @@ type OllamaModelDetails struct {
 	ParameterSize     types.String `tfsdk:"parameter_size" json:"parameter_size"`
 	QuantizationLevel types.String `tfsdk:"quantization_level" json:"quantization_level"`
 }
+
+type OllamaCustomModelResource struct {
+	Name         types.String              `tfsdk:"name"`
+	From         types.String              `tfsdk:"from"`
+	System       types.String              `tfsdk:"system"`
+	Template     types.String              `tfsdk:"template"`
+	Parameters   types.Map                 `tfsdk:"parameters"`
+	Stop         types.List                `tfsdk:"stop"`
+	Messages     types.List                `tfsdk:"messages"`
+	Adapters     types.List                `tfsdk:"adapters"`
+	Modelfile    ModelfileValue            `tfsdk:"modelfile"`
+	Digest       DigestValue               `tfsdk:"digest"`
+	SecurityScan *OllamaSecurityScanPolicy `tfsdk:"security_scan"`
+}
+
+type OllamaMessage struct {
+	Role    types.String `tfsdk:"role"`
+	Content types.String `tfsdk:"content"`
+}
+
+type OllamaBatchInferenceResource struct {
+	Model          types.String   `tfsdk:"model"`
+	Mode           types.String   `tfsdk:"mode"`
+	InputFile      types.String   `tfsdk:"input_file"`
+	OutputFile     types.String   `tfsdk:"output_file"`
+	CheckpointFile types.String   `tfsdk:"checkpoint_file"`
+	PromptTemplate types.String   `tfsdk:"prompt_template"`
+	System         types.String   `tfsdk:"system"`
+	Options        types.Map      `tfsdk:"options"`
+	Hosts          types.List     `tfsdk:"hosts"`
+	Concurrency    types.Int64    `tfsdk:"concurrency"`
+	KeepAlive      KeepAliveValue `tfsdk:"keep_alive"`
+	InputSHA256    types.String   `tfsdk:"input_sha256"`
+	ModelDigest    DigestValue    `tfsdk:"model_digest"`
+	RowsTotal      types.Int64    `tfsdk:"rows_total"`
+}
+
+type OllamaEmbeddingEvaluationResource struct {
+	Model               types.String   `tfsdk:"model"`
+	BaselineModel       types.String   `tfsdk:"baseline_model"`
+	DatasetFile         types.String   `tfsdk:"dataset_file"`
+	K                   types.Int64    `tfsdk:"k"`
+	Hosts               types.List     `tfsdk:"hosts"`
+	Concurrency         types.Int64    `tfsdk:"concurrency"`
+	KeepAlive           KeepAliveValue `tfsdk:"keep_alive"`
+	MinRecallAtK        types.Float64  `tfsdk:"min_recall_at_k"`
+	MinMRR              types.Float64  `tfsdk:"min_mrr"`
+	MinNDCG             types.Float64  `tfsdk:"min_ndcg"`
+	MaxRegression       types.Float64  `tfsdk:"max_regression"`
+	DatasetSHA256       types.String   `tfsdk:"dataset_sha256"`
+	ModelDigest         DigestValue    `tfsdk:"model_digest"`
+	BaselineModelDigest DigestValue    `tfsdk:"baseline_model_digest"`
+	RecallAtK           types.Float64  `tfsdk:"recall_at_k"`
+	MRR                 types.Float64  `tfsdk:"mrr"`
+	NDCG                types.Float64  `tfsdk:"ndcg"`
+	BaselineRecallAtK   types.Float64  `tfsdk:"baseline_recall_at_k"`
+	BaselineMRR         types.Float64  `tfsdk:"baseline_mrr"`
+	BaselineNDCG        types.Float64  `tfsdk:"baseline_ndcg"`
+}
+
+type OllamaDeterminismHostResult struct {
+	Host             types.String `tfsdk:"host"`
+	ServerVersion    types.String `tfsdk:"server_version"`
+	Digest           DigestValue  `tfsdk:"digest"`
+	OutputHashes     types.List   `tfsdk:"output_hashes"`
+	Deviates         types.Bool   `tfsdk:"deviates"`
+	DeviatingPrompts types.List   `tfsdk:"deviating_prompts"`
+	Error            types.String `tfsdk:"error"`
+}
+
+type OllamaModelfileDirectoryResource struct {
+	Directory    types.String              `tfsdk:"directory"`
+	Namespace    types.String              `tfsdk:"namespace"`
+	Files        types.Map                 `tfsdk:"files"`
+	Models       types.Map                 `tfsdk:"models"`
+	SecurityScan *OllamaSecurityScanPolicy `tfsdk:"security_scan"`
+}
+
+type OllamaGGUFExportResource struct {
+	Model          types.String `tfsdk:"model"`
+	ModelsDir      types.String `tfsdk:"models_dir"`
+	OutputFile     types.String `tfsdk:"output_file"`
+	Hardlink       types.Bool   `tfsdk:"hardlink"`
+	Digest         DigestValue  `tfsdk:"digest"`
+	Size           types.Int64  `tfsdk:"size"`
+	TemplateFile   types.String `tfsdk:"template_file"`
+	ParametersFile types.String `tfsdk:"parameters_file"`
+}
+
+type OllamaSecurityScanPolicy struct {
+	SeverityThreshold types.String   `tfsdk:"severity_threshold"`
+	MetadataAllowlist []types.String `tfsdk:"metadata_allowlist"`
+}
+
+type OllamaScanFinding struct {
+	Severity types.String `tfsdk:"severity"`
+	Rule     types.String `tfsdk:"rule"`
+	Location types.String `tfsdk:"location"`
+	Message  types.String `tfsdk:"message"`
+}
+
+type OllamaCatalogEntry struct {
+	Model       types.String `tfsdk:"model"`
+	Digest      DigestValue  `tfsdk:"digest"`
+	Environment types.String `tfsdk:"environment"`
+}
+
+type OllamaHostEndpoint struct {
+	URL     types.String `tfsdk:"url"`
+	Labels  types.Map    `tfsdk:"labels"`
+	Version types.String `tfsdk:"version"`
+}
@@ -0,0 +1,79 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/ollama/ollama/api"
+)
+
+const defaultEmbeddingConcurrency = 4
+
+// embedder embeds texts with one model, spread across the hosts of pool.
+// Responses go through the response cache when one is configured.
+type embedder struct {
+	pool   *hostPool
+	cache  *responseCache
+	budget *inferenceBudget
+
+	model     string
+	digest    string
+	keepAlive *api.Duration
+}
+
+// Embed returns the embeddings of texts in the same order.
+func (e *embedder) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	out := make([][]float64, len(texts))
+
+	err := e.pool.Run(ctx, len(texts), func(ctx context.Context, h poolHost, i int) error {
+		vec, err := e.embed(ctx, h, texts[i])
+		out[i] = vec
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (e *embedder) embed(ctx context.Context, h poolHost, text string) ([]float64, error) {
+	req := &api.EmbeddingRequest{Model: e.model, Prompt: text}
+
+	key, err := responseCacheKey(h.cacheHost, e.digest, req, 0)
+	if err != nil {
+		return nil, err
+	}
+	// keep_alive does not change the embedding and is not part of the key
+	req.KeepAlive = e.keepAlive
+
+	rsp, err := cachedCall(ctx, e.cache, key, func() (*api.EmbeddingResponse, error) {
+		if err := e.budget.Reserve(ctx); err != nil {
+			return nil, err
+		}
+		rsp, err := h.client.Embeddings(ctx, req)
+		e.budget.Record(ctx, 0)
+		return rsp, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not embed with %s on %s: %w", e.model, h.name, err)
+	}
+
+	return rsp.Embedding, nil
+}
+
+func cosineSimilarity(a, b []float64) float64 {
+	var dot, na, nb float64
+	for i := range a {
+		if i >= len(b) {
+			break
+		}
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
@@ -0,0 +1,134 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	pathpkg "path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// eolEntry describes the retirement of the models matching a pattern.
+type eolEntry struct {
+	EOLDate     string `json:"eol_date"`
+	Replacement string `json:"replacement,omitempty"`
+	Reason      string `json:"reason,omitempty"`
+
+	date time.Time
+}
+
+// eolPolicy holds the end-of-life metadata of models by name pattern.
+//
+// The file maps glob patterns as understood by path.Match to entries:
+//
+//	{
+//	  "llama2":     {"eol_date": "2026-06-30", "replacement": "llama3"},
+//	  "mistral:7b": {"eol_date": "2026-03-31", "replacement": "mistral-nemo"}
+//	}
+//
+// A pattern without a tag matches every tag of the model. References to a
+// matching model produce a plan warning until the EOL date and an error from
+// that date on.
+type eolPolicy struct {
+	patterns []string
+	entries  map[string]*eolEntry
+
+	// now returns the current time, time.Now unless set by tests.
+	now func() time.Time
+}
+
+func loadEOLPolicy(file string) (*eolPolicy, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := map[string]*eolEntry{}
+	if err := json.Unmarshal(b, &entries); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", file, err)
+	}
+
+	p := &eolPolicy{entries: entries, now: time.Now}
+	for pattern, e := range entries {
+		if e == nil {
+			return nil, fmt.Errorf("missing entry for %q", pattern)
+		}
+		if _, err := pathpkg.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		if e.date, err = time.Parse(time.DateOnly, e.EOLDate); err != nil {
+			return nil, fmt.Errorf("invalid eol_date for %q, expected YYYY-MM-DD: %w", pattern, err)
+		}
+		p.patterns = append(p.patterns, pattern)
+	}
+
+	// the most specific pattern wins, so "llama2:70b" can override "llama2"
+	sort.Slice(p.patterns, func(i, j int) bool {
+		if len(p.patterns[i]) != len(p.patterns[j]) {
+			return len(p.patterns[i]) > len(p.patterns[j])
+		}
+		return p.patterns[i] < p.patterns[j]
+	})
+
+	return p, nil
+}
+
+// Lookup returns the entry for model, or nil if it is not scheduled for
+// retirement.
+func (p *eolPolicy) Lookup(model string) (string, *eolEntry) {
+	if p == nil || model == "" {
+		return "", nil
+	}
+
+	name := normalizeModelName(model)
+	untagged := name[:strings.LastIndex(name, ":")]
+
+	for _, pattern := range p.patterns {
+		candidate := name
+		if i := strings.LastIndex(pattern, "/"); !strings.Contains(pattern[i+1:], ":") {
+			candidate = untagged
+		}
+		if ok, _ := pathpkg.Match(pattern, candidate); ok {
+			return pattern, p.entries[pattern]
+		}
+	}
+
+	return "", nil
+}
+
+// Check adds a warning to diags if model is deprecated, or an error if its
+// EOL date has passed. Unknown and null values are skipped.
+func (p *eolPolicy) Check(attr path.Path, model types.String, diags *diag.Diagnostics) {
+	if p == nil || model.IsNull() || model.IsUnknown() {
+		return
+	}
+	p.CheckName(attr, model.ValueString(), diags)
+}
+
+// CheckName is Check for a plain model name.
+func (p *eolPolicy) CheckName(attr path.Path, model string, diags *diag.Diagnostics) {
+	pattern, e := p.Lookup(model)
+	if e == nil {
+		return
+	}
+
+	detail := fmt.Sprintf("Model %s matches %q in the end-of-life metadata and is retired on %s.", model, pattern, e.EOLDate)
+	if e.Reason != "" {
+		detail += " " + e.Reason
+	}
+	if e.Replacement != "" {
+		detail += fmt.Sprintf(" Use %s instead.", e.Replacement)
+	}
+
+	if !p.now().Before(e.date) {
+		diags.AddAttributeError(attr, "Model past end of life", detail)
+	} else {
+		diags.AddAttributeWarning(attr, "Deprecated model", detail)
+	}
+}
@@ -0,0 +1,143 @@
+package provider
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func writeEOLPolicy(t *testing.T, content string) *eolPolicy {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), "eol.json")
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := loadEOLPolicy(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestEOLPolicyLookup(t *testing.T) {
+	p := writeEOLPolicy(t, `{
+		"llama2":                        {"eol_date": "2026-06-30"},
+		"llama2:70b":                    {"eol_date": "2026-03-31"},
+		"mistral:7b":                    {"eol_date": "2026-01-31"},
+		"codellama*":                    {"eol_date": "2026-05-31"},
+		"registry.example.com/team/*":   {"eol_date": "2026-12-31"},
+		"phi3:*-instruct":               {"eol_date": "2026-09-30"}
+	}`)
+
+	tests := map[string]string{
+		"llama2":                                 "llama2",
+		"llama2:latest":                          "llama2",
+		"llama2:13b":                             "llama2",
+		"llama2:70b":                             "llama2:70b",
+		"llama3":                                 "",
+		"mistral":                                "",
+		"mistral:7b":                             "mistral:7b",
+		"codellama:7b":                           "codellama*",
+		"codellama-python":                       "codellama*",
+		"registry.example.com/team/model:v1":     "registry.example.com/team/*",
+		"registry.example.com/team/sub/model:v1": "",
+		"registry.example.com/other/model":       "",
+		"phi3:mini-instruct":                     "phi3:*-instruct",
+		"phi3:mini":                              "",
+		"":                                       "",
+	}
+
+	for model, want := range tests {
+		got, e := p.Lookup(model)
+		if got != want {
+			t.Errorf("Lookup(%q) = %q, want %q", model, got, want)
+		}
+		if (e != nil) != (want != "") {
+			t.Errorf("Lookup(%q) entry = %v, want entry %v", model, e, want != "")
+		}
+	}
+
+	var nilPolicy *eolPolicy
+	if pattern, e := nilPolicy.Lookup("llama2"); pattern != "" || e != nil {
+		t.Errorf("Lookup on nil policy = %q, %v, want no match", pattern, e)
+	}
+}
+
+func TestEOLPolicyCheckDate(t *testing.T) {
+	p := writeEOLPolicy(t, `{"llama2": {"eol_date": "2026-06-30", "replacement": "llama3"}}`)
+
+	tests := map[string]struct {
+		now         time.Time
+		wantWarning bool
+		wantError   bool
+	}{
+		"long before": {
+			now:         time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+			wantWarning: true,
+		},
+		"last second before": {
+			now:         time.Date(2026, 6, 29, 23, 59, 59, 0, time.UTC),
+			wantWarning: true,
+		},
+		"on the date": {
+			now:       time.Date(2026, 6, 30, 0, 0, 0, 0, time.UTC),
+			wantError: true,
+		},
+		"after": {
+			now:       time.Date(2026, 7, 1, 12, 0, 0, 0, time.UTC),
+			wantError: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			p.now = func() time.Time { return tt.now }
+
+			var diags diag.Diagnostics
+			p.Check(path.Root("name"), types.StringValue("llama2:7b"), &diags)
+
+			if got := diags.WarningsCount() > 0; got != tt.wantWarning {
+				t.Errorf("warning = %v, want %v: %v", got, tt.wantWarning, diags)
+			}
+			if got := diags.HasError(); got != tt.wantError {
+				t.Errorf("error = %v, want %v: %v", got, tt.wantError, diags)
+			}
+		})
+	}
+
+	var diags diag.Diagnostics
+	p.Check(path.Root("name"), types.StringUnknown(), &diags)
+	p.Check(path.Root("name"), types.StringNull(), &diags)
+	p.Check(path.Root("name"), types.StringValue("llama3"), &diags)
+	if len(diags) > 0 {
+		t.Errorf("Check of unknown, null and unlisted models = %v, want no diagnostics", diags)
+	}
+}
+
+func TestLoadEOLPolicyErrors(t *testing.T) {
+	tests := map[string]string{
+		"invalid json":    `{`,
+		"missing entry":   `{"llama2": null}`,
+		"invalid pattern": `{"llama[": {"eol_date": "2026-06-30"}}`,
+		"invalid date":    `{"llama2": {"eol_date": "30.06.2026"}}`,
+	}
+
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "eol.json")
+			if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := loadEOLPolicy(file); err == nil {
+				t.Error("loadEOLPolicy() succeeded, want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,397 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/ollama/ollama/api"
+)
+
+// fakeOllama is an in-memory Ollama server for tests. Models are stored
+// under their normalized name.
+type fakeOllama struct {
+	mu     sync.Mutex
+	models map[string]api.ModelResponse
+	calls  map[string]int
+
+	// Version is returned by /api/version.
+	Version string
+	// Digests overrides the digest a pulled model gets, by normalized name.
+	Digests map[string]string
+	// Licenses holds the license text of models, by normalized name.
+	Licenses map[string]string
+	// Modelfiles overrides the Modelfile Show reports, by normalized name.
+	Modelfiles map[string]string
+	// FailLoad makes generate requests fail.
+	FailLoad bool
+}
+
+// fakeBlobDir is where the fake server claims to keep uploaded blobs.
+const fakeBlobDir = "/root/.ollama/models/blobs/"
+
+// newFakeOllama starts a fakeOllama and returns it with a client for it.
+func newFakeOllama(t *testing.T) (*fakeOllama, *httptest.Server, *api.Client) {
+	t.Helper()
+
+	f := &fakeOllama{
+		models:     map[string]api.ModelResponse{},
+		calls:      map[string]int{},
+		Version:    "0.3.0",
+		Digests:    map[string]string{},
+		Licenses:   map[string]string{},
+		Modelfiles: map[string]string{},
+	}
+
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	client, _, err := NewClient(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f, srv, client
+}
+
+// AddModel stores a model as if it had been pulled before.
+func (f *fakeOllama) AddModel(name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.addModel(name)
+}
+
+func (f *fakeOllama) addModel(name string) {
+	name = normalizeModelName(name)
+	digest, ok := f.Digests[name]
+	if !ok {
+		digest = fmt.Sprintf("%x", sha256.Sum256([]byte(name)))
+	}
+	f.models[name] = api.ModelResponse{Name: name, Model: name, Digest: digest, Size: 1024, ModifiedAt: time.Now()}
+}
+
+// Has reports whether the server has a model called name.
+func (f *fakeOllama) Has(name string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, ok := f.models[normalizeModelName(name)]
+	return ok
+}
+
+// Models returns the names of all models on the server, sorted.
+func (f *fakeOllama) Models() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	names := make([]string, 0, len(f.models))
+	for name := range f.models {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Calls returns how often an endpoint such as "/api/pull" was called.
+func (f *fakeOllama) Calls(path string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[path]
+}
+
+func (f *fakeOllama) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if strings.HasPrefix(r.URL.Path, "/api/blobs/") {
+		// every blob is there already, uploads are not stored
+		f.calls["/api/blobs"]++
+		return
+	}
+	f.calls[r.URL.Path]++
+
+	var req struct {
+		Name        string `json:"name"`
+		Model       string `json:"model"`
+		Source      string `json:"source"`
+		Destination string `json:"destination"`
+		Prompt      string `json:"prompt"`
+		Modelfile   string `json:"modelfile"`
+	}
+	if r.Body != nil && r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			f.error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	name := req.Model
+	if name == "" {
+		name = req.Name
+	}
+	model, exists := f.models[normalizeModelName(name)]
+
+	switch r.URL.Path {
+	case "/api/version":
+		f.reply(w, map[string]string{"version": f.Version})
+	case "/api/tags":
+		list := api.ListResponse{Models: []api.ModelResponse{}}
+		for _, m := range f.models {
+			list.Models = append(list.Models, m)
+		}
+		sort.Slice(list.Models, func(i, j int) bool { return list.Models[i].Name < list.Models[j].Name })
+		f.reply(w, list)
+	case "/api/pull":
+		f.addModel(name)
+		f.reply(w, api.ProgressResponse{Status: "success"})
+	case "/api/create":
+		// like the server, a base model must exist, blobs are taken as given
+		mf, err := parseModelfile(req.Modelfile)
+		if err != nil {
+			f.error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if _, ok := f.models[normalizeModelName(mf.From)]; !ok && !strings.HasPrefix(mf.From, "@") {
+			f.error(w, http.StatusNotFound, fmt.Sprintf("model '%s' not found", mf.From))
+			return
+		}
+		name = normalizeModelName(name)
+		f.Digests[name] = fmt.Sprintf("%x", sha256.Sum256([]byte(req.Modelfile)))
+		// Show reports uploaded blobs by their path in the models store
+		f.Modelfiles[name] = strings.ReplaceAll(req.Modelfile, "@sha256:", fakeBlobDir+"sha256-")
+		f.addModel(name)
+		f.reply(w, api.ProgressResponse{Status: "success"})
+	case "/api/show":
+		if !exists {
+			f.error(w, http.StatusNotFound, fmt.Sprintf("model '%s' not found", name))
+			return
+		}
+		mf, ok := f.Modelfiles[model.Name]
+		if !ok {
+			mf = "FROM " + model.Name + "\n"
+		}
+		f.reply(w, api.ShowResponse{License: f.Licenses[model.Name], Modelfile: mf})
+	case "/api/copy":
+		source, ok := f.models[normalizeModelName(req.Source)]
+		if !ok {
+			f.error(w, http.StatusNotFound, fmt.Sprintf("model '%s' not found", req.Source))
+			return
+		}
+		dest := normalizeModelName(req.Destination)
+		source.Name, source.Model = dest, dest
+		f.models[dest] = source
+		f.Licenses[dest] = f.Licenses[normalizeModelName(req.Source)]
+	case "/api/delete":
+		if !exists {
+			f.error(w, http.StatusNotFound, fmt.Sprintf("model '%s' not found", name))
+			return
+		}
+		delete(f.models, model.Name)
+	case "/api/generate":
+		if !exists || f.FailLoad {
+			f.error(w, http.StatusInternalServerError, "failed to load model")
+			return
+		}
+		f.reply(w, api.GenerateResponse{Model: name, Response: "re: " + req.Prompt, Done: true})
+	case "/api/embeddings":
+		if !exists {
+			f.error(w, http.StatusNotFound, fmt.Sprintf("model '%s' not found", name))
+			return
+		}
+		sum := sha256.Sum256([]byte(req.Prompt))
+		vec := make([]float64, 8)
+		for i := range vec {
+			vec[i] = float64(sum[i])
+		}
+		f.reply(w, api.EmbeddingResponse{Embedding: vec})
+	default:
+		f.error(w, http.StatusNotFound, "not found")
+	}
+}
+
+func (f *fakeOllama) reply(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func (f *fakeOllama) error(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
+
+// resourceSchema returns the schema of r.
+func resourceSchema(t *testing.T, r resource.Resource) resource.SchemaResponse {
+	t.Helper()
+
+	var resp resource.SchemaResponse
+	r.Schema(context.Background(), resource.SchemaRequest{}, &resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("schema: %v", resp.Diagnostics)
+	}
+	return resp
+}
+
+// applyResource runs Create, Update or Delete of r like Terraform would for
+// a change from prior to planned, where a nil value stands for no object.
+// It returns the new state into out, also when the apply failed, unless there
+// is none.
+func applyResource[T any](t *testing.T, r resource.Resource, prior, planned *T, out *T) diag.Diagnostics {
+	t.Helper()
+
+	ctx := context.Background()
+	s := resourceSchema(t, r).Schema
+	null := tftypes.NewValue(s.Type().TerraformType(ctx), nil)
+
+	state := tfsdk.State{Schema: s, Raw: null}
+	if prior != nil {
+		if diags := state.Set(ctx, prior); diags.HasError() {
+			t.Fatalf("prior state: %v", diags)
+		}
+	}
+	plan := tfsdk.Plan{Schema: s, Raw: null}
+	if planned != nil {
+		if diags := plan.Set(ctx, planned); diags.HasError() {
+			t.Fatalf("plan: %v", diags)
+		}
+	}
+
+	var (
+		diags    diag.Diagnostics
+		newState tfsdk.State
+	)
+	switch {
+	case prior == nil:
+		resp := resource.CreateResponse{State: tfsdk.State{Schema: s, Raw: null}}
+		r.Create(ctx, resource.CreateRequest{Plan: plan}, &resp)
+		diags, newState = resp.Diagnostics, resp.State
+	case planned == nil:
+		resp := resource.DeleteResponse{State: state}
+		r.Delete(ctx, resource.DeleteRequest{State: state}, &resp)
+		return resp.Diagnostics
+	default:
+		resp := resource.UpdateResponse{State: state}
+		r.Update(ctx, resource.UpdateRequest{State: state, Plan: plan}, &resp)
+		diags, newState = resp.Diagnostics, resp.State
+	}
+
+	if out != nil && !newState.Raw.IsNull() {
+		diags.Append(newState.Get(ctx, out)...)
+	}
+	return diags
+}
+
+// importResource runs ImportState of r with id and then Read, like
+// `terraform import` does, and returns the imported state into out.
+func importResource[T any](t *testing.T, r resource.ResourceWithImportState, id string, out *T) diag.Diagnostics {
+	t.Helper()
+
+	ctx := context.Background()
+	s := resourceSchema(t, r).Schema
+
+	resp := resource.ImportStateResponse{State: tfsdk.State{Schema: s, Raw: tftypes.NewValue(s.Type().TerraformType(ctx), nil)}}
+	r.ImportState(ctx, resource.ImportStateRequest{ID: id}, &resp)
+	if resp.Diagnostics.HasError() {
+		return resp.Diagnostics
+	}
+
+	var imported T
+	if diags := resp.State.Get(ctx, &imported); diags.HasError() {
+		return diags
+	}
+	found, diags := readResource(t, r, &imported, out)
+	if !found && !diags.HasError() {
+		diags.AddError("import", "the imported resource was not found by Read")
+	}
+	return append(resp.Diagnostics, diags...)
+}
+
+// plannedChanges returns the top-level attributes Terraform would plan to
+// change for config against state: those configured to a value other than
+// the one in state. Attributes config leaves null are computed by the
+// provider and keep their state.
+func plannedChanges[T any](t *testing.T, r resource.Resource, config, state *T) []string {
+	t.Helper()
+
+	ctx := context.Background()
+	s := resourceSchema(t, r).Schema
+
+	attrs := func(v *T) map[string]tftypes.Value {
+		raw := tfsdk.State{Schema: s}
+		if diags := raw.Set(ctx, v); diags.HasError() {
+			t.Fatalf("set: %v", diags)
+		}
+		m := map[string]tftypes.Value{}
+		if err := raw.Raw.As(&m); err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+
+	var changed []string
+	prior := attrs(state)
+	for name, v := range attrs(config) {
+		if !v.IsNull() && !v.Equal(prior[name]) {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// readResource runs Read of r on prior and returns whether the resource still
+// exists, with its refreshed state in out.
+func readResource[T any](t *testing.T, r resource.Resource, prior *T, out *T) (bool, diag.Diagnostics) {
+	t.Helper()
+
+	ctx := context.Background()
+	s := resourceSchema(t, r).Schema
+
+	state := tfsdk.State{Schema: s}
+	if diags := state.Set(ctx, prior); diags.HasError() {
+		t.Fatalf("prior state: %v", diags)
+	}
+
+	resp := resource.ReadResponse{State: state}
+	r.Read(ctx, resource.ReadRequest{State: state}, &resp)
+	if resp.Diagnostics.HasError() || resp.State.Raw.IsNull() {
+		return false, resp.Diagnostics
+	}
+	return true, append(resp.Diagnostics, resp.State.Get(ctx, out)...)
+}
+
+// readDataSource runs Read of d with config and returns the result into out.
+func readDataSource[T any](t *testing.T, d datasource.DataSource, config *T, out *T) diag.Diagnostics {
+	t.Helper()
+
+	ctx := context.Background()
+	var schemaResp datasource.SchemaResponse
+	d.Schema(ctx, datasource.SchemaRequest{}, &schemaResp)
+	if schemaResp.Diagnostics.HasError() {
+		t.Fatalf("schema: %v", schemaResp.Diagnostics)
+	}
+	s := schemaResp.Schema
+
+	// tfsdk.Config cannot be set from a struct, so go through a state
+	raw := tfsdk.State{Schema: s}
+	if diags := raw.Set(ctx, config); diags.HasError() {
+		t.Fatalf("config: %v", diags)
+	}
+
+	resp := datasource.ReadResponse{State: tfsdk.State{Schema: s, Raw: tftypes.NewValue(s.Type().TerraformType(ctx), nil)}}
+	d.Read(ctx, datasource.ReadRequest{Config: tfsdk.Config{Schema: s, Raw: raw.Raw}}, &resp)
+	if resp.Diagnostics.HasError() {
+		return resp.Diagnostics
+	}
+	return append(resp.Diagnostics, resp.State.Get(ctx, out)...)
+}
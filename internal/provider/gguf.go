@@ -0,0 +1,150 @@
+package provider
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+const (
+	ggufMagic = "GGUF"
+
+	// ggufMaxString bounds strings read from a file, so a corrupt or hostile
+	// header cannot make the reader allocate arbitrary amounts of memory.
+	ggufMaxString = 64 << 20
+)
+
+// GGUF metadata value types.
+const (
+	ggufUint8 uint32 = iota
+	ggufInt8
+	ggufUint16
+	ggufInt16
+	ggufUint32
+	ggufInt32
+	ggufFloat32
+	ggufBool
+	ggufString
+	ggufArray
+	ggufUint64
+	ggufInt64
+	ggufFloat64
+)
+
+// ggufArraySummary stands in for array values, which are skipped rather than
+// read because token lists hold hundreds of thousands of entries.
+type ggufArraySummary struct {
+	Type  uint32
+	Count uint64
+}
+
+// readGGUFMetadata reads the key/value metadata of a GGUF file. Tensor data
+// is not read.
+func readGGUFMetadata(path string) (map[string]any, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := &ggufReader{r: bufio.NewReader(f)}
+
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(r.r, magic); err != nil {
+		return nil, fmt.Errorf("could not read GGUF header: %w", err)
+	}
+	if string(magic) != ggufMagic {
+		return nil, errors.New("not a GGUF file")
+	}
+
+	version := r.uint32()
+	if version < 2 || version > 3 {
+		return nil, fmt.Errorf("unsupported GGUF version %d", version)
+	}
+	r.uint64() // tensor count
+	count := r.uint64()
+
+	meta := map[string]any{}
+	for i := uint64(0); i < count && r.err == nil; i++ {
+		key := r.string()
+		meta[key] = r.value(r.uint32())
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("could not read GGUF metadata: %w", r.err)
+	}
+
+	return meta, nil
+}
+
+// ggufReader reads little-endian GGUF values and remembers the first error.
+type ggufReader struct {
+	r   *bufio.Reader
+	err error
+}
+
+func (r *ggufReader) read(n int) []byte {
+	if r.err != nil {
+		return make([]byte, n)
+	}
+	b := make([]byte, n)
+	_, r.err = io.ReadFull(r.r, b)
+	return b
+}
+
+func (r *ggufReader) uint32() uint32 { return binary.LittleEndian.Uint32(r.read(4)) }
+func (r *ggufReader) uint64() uint64 { return binary.LittleEndian.Uint64(r.read(8)) }
+
+func (r *ggufReader) string() string {
+	n := r.uint64()
+	if n > ggufMaxString {
+		if r.err == nil {
+			r.err = fmt.Errorf("string of %d bytes exceeds limit", n)
+		}
+		return ""
+	}
+	return string(r.read(int(n)))
+}
+
+func (r *ggufReader) value(t uint32) any {
+	switch t {
+	case ggufUint8:
+		return r.read(1)[0]
+	case ggufInt8:
+		return int8(r.read(1)[0])
+	case ggufUint16:
+		return binary.LittleEndian.Uint16(r.read(2))
+	case ggufInt16:
+		return int16(binary.LittleEndian.Uint16(r.read(2)))
+	case ggufUint32:
+		return r.uint32()
+	case ggufInt32:
+		return int32(r.uint32())
+	case ggufFloat32:
+		return math.Float32frombits(r.uint32())
+	case ggufBool:
+		return r.read(1)[0] != 0
+	case ggufString:
+		return r.string()
+	case ggufUint64:
+		return r.uint64()
+	case ggufInt64:
+		return int64(r.uint64())
+	case ggufFloat64:
+		return math.Float64frombits(r.uint64())
+	case ggufArray:
+		elem, n := r.uint32(), r.uint64()
+		for i := uint64(0); i < n && r.err == nil; i++ {
+			r.value(elem)
+		}
+		return ggufArraySummary{Type: elem, Count: n}
+	default:
+		if r.err == nil {
+			r.err = fmt.Errorf("unknown value type %d", t)
+		}
+		return nil
+	}
+}
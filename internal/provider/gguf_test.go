@@ -0,0 +1,147 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// ggufKV is a metadata entry written by writeGGUF.
+type ggufKV struct {
+	key   string
+	value any
+}
+
+// writeGGUF writes a GGUF v3 file with the given metadata and no tensors.
+// Values may be strings, uint32, int32, float32, bool, uint64 or []string.
+func writeGGUF(t *testing.T, path string, meta ...ggufKV) {
+	t.Helper()
+
+	var b bytes.Buffer
+	le := func(v any) { binary.Write(&b, binary.LittleEndian, v) }
+	str := func(s string) {
+		le(uint64(len(s)))
+		b.WriteString(s)
+	}
+
+	b.WriteString(ggufMagic)
+	le(uint32(3))
+	le(uint64(0))
+	le(uint64(len(meta)))
+
+	for _, kv := range meta {
+		str(kv.key)
+		switch v := kv.value.(type) {
+		case string:
+			le(ggufString)
+			str(v)
+		case uint32:
+			le(ggufUint32)
+			le(v)
+		case int32:
+			le(ggufInt32)
+			le(v)
+		case float32:
+			le(ggufFloat32)
+			le(v)
+		case bool:
+			le(ggufBool)
+			le(v)
+		case uint64:
+			le(ggufUint64)
+			le(v)
+		case []string:
+			le(ggufArray)
+			le(ggufString)
+			le(uint64(len(v)))
+			for _, s := range v {
+				str(s)
+			}
+		default:
+			t.Fatalf("unsupported GGUF test value %T", v)
+		}
+	}
+
+	if err := os.WriteFile(path, b.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadGGUFMetadata(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "model.gguf")
+	writeGGUF(t, p,
+		ggufKV{"general.architecture", "llama"},
+		ggufKV{"general.name", "Test Model"},
+		ggufKV{"llama.context_length", uint32(8192)},
+		ggufKV{"llama.rope.freq_base", float32(500000)},
+		ggufKV{"general.quantization_version", uint64(2)},
+		ggufKV{"tokenizer.ggml.add_bos_token", true},
+		ggufKV{"tokenizer.ggml.eos_token_id", int32(-1)},
+		ggufKV{"tokenizer.ggml.tokens", []string{"<s>", "</s>", "hello"}},
+	)
+
+	got, err := readGGUFMetadata(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]any{
+		"general.architecture":         "llama",
+		"general.name":                 "Test Model",
+		"llama.context_length":         uint32(8192),
+		"llama.rope.freq_base":         float32(500000),
+		"general.quantization_version": uint64(2),
+		"tokenizer.ggml.add_bos_token": true,
+		"tokenizer.ggml.eos_token_id":  int32(-1),
+		"tokenizer.ggml.tokens":        ggufArraySummary{Type: ggufString, Count: 3},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("readGGUFMetadata() = %#v, want %#v", got, want)
+	}
+}
+
+func TestReadGGUFMetadataErrors(t *testing.T) {
+	valid := filepath.Join(t.TempDir(), "valid.gguf")
+	writeGGUF(t, valid, ggufKV{"general.name", "truncated"})
+	validBytes, err := os.ReadFile(valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hugeString := append([]byte(ggufMagic), 3, 0, 0, 0)
+	hugeString = binary.LittleEndian.AppendUint64(hugeString, 0)
+	hugeString = binary.LittleEndian.AppendUint64(hugeString, 1)
+	hugeString = binary.LittleEndian.AppendUint64(hugeString, 1<<40)
+
+	unknownType := append([]byte(ggufMagic), 3, 0, 0, 0)
+	unknownType = binary.LittleEndian.AppendUint64(unknownType, 0)
+	unknownType = binary.LittleEndian.AppendUint64(unknownType, 1)
+	unknownType = binary.LittleEndian.AppendUint64(unknownType, 1)
+	unknownType = append(unknownType, 'k')
+	unknownType = binary.LittleEndian.AppendUint32(unknownType, 99)
+
+	tests := map[string][]byte{
+		"empty":            {},
+		"wrong magic":      []byte("GGML\x03\x00\x00\x00"),
+		"safetensors":      {8, 0, 0, 0, 0, 0, 0, 0, '{', '}'},
+		"version 1":        append([]byte(ggufMagic), 1, 0, 0, 0),
+		"truncated":        validBytes[:len(validBytes)-4],
+		"oversized string": hugeString,
+		"unknown type":     unknownType,
+	}
+
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := filepath.Join(t.TempDir(), "model.gguf")
+			if err := os.WriteFile(p, content, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if meta, err := readGGUFMetadata(p); err == nil {
+				t.Errorf("readGGUFMetadata() = %v, want error", meta)
+			}
+		})
+	}
+}
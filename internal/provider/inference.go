@@ -0,0 +1,86 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/ollama/ollama/api"
+)
+
+// generate runs req without streaming and returns the complete response.
+// The request is accounted against budget, which may be nil.
+func generate(ctx context.Context, client *api.Client, budget *inferenceBudget, req *api.GenerateRequest) (api.GenerateResponse, error) {
+	if err := budget.Reserve(ctx); err != nil {
+		return api.GenerateResponse{}, err
+	}
+
+	noStream := false
+	req.Stream = &noStream
+
+	var rsp api.GenerateResponse
+	err := client.Generate(ctx, req, func(r api.GenerateResponse) error {
+		rsp = r
+		return nil
+	})
+
+	budget.Record(ctx, rsp.EvalCount)
+	return rsp, err
+}
+
+// chat runs req without streaming and returns the complete response.
+// The request is accounted against budget, which may be nil.
+func chat(ctx context.Context, client *api.Client, budget *inferenceBudget, req *api.ChatRequest) (api.ChatResponse, error) {
+	if err := budget.Reserve(ctx); err != nil {
+		return api.ChatResponse{}, err
+	}
+
+	noStream := false
+	req.Stream = &noStream
+
+	var rsp api.ChatResponse
+	err := client.Chat(ctx, req, func(r api.ChatResponse) error {
+		rsp = r
+		return nil
+	})
+
+	budget.Record(ctx, rsp.EvalCount)
+	return rsp, err
+}
+
+// requestOptions converts the string values of an options attribute into the
+// typed values the API expects, e.g. "0.2" for temperature into a float.
+func requestOptions(ctx context.Context, m types.Map) (map[string]any, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	if m.IsNull() || m.IsUnknown() {
+		return nil, diags
+	}
+
+	var values map[string]string
+	if diags.Append(m.ElementsAs(ctx, &values, false)...); diags.HasError() {
+		return nil, diags
+	}
+
+	params := make(map[string][]string, len(values))
+	for k, v := range values {
+		params[k] = []string{v}
+	}
+
+	opts, err := api.FormatParams(params)
+	if err != nil {
+		diags.AddError("Invalid options", fmt.Sprintf("Could not convert inference options: %s", err))
+		return nil, diags
+	}
+
+	return opts, diags
+}
+
+// optionSeed returns the seed set in opts, or 0.
+func optionSeed(opts map[string]any) int64 {
+	if seed, ok := opts["seed"].(int64); ok {
+		return seed
+	}
+	return 0
+}
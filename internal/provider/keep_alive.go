@@ -0,0 +1,86 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// keepAlivePaths are the endpoints whose requests carry a keep_alive field.
+var keepAlivePaths = []string{"/api/generate", "/api/chat", "/api/embeddings"}
+
+// keepAliveClient is the HTTP client of API clients created by NewClient.
+var keepAliveClient = &http.Client{Transport: &keepAliveTransport{base: http.DefaultTransport}}
+
+// keepAliveTransport rewrites the keep_alive field of requests into seconds.
+// api.Duration has no MarshalJSON, so the api package encodes it as
+// {"Duration":<nanoseconds>}, which the server does not understand and
+// replaces with its default of five minutes.
+type keepAliveTransport struct {
+	base http.RoundTripper
+}
+
+func (t *keepAliveTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodPost || req.Body == nil || !slices.ContainsFunc(keepAlivePaths, func(p string) bool {
+		return strings.HasSuffix(req.URL.Path, p)
+	}) {
+		return t.base.RoundTrip(req)
+	}
+
+	b, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	if rewritten, ok := rewriteKeepAlive(b); ok {
+		b = rewritten
+	}
+
+	req = req.Clone(req.Context())
+	req.Body = io.NopCloser(bytes.NewReader(b))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(b)), nil
+	}
+	req.ContentLength = int64(len(b))
+
+	return t.base.RoundTrip(req)
+}
+
+// rewriteKeepAlive replaces a keep_alive object in the JSON request body b
+// with its number of seconds, or -1 for negative durations. It reports
+// whether b was changed.
+func rewriteKeepAlive(b []byte) ([]byte, bool) {
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(b, &body); err != nil {
+		return nil, false
+	}
+
+	raw, ok := body["keep_alive"]
+	if !ok || !bytes.HasPrefix(bytes.TrimSpace(raw), []byte("{")) {
+		return nil, false
+	}
+
+	var d struct {
+		Duration time.Duration
+	}
+	if err := json.Unmarshal(raw, &d); err != nil {
+		return nil, false
+	}
+
+	seconds := d.Duration.Seconds()
+	if d.Duration < 0 {
+		seconds = -1
+	}
+	body["keep_alive"], _ = json.Marshal(seconds)
+
+	rewritten, err := json.Marshal(body)
+	if err != nil {
+		return nil, false
+	}
+	return rewritten, true
+}
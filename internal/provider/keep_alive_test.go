@@ -0,0 +1,123 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+func TestRewriteKeepAlive(t *testing.T) {
+	tests := map[string]struct {
+		body string
+		// want is the keep_alive in the rewritten body, empty if b is
+		// left alone
+		want string
+	}{
+		"positive": {
+			body: `{"model":"llama3","keep_alive":{"Duration":300000000000}}`,
+			want: "300",
+		},
+		"fraction of a second": {
+			body: `{"model":"llama3","keep_alive":{"Duration":1500000000}}`,
+			want: "1.5",
+		},
+		"zero": {
+			body: `{"model":"llama3","keep_alive":{"Duration":0}}`,
+			want: "0",
+		},
+		"negative": {
+			body: `{"model":"llama3","keep_alive":{"Duration":-1}}`,
+			want: "-1",
+		},
+		"large negative": {
+			body: `{"model":"llama3","keep_alive":{"Duration":-300000000000}}`,
+			want: "-1",
+		},
+		"already seconds": {
+			body: `{"model":"llama3","keep_alive":300}`,
+		},
+		"already a string": {
+			body: `{"model":"llama3","keep_alive":"5m"}`,
+		},
+		"absent": {
+			body: `{"model":"llama3"}`,
+		},
+		"invalid json": {
+			body: `{"model":`,
+		},
+		"invalid duration": {
+			body: `{"model":"llama3","keep_alive":{"Duration":"5m"}}`,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, ok := rewriteKeepAlive([]byte(tt.body))
+			if ok != (tt.want != "") {
+				t.Fatalf("rewriteKeepAlive() changed = %v, want %v", ok, tt.want != "")
+			}
+			if !ok {
+				return
+			}
+
+			var body map[string]json.RawMessage
+			if err := json.Unmarshal(got, &body); err != nil {
+				t.Fatalf("rewritten body %s: %v", got, err)
+			}
+			if string(body["keep_alive"]) != tt.want {
+				t.Errorf("keep_alive = %s, want %s", body["keep_alive"], tt.want)
+			}
+			if string(body["model"]) != `"llama3"` {
+				t.Errorf("model = %s, want it unchanged", body["model"])
+			}
+		})
+	}
+}
+
+func TestKeepAliveTransport(t *testing.T) {
+	var got json.RawMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			KeepAlive json.RawMessage `json:"keep_alive"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		got = body.KeepAlive
+		json.NewEncoder(w).Encode(api.GenerateResponse{Done: true})
+	}))
+	t.Cleanup(srv.Close)
+
+	client, _, err := NewClient(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		keepAlive *api.Duration
+		want      string
+	}{
+		"positive": {keepAlive: &api.Duration{Duration: 10 * time.Minute}, want: "600"},
+		"zero":     {keepAlive: &api.Duration{Duration: 0}, want: "0"},
+		"negative": {keepAlive: &api.Duration{Duration: keepAliveForever}, want: "-1"},
+		"unset":    {},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got = nil
+			req := &api.GenerateRequest{Model: "llama3", Prompt: "hi", KeepAlive: tt.keepAlive}
+			if err := client.Generate(context.Background(), req, func(api.GenerateResponse) error { return nil }); err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("keep_alive sent = %s, want %q", got, tt.want)
+			}
+		})
+	}
+}
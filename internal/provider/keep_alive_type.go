@@ -0,0 +1,153 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/attr/xattr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/ollama/ollama/api"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ basetypes.StringTypable                    = KeepAliveType{}
+	_ basetypes.StringValuableWithSemanticEquals = KeepAliveValue{}
+	_ xattr.ValidateableAttribute                = KeepAliveValue{}
+)
+
+// keepAliveForever stands for negative keep_alive values, which keep the model
+// loaded indefinitely.
+const keepAliveForever = time.Duration(-1)
+
+// parseKeepAlive parses a keep_alive value the way the server does: a Go
+// duration such as "5m", or a number of seconds such as "300". Any negative
+// value means forever and is returned as keepAliveForever.
+func parseKeepAlive(s string) (time.Duration, error) {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		seconds, numErr := strconv.ParseFloat(s, 64)
+		if numErr != nil {
+			return 0, fmt.Errorf("invalid keep_alive %q, expected a duration such as \"5m\" or a number of seconds", s)
+		}
+		d = time.Duration(seconds * float64(time.Second))
+	}
+
+	if d < 0 {
+		return keepAliveForever, nil
+	}
+	return d, nil
+}
+
+// KeepAliveType is a string attribute holding how long a model stays loaded
+// after a request. Values for the same duration, such as "5m" and "300", and
+// all negative values are semantically equal.
+type KeepAliveType struct {
+	basetypes.StringType
+}
+
+func (t KeepAliveType) Equal(o attr.Type) bool {
+	other, ok := o.(KeepAliveType)
+	if !ok {
+		return false
+	}
+	return t.StringType.Equal(other.StringType)
+}
+
+func (t KeepAliveType) String() string {
+	return "KeepAliveType"
+}
+
+func (t KeepAliveType) ValueFromString(ctx context.Context, in basetypes.StringValue) (basetypes.StringValuable, diag.Diagnostics) {
+	return KeepAliveValue{StringValue: in}, nil
+}
+
+func (t KeepAliveType) ValueFromTerraform(ctx context.Context, in tftypes.Value) (attr.Value, error) {
+	attrValue, err := t.StringType.ValueFromTerraform(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+
+	stringValue, ok := attrValue.(basetypes.StringValue)
+	if !ok {
+		return nil, fmt.Errorf("unexpected value type of %T", attrValue)
+	}
+
+	return KeepAliveValue{StringValue: stringValue}, nil
+}
+
+func (t KeepAliveType) ValueType(ctx context.Context) attr.Value {
+	return KeepAliveValue{}
+}
+
+// KeepAliveValue is a value of KeepAliveType.
+type KeepAliveValue struct {
+	basetypes.StringValue
+}
+
+func (v KeepAliveValue) Equal(o attr.Value) bool {
+	other, ok := o.(KeepAliveValue)
+	if !ok {
+		return false
+	}
+	return v.StringValue.Equal(other.StringValue)
+}
+
+func (v KeepAliveValue) Type(ctx context.Context) attr.Type {
+	return KeepAliveType{}
+}
+
+// StringSemanticEquals compares the parsed durations.
+func (v KeepAliveValue) StringSemanticEquals(ctx context.Context, newValuable basetypes.StringValuable) (bool, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	newValue, ok := newValuable.(KeepAliveValue)
+	if !ok {
+		diags.AddError(
+			"Semantic Equality Check Error",
+			fmt.Sprintf("Expected value type %T but got value type %T. Please report this to the provider developers.", v, newValuable),
+		)
+		return false, diags
+	}
+
+	prior, err := parseKeepAlive(v.ValueString())
+	if err != nil {
+		return false, diags
+	}
+	current, err := parseKeepAlive(newValue.ValueString())
+	if err != nil {
+		return false, diags
+	}
+
+	return prior == current, diags
+}
+
+// ValidateAttribute rejects values the server would not accept.
+func (v KeepAliveValue) ValidateAttribute(ctx context.Context, req xattr.ValidateAttributeRequest, resp *xattr.ValidateAttributeResponse) {
+	if v.IsNull() || v.IsUnknown() {
+		return
+	}
+
+	if _, err := parseKeepAlive(v.ValueString()); err != nil {
+		resp.Diagnostics.AddAttributeError(req.Path, "Invalid keep_alive", err.Error())
+	}
+}
+
+// Duration returns the value for a request, or nil for the server default if
+// the value is null.
+func (v KeepAliveValue) Duration() *api.Duration {
+	if v.IsNull() || v.IsUnknown() {
+		return nil
+	}
+
+	d, err := parseKeepAlive(v.ValueString())
+	if err != nil {
+		return nil
+	}
+	return &api.Duration{Duration: d}
+}
@@ -0,0 +1,37 @@
+package provider
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseKeepAlive(t *testing.T) {
+	tests := map[string]time.Duration{
+		"5m":   5 * time.Minute,
+		"1h":   time.Hour,
+		"300":  300 * time.Second,
+		"1.5":  1500 * time.Millisecond,
+		"0":    0,
+		"0s":   0,
+		"-1":   keepAliveForever,
+		"-5m":  keepAliveForever,
+		"-300": keepAliveForever,
+	}
+
+	for s, want := range tests {
+		got, err := parseKeepAlive(s)
+		if err != nil {
+			t.Errorf("parseKeepAlive(%q) error: %v", s, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("parseKeepAlive(%q) = %v, want %v", s, got, want)
+		}
+	}
+
+	for _, s := range []string{"", "forever", "5 minutes"} {
+		if d, err := parseKeepAlive(s); err == nil {
+			t.Errorf("parseKeepAlive(%q) = %v, want error", s, d)
+		}
+	}
+}
@@ -0,0 +1,321 @@
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	// licenseNone is the SPDX value for a model without license text.
+	licenseNone = "NONE"
+	// licenseNoAssertion is the SPDX value for text no known license matches.
+	licenseNoAssertion = "NOASSERTION"
+
+	// licenseMinConfidence is the share of a license's markers that must be
+	// found for the text to be classified as that license.
+	licenseMinConfidence = 0.5
+)
+
+// knownLicense describes a license by phrases that are characteristic of its
+// text. Licenses that are not on the SPDX list use a LicenseRef- identifier.
+type knownLicense struct {
+	ID      string
+	Name    string
+	Markers []string
+	// Headers are phrases of the short notice that refers to the license
+	// instead of including it. They name the license outright, so a single
+	// one is enough.
+	Headers []string
+}
+
+// knownLicenses are the licenses models are commonly published under. Markers
+// are matched against normalized text, see normalizeLicenseText.
+var knownLicenses = []knownLicense{
+	{
+		ID:   "Apache-2.0",
+		Name: "Apache License 2.0",
+		Markers: []string{
+			"apache license",
+			"version 2.0, january 2004",
+			"terms and conditions for use, reproduction, and distribution",
+			"grant of patent license",
+			"licensed under the apache license, version 2.0",
+		},
+		Headers: []string{
+			"licensed under the apache license, version 2.0",
+		},
+	},
+	{
+		ID:   "MIT",
+		Name: "MIT License",
+		Markers: []string{
+			"permission is hereby granted, free of charge, to any person obtaining a copy",
+			"the above copyright notice and this permission notice shall be included",
+			`the software is provided "as is", without warranty of any kind`,
+		},
+		Headers: []string{
+			"licensed under the mit license",
+		},
+	},
+	{
+		ID:   "BSD-3-Clause",
+		Name: `BSD 3-Clause "New" or "Revised" License`,
+		Markers: []string{
+			"redistribution and use in source and binary forms",
+			"redistributions in binary form must reproduce the above copyright notice",
+			"neither the name of",
+			`this software is provided by the copyright holders and contributors "as is"`,
+		},
+	},
+	{
+		ID:   "BSD-2-Clause",
+		Name: `BSD 2-Clause "Simplified" License`,
+		Markers: []string{
+			"redistribution and use in source and binary forms",
+			"redistributions in binary form must reproduce the above copyright notice",
+			`this software is provided by the copyright holders and contributors "as is"`,
+		},
+	},
+	{
+		ID:   "CC-BY-4.0",
+		Name: "Creative Commons Attribution 4.0 International",
+		Markers: []string{
+			"creative commons",
+			"attribution 4.0 international",
+		},
+	},
+	{
+		ID:   "CC-BY-SA-4.0",
+		Name: "Creative Commons Attribution Share Alike 4.0 International",
+		Markers: []string{
+			"creative commons",
+			"attribution-sharealike 4.0 international",
+		},
+	},
+	{
+		ID:   "CC-BY-NC-4.0",
+		Name: "Creative Commons Attribution Non Commercial 4.0 International",
+		Markers: []string{
+			"creative commons",
+			"attribution-noncommercial 4.0 international",
+		},
+	},
+	{
+		ID:   "BigScience-OpenRAIL-M",
+		Name: "BigScience Open RAIL-M License",
+		Markers: []string{
+			"bigscience open rail-m license",
+			"use-based restrictions",
+		},
+	},
+	{
+		ID:   "LicenseRef-Llama-2-Community",
+		Name: "Llama 2 Community License Agreement",
+		Markers: []string{
+			"llama 2 community license agreement",
+			"llama 2 version release date: july 18, 2023",
+			"700 million monthly active users",
+			"llama materials",
+		},
+	},
+	{
+		ID:   "LicenseRef-Llama-3-Community",
+		Name: "Meta Llama 3 Community License Agreement",
+		Markers: []string{
+			"meta llama 3 community license agreement",
+			"meta llama 3 version release date: april 18, 2024",
+			"700 million monthly active users",
+			"built with meta llama 3",
+		},
+	},
+	{
+		ID:   "LicenseRef-Llama-3.1-Community",
+		Name: "Llama 3.1 Community License Agreement",
+		Markers: []string{
+			"llama 3.1 community license agreement",
+			"llama 3.1 version release date: july 23, 2024",
+			"700 million monthly active users",
+			"built with llama",
+		},
+	},
+	{
+		ID:   "LicenseRef-Llama-3.2-Community",
+		Name: "Llama 3.2 Community License Agreement",
+		Markers: []string{
+			"llama 3.2 community license agreement",
+			"llama 3.2 version release date: september 25, 2024",
+			"700 million monthly active users",
+			"built with llama",
+		},
+	},
+	{
+		ID:   "LicenseRef-Gemma-Terms-of-Use",
+		Name: "Gemma Terms of Use",
+		Markers: []string{
+			"gemma terms of use",
+			"ai.google.dev/gemma/terms",
+			"model derivatives",
+			"gemma prohibited use policy",
+		},
+	},
+	{
+		ID:   "LicenseRef-Tongyi-Qianwen",
+		Name: "Tongyi Qianwen License Agreement",
+		Markers: []string{
+			"tongyi qianwen license agreement",
+			"alibaba cloud",
+			"100 million monthly active users",
+		},
+	},
+	{
+		ID:   "LicenseRef-Mistral-Research",
+		Name: "Mistral AI Research License",
+		Markers: []string{
+			"mistral ai research license",
+			"research purposes",
+			"mistral models",
+		},
+	},
+}
+
+var (
+	spdxIdentifierPattern = regexp.MustCompile(`(?i)spdx-license-identifier:[ \t]*([A-Za-z0-9.+:() \t-]+)`)
+	licenseSpacePattern   = regexp.MustCompile(`\s+`)
+	licenseQuoteReplacer  = strings.NewReplacer("\u201c", `"`, "\u201d", `"`, "\u2018", "'", "\u2019", "'")
+)
+
+// licenseMatch is the result of classifying a license text.
+type licenseMatch struct {
+	ID         string
+	Name       string
+	Confidence float64
+}
+
+// classifyLicense maps license text to an SPDX identifier. The confidence is
+// 1 for texts that state their identifier, e.g. in an SPDX-License-Identifier
+// line or a license header, and the share of matched markers otherwise. Text
+// without a match of at least licenseMinConfidence, that matches two licenses
+// equally well or that states a compound expression such as
+// "Apache-2.0 OR MIT" is NOASSERTION.
+func classifyLicense(text string) licenseMatch {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return licenseMatch{ID: licenseNone, Confidence: 1}
+	}
+
+	// a stated identifier is trusted even if it is not a known license
+	if m := spdxIdentifierPattern.FindStringSubmatch(text); m != nil {
+		id := strings.TrimSpace(m[1])
+		// a policy cannot tell which license of an expression applies
+		if strings.ContainsAny(id, "() \t") {
+			return licenseMatch{ID: licenseNoAssertion}
+		}
+		if l, ok := knownLicenseByID(id); ok {
+			return l
+		}
+		return licenseMatch{ID: id, Confidence: 1}
+	}
+	if l, ok := knownLicenseByID(text); ok {
+		return l
+	}
+
+	normalized := normalizeLicenseText(text)
+
+	// a header names its license, but one naming two is a choice of licenses
+	var headed []knownLicense
+	for _, l := range knownLicenses {
+		if slices.ContainsFunc(l.Headers, func(h string) bool { return strings.Contains(normalized, h) }) {
+			headed = append(headed, l)
+		}
+	}
+	switch len(headed) {
+	case 0:
+	case 1:
+		return licenseMatch{ID: headed[0].ID, Name: headed[0].Name, Confidence: 1}
+	default:
+		return licenseMatch{ID: licenseNoAssertion}
+	}
+
+	best := licenseMatch{ID: licenseNoAssertion}
+	bestMatched := 0
+	ambiguous := false
+	for _, l := range knownLicenses {
+		matched := 0
+		for _, marker := range l.Markers {
+			if strings.Contains(normalized, marker) {
+				matched++
+			}
+		}
+		confidence := float64(matched) / float64(len(l.Markers))
+
+		// on a tie the license with more matching markers is more specific,
+		// e.g. BSD-3-Clause over BSD-2-Clause; a tie on both cannot be
+		// decided, e.g. text with only the markers Llama 3.1 and 3.2 share
+		switch {
+		case confidence > best.Confidence || (confidence == best.Confidence && matched > bestMatched):
+			best = licenseMatch{ID: l.ID, Name: l.Name, Confidence: confidence}
+			bestMatched = matched
+			ambiguous = false
+		case confidence == best.Confidence && matched == bestMatched && matched > 0:
+			ambiguous = true
+		}
+	}
+
+	if ambiguous || best.Confidence < licenseMinConfidence {
+		return licenseMatch{ID: licenseNoAssertion}
+	}
+	return best
+}
+
+// knownLicenseByID looks up a known license by its identifier, compared case
+// insensitively.
+func knownLicenseByID(id string) (licenseMatch, bool) {
+	for _, l := range knownLicenses {
+		if strings.EqualFold(l.ID, id) {
+			return licenseMatch{ID: l.ID, Name: l.Name, Confidence: 1}, true
+		}
+	}
+	return licenseMatch{}, false
+}
+
+// normalizeLicenseText lowercases text, straightens typographic quotes and
+// collapses whitespace, so markers match regardless of line wrapping.
+func normalizeLicenseText(text string) string {
+	text = licenseQuoteReplacer.Replace(strings.ToLower(text))
+	return licenseSpacePattern.ReplaceAllString(text, " ")
+}
+
+// licensePolicy decides which licenses models may be pulled under.
+type licensePolicy struct {
+	Allow         []string
+	Deny          []string
+	MinConfidence float64
+}
+
+// Check returns why a model under license m is not allowed, or an empty
+// string if it is. Identifiers are compared case insensitively.
+func (p *licensePolicy) Check(m licenseMatch) string {
+	if p == nil {
+		return ""
+	}
+
+	for _, id := range p.Deny {
+		if strings.EqualFold(id, m.ID) {
+			return fmt.Sprintf("license %s is denied", m.ID)
+		}
+	}
+	if m.Confidence < p.MinConfidence {
+		return fmt.Sprintf("license %s was classified with confidence %.2f, below the required %.2f", m.ID, m.Confidence, p.MinConfidence)
+	}
+	if len(p.Allow) == 0 {
+		return ""
+	}
+	for _, id := range p.Allow {
+		if strings.EqualFold(id, m.ID) {
+			return ""
+		}
+	}
+	return fmt.Sprintf("license %s is not in the allowed licenses %s", m.ID, strings.Join(p.Allow, ", "))
+}
@@ -0,0 +1,201 @@
+package provider
+
+import "testing"
+
+const (
+	mitLicenseText = `MIT License
+
+Copyright (c) Microsoft Corporation.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.`
+
+	bsd2LicenseText = `BSD 2-Clause License
+
+Copyright (c) 2023, the authors
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+1. Redistributions of source code must retain the above copyright notice, this
+   list of conditions and the following disclaimer.
+
+2. Redistributions in binary form must reproduce the above copyright notice,
+   this list of conditions and the following disclaimer in the documentation
+   and/or other materials provided with the distribution.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED.`
+
+	bsd3LicenseText = `BSD 3-Clause License
+
+Copyright (c) 2023, the authors
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+1. Redistributions of source code must retain the above copyright notice, this
+   list of conditions and the following disclaimer.
+
+2. Redistributions in binary form must reproduce the above copyright notice,
+   this list of conditions and the following disclaimer in the documentation
+   and/or other materials provided with the distribution.
+
+3. Neither the name of the copyright holder nor the names of its
+   contributors may be used to endorse or promote products derived from
+   this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED.`
+
+	apacheLicenseText = `
+                                 Apache License
+                           Version 2.0, January 2004
+                        http://www.apache.org/licenses/
+
+   TERMS AND CONDITIONS FOR USE, REPRODUCTION, AND DISTRIBUTION
+
+   1. Definitions.
+
+      "License" shall mean the terms and conditions for use, reproduction,
+      and distribution as defined by Sections 1 through 9 of this document.
+
+   3. Grant of Patent License. Subject to the terms and conditions of
+      this License, each Contributor hereby grants to You a perpetual,
+      worldwide, non-exclusive, no-charge, royalty-free, irrevocable
+      (except as stated in this section) patent license to make, have made,
+      use, offer to sell, sell, import, and otherwise transfer the Work.`
+
+	apacheHeaderText = `Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.`
+
+	llama31LicenseText = `LLAMA 3.1 COMMUNITY LICENSE AGREEMENT
+Llama 3.1 Version Release Date: July 23, 2024
+
+“Agreement” means the terms and conditions for use, reproduction, distribution and modification of the
+Llama Materials set forth herein.
+
+i. If you distribute or make available the Llama Materials (or any derivative works
+thereof), or a product or service (including another AI model) that contains any of them, you shall (A)
+provide a copy of this Agreement with any such Llama Materials; and (B) prominently display “Built with
+Llama” on a related website, user interface, blogpost, about page, or product documentation.
+
+2. Additional Commercial Terms. If, on the Llama 3.1 version release date, the monthly active users
+of the products or services made available by or for Licensee, or Licensee’s affiliates, is greater than 700
+million monthly active users in the preceding calendar month, you must request a license from Meta.`
+
+	llama32LicenseText = `LLAMA 3.2 COMMUNITY LICENSE AGREEMENT
+Llama 3.2 Version Release Date: September 25, 2024
+
+“Agreement” means the terms and conditions for use, reproduction, distribution
+and modification of the Llama Materials set forth herein.
+
+i. If you distribute or make available the Llama Materials (or any derivative works thereof),
+or a product or service (including another AI model) that contains any of them, you shall (A) provide
+a copy of this Agreement with any such Llama Materials; and (B) prominently display “Built with Llama”
+on a related website, user interface, blogpost, about page, or product documentation.
+
+2. Additional Commercial Terms. If, on the Llama 3.2 version release date, the monthly active users
+of the products or services made available by or for Licensee, or Licensee’s affiliates,
+is greater than 700 million monthly active users in the preceding calendar month, you must request
+a license from Meta.`
+
+	// llamaSharedText only has the passages Llama 3.1 and 3.2 share.
+	llamaSharedText = `i. If you distribute or make available the Llama Materials, you shall prominently display “Built with
+Llama” on a related website. If the monthly active users is greater than 700 million monthly active users
+in the preceding calendar month, you must request a license from Meta.`
+)
+
+func TestClassifyLicense(t *testing.T) {
+	tests := map[string]struct {
+		text       string
+		want       string
+		confidence float64
+	}{
+		"empty":                     {text: " \n", want: licenseNone, confidence: 1},
+		"spdx identifier":           {text: "SPDX-License-Identifier: apache-2.0", want: "Apache-2.0", confidence: 1},
+		"unknown spdx identifier":   {text: "// SPDX-License-Identifier: MPL-2.0", want: "MPL-2.0", confidence: 1},
+		"bare identifier":           {text: "mit", want: "MIT", confidence: 1},
+		"mit":                       {text: mitLicenseText, want: "MIT", confidence: 1},
+		"bsd 2-clause":              {text: bsd2LicenseText, want: "BSD-2-Clause", confidence: 1},
+		"bsd 3-clause":              {text: bsd3LicenseText, want: "BSD-3-Clause", confidence: 1},
+		"apache":                    {text: apacheLicenseText, want: "Apache-2.0", confidence: 0.8},
+		"llama 3.1":                 {text: llama31LicenseText, want: "LicenseRef-Llama-3.1-Community", confidence: 1},
+		"llama 3.2":                 {text: llama32LicenseText, want: "LicenseRef-Llama-3.2-Community", confidence: 1},
+		"llama 3.1 and 3.2 tie":     {text: llamaSharedText, want: licenseNoAssertion},
+		"unrelated text":            {text: "All rights reserved.", want: licenseNoAssertion},
+		"below the minimum":         {text: "Apache License\nVersion 2.0, January 2004", want: licenseNoAssertion},
+		"apache header":             {text: apacheHeaderText, want: "Apache-2.0", confidence: 1},
+		"mit header":                {text: "This model is licensed under the MIT License.", want: "MIT", confidence: 1},
+		"two headers":               {text: "The code is licensed under the MIT License. The weights are licensed under the Apache License, Version 2.0.", want: licenseNoAssertion},
+		"spdx in a comment":         {text: "/* SPDX-License-Identifier: MIT */", want: "MIT", confidence: 1},
+		"spdx or expression":        {text: "SPDX-License-Identifier: Apache-2.0 OR MIT", want: licenseNoAssertion},
+		"spdx with expression":      {text: "SPDX-License-Identifier: GPL-2.0-only WITH Classpath-exception-2.0", want: licenseNoAssertion},
+		"spdx parenthesized":        {text: "SPDX-License-Identifier: (MIT AND BSD-2-Clause)\n", want: licenseNoAssertion},
+		"spdx document ref":         {text: "SPDX-License-Identifier: DocumentRef-spdx-tool-1.2:LicenseRef-MIT-Style-2", want: "DocumentRef-spdx-tool-1.2:LicenseRef-MIT-Style-2", confidence: 1},
+		"wrapped with curly quotes": {text: "THE SOFTWARE IS PROVIDED “AS IS”,\n  WITHOUT WARRANTY OF ANY KIND. Permission is hereby granted, free of charge, to any person\nobtaining a copy", want: "MIT", confidence: 2.0 / 3},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := classifyLicense(tt.text)
+			if got.ID != tt.want || got.Confidence != tt.confidence {
+				t.Errorf("classifyLicense() = %s (%.2f), want %s (%.2f)", got.ID, got.Confidence, tt.want, tt.confidence)
+			}
+		})
+	}
+}
+
+func TestLicensePolicyCheck(t *testing.T) {
+	mit := licenseMatch{ID: "MIT", Confidence: 1}
+	unsure := licenseMatch{ID: "BSD-2-Clause", Confidence: 0.67}
+
+	tests := map[string]struct {
+		policy  *licensePolicy
+		match   licenseMatch
+		allowed bool
+	}{
+		"no policy":            {policy: nil, match: mit, allowed: true},
+		"empty policy":         {policy: &licensePolicy{}, match: mit, allowed: true},
+		"allowed":              {policy: &licensePolicy{Allow: []string{"mit"}}, match: mit, allowed: true},
+		"not allowed":          {policy: &licensePolicy{Allow: []string{"Apache-2.0"}}, match: mit},
+		"denied":               {policy: &licensePolicy{Deny: []string{"MIT"}}, match: mit},
+		"deny wins over allow": {policy: &licensePolicy{Allow: []string{"MIT"}, Deny: []string{"MIT"}}, match: mit},
+		"below confidence":     {policy: &licensePolicy{MinConfidence: 0.8}, match: unsure},
+		"at confidence":        {policy: &licensePolicy{MinConfidence: 0.67}, match: unsure, allowed: true},
+		"deny unclassified":    {policy: &licensePolicy{Deny: []string{licenseNoAssertion}}, match: licenseMatch{ID: licenseNoAssertion}},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			reason := tt.policy.Check(tt.match)
+			if (reason == "") != tt.allowed {
+				t.Errorf("Check() = %q, want allowed %v", reason, tt.allowed)
+			}
+		})
+	}
+}
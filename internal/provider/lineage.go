@@ -0,0 +1,276 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/ollama/ollama/api"
+)
+
+const (
+	lineageJobPull   = "pull"
+	lineageJobCreate = "create"
+	lineageJobCopy   = "copy"
+
+	defaultLineageNamespace = "terraform-provider-ollama"
+	lineageSendTimeout      = 10 * time.Second
+
+	lineageRunEventSchema     = "https://openlineage.io/spec/2-0-2/OpenLineage.json#/$defs/RunEvent"
+	lineageVersionFacetSchema = "https://openlineage.io/spec/facets/1-0-1/DatasetVersionDatasetFacet.json#/$defs/DatasetVersionDatasetFacet"
+	lineageErrorFacetSchema   = "https://openlineage.io/spec/facets/1-0-1/ErrorMessageRunFacet.json#/$defs/ErrorMessageRunFacet"
+)
+
+// lineageEmitter sends OpenLineage run events for operations that produce
+// models. Models are datasets: the namespace identifies the host, the name is
+// the model name and the digest is recorded as the dataset version.
+//
+// Lineage is best effort: events that cannot be sent are logged and never
+// fail the operation they describe.
+type lineageEmitter struct {
+	transport lineageTransport
+	client    *api.Client
+	namespace string
+	dataset   string
+	producer  string
+}
+
+// lineageTransport delivers a serialized run event.
+type lineageTransport interface {
+	Send(ctx context.Context, event []byte) error
+}
+
+// lineageDataset is a model taking part in a run. An empty digest is looked up
+// on the host when the run finishes.
+type lineageDataset struct {
+	Name   string
+	Digest string
+}
+
+// lineageBase returns the base model of mf as run input. Models created from
+// local weights have no input dataset.
+func lineageBase(mf *modelfile, dir string) []lineageDataset {
+	if mf.From == "" || isLocalModelPath(mf.From, dir) {
+		return nil
+	}
+	return []lineageDataset{{Name: mf.From}}
+}
+
+// lineageRun is a started run. A nil run is a no-op.
+type lineageRun struct {
+	emitter *lineageEmitter
+	id      string
+	job     string
+	inputs  []lineageDataset
+	outputs []lineageDataset
+}
+
+// Start emits the START event of a run of job, e.g. a pull of a model. A nil
+// emitter returns a nil run, so callers do not need to check whether lineage
+// is enabled.
+func (l *lineageEmitter) Start(ctx context.Context, job string, inputs, outputs []lineageDataset) *lineageRun {
+	if l == nil {
+		return nil
+	}
+
+	// one job per operation and model, e.g. "pull.llama3:latest"
+	for _, d := range outputs {
+		job += "." + normalizeModelName(d.Name)
+	}
+
+	run := &lineageRun{emitter: l, id: uuid.Must(uuid.NewV7()).String(), job: job, inputs: inputs, outputs: outputs}
+	l.emit(ctx, run, "START", nil)
+	return run
+}
+
+// Finish emits the COMPLETE event of the run, or the FAIL event if err is set.
+func (r *lineageRun) Finish(ctx context.Context, err error) {
+	if r == nil {
+		return
+	}
+
+	if err != nil {
+		r.emitter.emit(ctx, r, "FAIL", err)
+		return
+	}
+
+	r.inputs = r.emitter.resolveDigests(ctx, r.inputs)
+	r.outputs = r.emitter.resolveDigests(ctx, r.outputs)
+	r.emitter.emit(ctx, r, "COMPLETE", nil)
+}
+
+func (l *lineageEmitter) resolveDigests(ctx context.Context, datasets []lineageDataset) []lineageDataset {
+	resolved := make([]lineageDataset, len(datasets))
+	for i, d := range datasets {
+		resolved[i] = d
+		if d.Digest != "" {
+			continue
+		}
+		listed, err := lookupModel(ctx, l.client, d.Name)
+		if err != nil {
+			tflog.Warn(ctx, fmt.Sprintf("could not look up digest of %s for lineage: %s", d.Name, err))
+			continue
+		}
+		if listed != nil {
+			resolved[i].Digest = listed.Digest
+		}
+	}
+	return resolved
+}
+
+func (l *lineageEmitter) emit(ctx context.Context, run *lineageRun, eventType string, runErr error) {
+	event := map[string]any{
+		"eventType": eventType,
+		"eventTime": time.Now().UTC().Format(time.RFC3339Nano),
+		"run":       map[string]any{"runId": run.id, "facets": l.runFacets(runErr)},
+		"job": map[string]any{
+			"namespace": l.namespace,
+			"name":      run.job,
+		},
+		"inputs":    l.datasets(run.inputs),
+		"outputs":   l.datasets(run.outputs),
+		"producer":  l.producer,
+		"schemaURL": lineageRunEventSchema,
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		tflog.Warn(ctx, fmt.Sprintf("could not encode lineage event: %s", err))
+		return
+	}
+
+	// send even if the operation was cancelled, a FAIL event is still useful
+	sendCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), lineageSendTimeout)
+	defer cancel()
+
+	if err := l.transport.Send(sendCtx, data); err != nil {
+		tflog.Warn(ctx, fmt.Sprintf("could not send %s lineage event for %s: %s", eventType, run.job, err))
+		return
+	}
+	tflog.Debug(ctx, fmt.Sprintf("sent %s lineage event for run %s", eventType, run.id))
+}
+
+func (l *lineageEmitter) runFacets(err error) map[string]any {
+	if err == nil {
+		return map[string]any{}
+	}
+	return map[string]any{
+		"errorMessage": map[string]any{
+			"_producer":           l.producer,
+			"_schemaURL":          lineageErrorFacetSchema,
+			"message":             err.Error(),
+			"programmingLanguage": "go",
+		},
+	}
+}
+
+func (l *lineageEmitter) datasets(datasets []lineageDataset) []map[string]any {
+	out := []map[string]any{}
+	for _, d := range datasets {
+		facets := map[string]any{}
+		if d.Digest != "" {
+			facets["version"] = map[string]any{
+				"_producer":      l.producer,
+				"_schemaURL":     lineageVersionFacetSchema,
+				"datasetVersion": d.Digest,
+			}
+		}
+		out = append(out, map[string]any{
+			"namespace": l.dataset,
+			"name":      normalizeModelName(d.Name),
+			"facets":    facets,
+		})
+	}
+	return out
+}
+
+// httpLineageTransport posts events to an OpenLineage HTTP endpoint such as
+// Marquez' /api/v1/lineage.
+type httpLineageTransport struct {
+	url    string
+	apiKey string
+	client *http.Client
+}
+
+func (t *httpLineageTransport) Send(ctx context.Context, event []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(event))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if t.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+t.apiKey)
+	}
+
+	rsp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(rsp.Body, 1024))
+		return fmt.Errorf("%s: %s", rsp.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
+
+// fileLineageTransport appends events to a file, one JSON document per line.
+type fileLineageTransport struct {
+	path string
+
+	mu sync.Mutex
+}
+
+func (t *fileLineageTransport) Send(ctx context.Context, event []byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	f, err := os.OpenFile(t.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(event, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// configureLineage builds the lineage emitter from the provider configuration.
+func configureLineage(config *OllamaProviderLineageModel, client *api.Client, base *url.URL, httpClient *http.Client, version string) (*lineageEmitter, error) {
+	var transport lineageTransport
+	switch {
+	case config.URL.ValueString() != "" && config.File.ValueString() != "":
+		return nil, errors.New("only one of url and file can be set")
+	case config.URL.ValueString() != "":
+		transport = &httpLineageTransport{url: config.URL.ValueString(), apiKey: config.APIKey.ValueString(), client: httpClient}
+	case config.File.ValueString() != "":
+		transport = &fileLineageTransport{path: config.File.ValueString()}
+	default:
+		return nil, errors.New("one of url or file must be set")
+	}
+
+	namespace := config.Namespace.ValueString()
+	if namespace == "" {
+		namespace = defaultLineageNamespace
+	}
+
+	return &lineageEmitter{
+		transport: transport,
+		client:    client,
+		namespace: namespace,
+		dataset:   "ollama://" + base.Host + base.Path,
+		producer:  "https://github.com/hashicorp/terraform-provider-ollama/tree/" + version,
+	}, nil
+}
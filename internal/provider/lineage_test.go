@@ -0,0 +1,363 @@
+package provider
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// lineageEvent is the part of a run event the tests look at.
+type lineageEvent struct {
+	EventType string `json:"eventType"`
+	EventTime string `json:"eventTime"`
+	Run       struct {
+		RunID  string `json:"runId"`
+		Facets struct {
+			ErrorMessage *struct {
+				Message string `json:"message"`
+			} `json:"errorMessage"`
+		} `json:"facets"`
+	} `json:"run"`
+	Job struct {
+		Namespace string `json:"namespace"`
+		Name      string `json:"name"`
+	} `json:"job"`
+	Inputs    []lineageEventDataset `json:"inputs"`
+	Outputs   []lineageEventDataset `json:"outputs"`
+	Producer  string                `json:"producer"`
+	SchemaURL string                `json:"schemaURL"`
+}
+
+type lineageEventDataset struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Facets    struct {
+		Version *struct {
+			DatasetVersion string `json:"datasetVersion"`
+		} `json:"version"`
+	} `json:"facets"`
+}
+
+// version returns the dataset version of d, or "" if it has none.
+func (d lineageEventDataset) version() string {
+	if d.Facets.Version == nil {
+		return ""
+	}
+	return d.Facets.Version.DatasetVersion
+}
+
+// lineageCollector is an OpenLineage HTTP endpoint that records the events
+// posted to it.
+type lineageCollector struct {
+	mu     sync.Mutex
+	events []lineageEvent
+	auth   []string
+	status int
+}
+
+func (c *lineageCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.status != 0 {
+		http.Error(w, "collector is down", c.status)
+		return
+	}
+
+	var event lineageEvent
+	if err := json.NewDecoder(r.Body).Decode(&event); err != nil || r.Header.Get("Content-Type") != "application/json" {
+		http.Error(w, "invalid event", http.StatusBadRequest)
+		return
+	}
+	c.events = append(c.events, event)
+	c.auth = append(c.auth, r.Header.Get("Authorization"))
+}
+
+func (c *lineageCollector) Events() []lineageEvent {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]lineageEvent(nil), c.events...)
+}
+
+// newTestLineage returns an emitter with the given configuration for a fake
+// server on which llama3 gets the digest sha256:1111 once it is added.
+func newTestLineage(t *testing.T, config *OllamaProviderLineageModel) (*fakeOllama, *lineageEmitter) {
+	t.Helper()
+
+	f, srv, client := newFakeOllama(t)
+	f.Digests["llama3:latest"] = "sha256:1111"
+
+	base, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := configureLineage(config, client, base, http.DefaultClient, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f, l
+}
+
+func TestLineageHTTP(t *testing.T) {
+	collector := &lineageCollector{}
+	srv := httptest.NewServer(collector)
+	t.Cleanup(srv.Close)
+
+	f, l := newTestLineage(t, &OllamaProviderLineageModel{
+		URL:    types.StringValue(srv.URL),
+		APIKey: types.StringValue("secret"),
+	})
+	ctx := context.Background()
+
+	run := l.Start(ctx, lineageJobPull, nil, []lineageDataset{{Name: "llama3"}})
+	f.AddModel("llama3")
+	run.Finish(ctx, nil)
+
+	events := collector.Events()
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want START and COMPLETE", len(events))
+	}
+	start, complete := events[0], events[1]
+
+	if start.EventType != "START" || complete.EventType != "COMPLETE" {
+		t.Errorf("event types = %s, %s, want START, COMPLETE", start.EventType, complete.EventType)
+	}
+	if start.Run.RunID == "" || start.Run.RunID != complete.Run.RunID {
+		t.Errorf("run ids = %q, %q, want the same id", start.Run.RunID, complete.Run.RunID)
+	}
+	for _, e := range events {
+		if e.Job.Namespace != defaultLineageNamespace || e.Job.Name != "pull.llama3:latest" {
+			t.Errorf("%s job = %s/%s, want %s/pull.llama3:latest", e.EventType, e.Job.Namespace, e.Job.Name, defaultLineageNamespace)
+		}
+		if e.Producer != "https://github.com/hashicorp/terraform-provider-ollama/tree/test" || e.SchemaURL != lineageRunEventSchema {
+			t.Errorf("%s producer = %s, schema = %s", e.EventType, e.Producer, e.SchemaURL)
+		}
+		if e.EventTime == "" {
+			t.Errorf("%s has no event time", e.EventType)
+		}
+		if len(e.Inputs) != 0 || len(e.Outputs) != 1 {
+			t.Fatalf("%s has %d inputs and %d outputs, want 0 and 1", e.EventType, len(e.Inputs), len(e.Outputs))
+		}
+		if want := l.dataset; e.Outputs[0].Namespace != want || e.Outputs[0].Name != "llama3:latest" {
+			t.Errorf("%s output = %s/%s, want %s/llama3:latest", e.EventType, e.Outputs[0].Namespace, e.Outputs[0].Name, want)
+		}
+	}
+
+	// the digest is only known once the pull finished
+	if got := start.Outputs[0].version(); got != "" {
+		t.Errorf("START output version = %q, want none", got)
+	}
+	if got, want := complete.Outputs[0].version(), "sha256:1111"; got != want {
+		t.Errorf("COMPLETE output version = %q, want the digest %q", got, want)
+	}
+
+	for i, auth := range collector.auth {
+		if auth != "Bearer secret" {
+			t.Errorf("event %d Authorization = %q, want the api key", i, auth)
+		}
+	}
+}
+
+func TestLineageFail(t *testing.T) {
+	collector := &lineageCollector{}
+	srv := httptest.NewServer(collector)
+	t.Cleanup(srv.Close)
+
+	f, l := newTestLineage(t, &OllamaProviderLineageModel{URL: types.StringValue(srv.URL)})
+	ctx := context.Background()
+
+	f.AddModel("llama3")
+	run := l.Start(ctx, lineageJobCreate, []lineageDataset{{Name: "llama3"}}, []lineageDataset{{Name: "mario"}})
+	run.Finish(ctx, errors.New("invalid modelfile"))
+
+	events := collector.Events()
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want START and FAIL", len(events))
+	}
+	fail := events[1]
+	if fail.EventType != "FAIL" || fail.Job.Name != "create.mario:latest" {
+		t.Errorf("event = %s of %s, want FAIL of create.mario:latest", fail.EventType, fail.Job.Name)
+	}
+	if fail.Run.Facets.ErrorMessage == nil || fail.Run.Facets.ErrorMessage.Message != "invalid modelfile" {
+		t.Errorf("error facet = %+v, want the error", fail.Run.Facets.ErrorMessage)
+	}
+	if events[0].Run.Facets.ErrorMessage != nil {
+		t.Error("START event has an error facet")
+	}
+
+	// a failed run does not look up digests
+	if got := fail.Inputs[0].version(); got != "" {
+		t.Errorf("FAIL input version = %q, want none", got)
+	}
+	if got := f.Calls("/api/tags"); got != 0 {
+		t.Errorf("looked up digests %d times for a failed run", got)
+	}
+}
+
+func TestLineageResolveDigests(t *testing.T) {
+	f, l := newTestLineage(t, &OllamaProviderLineageModel{File: types.StringValue(filepath.Join(t.TempDir(), "lineage.jsonl"))})
+	f.AddModel("llama3")
+	f.AddModel("llama3-snapshot")
+
+	got := l.resolveDigests(context.Background(), []lineageDataset{
+		{Name: "llama3"},
+		{Name: "llama3-snapshot", Digest: "sha256:given"},
+		{Name: "missing"},
+	})
+
+	want := []lineageDataset{
+		{Name: "llama3", Digest: "sha256:1111"},
+		// a known digest is kept, not looked up again
+		{Name: "llama3-snapshot", Digest: "sha256:given"},
+		// a model that is gone has no version
+		{Name: "missing"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("resolveDigests() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("resolveDigests()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestLineageFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lineage.jsonl")
+	f, l := newTestLineage(t, &OllamaProviderLineageModel{
+		File:      types.StringValue(path),
+		Namespace: types.StringValue("ml-platform"),
+	})
+	ctx := context.Background()
+	f.AddModel("llama3")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			run := l.Start(ctx, lineageJobCopy, []lineageDataset{{Name: "llama3"}}, []lineageDataset{{Name: "llama3"}})
+			run.Finish(ctx, nil)
+		}()
+	}
+	wg.Wait()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	counts := map[string]int{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var e lineageEvent
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("line %q is no event: %v", scanner.Text(), err)
+		}
+		if e.Job.Namespace != "ml-platform" || e.Job.Name != "copy.llama3:latest" {
+			t.Errorf("job = %s/%s, want ml-platform/copy.llama3:latest", e.Job.Namespace, e.Job.Name)
+		}
+		counts[e.EventType]++
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if counts["START"] != 10 || counts["COMPLETE"] != 10 {
+		t.Errorf("events = %v, want 10 START and 10 COMPLETE", counts)
+	}
+}
+
+func TestHTTPLineageTransportError(t *testing.T) {
+	collector := &lineageCollector{status: http.StatusServiceUnavailable}
+	srv := httptest.NewServer(collector)
+	t.Cleanup(srv.Close)
+
+	transport := &httpLineageTransport{url: srv.URL, client: http.DefaultClient}
+	err := transport.Send(context.Background(), []byte(`{}`))
+	if err == nil || !strings.Contains(err.Error(), "503") || !strings.Contains(err.Error(), "collector is down") {
+		t.Errorf("Send() = %v, want the status and body", err)
+	}
+
+	// a collector that is down does not fail the operation
+	_, l := newTestLineage(t, &OllamaProviderLineageModel{URL: types.StringValue(srv.URL)})
+	l.Start(context.Background(), lineageJobPull, nil, []lineageDataset{{Name: "llama3"}}).Finish(context.Background(), nil)
+}
+
+func TestConfigureLineage(t *testing.T) {
+	tests := map[string]struct {
+		config  OllamaProviderLineageModel
+		wantErr string
+	}{
+		"url":     {config: OllamaProviderLineageModel{URL: types.StringValue("http://marquez:5000/api/v1/lineage")}},
+		"file":    {config: OllamaProviderLineageModel{File: types.StringValue("lineage.jsonl")}},
+		"both":    {config: OllamaProviderLineageModel{URL: types.StringValue("http://marquez:5000"), File: types.StringValue("lineage.jsonl")}, wantErr: "only one of url and file can be set"},
+		"neither": {config: OllamaProviderLineageModel{}, wantErr: "one of url or file must be set"},
+	}
+
+	base := &url.URL{Scheme: "http", Host: "localhost:11434"}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			l, err := configureLineage(&tt.config, nil, base, http.DefaultClient, "v0.1.0")
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("configureLineage() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if l.dataset != "ollama://localhost:11434" || l.namespace != defaultLineageNamespace {
+				t.Errorf("dataset namespace = %s, job namespace = %s", l.dataset, l.namespace)
+			}
+		})
+	}
+}
+
+func TestLineageBase(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "model.gguf"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		from string
+		want []lineageDataset
+	}{
+		"model":         {from: "llama3", want: []lineageDataset{{Name: "llama3"}}},
+		"user model":    {from: "user/model:v1", want: []lineageDataset{{Name: "user/model:v1"}}},
+		"relative file": {from: "./model.gguf"},
+		"absolute file": {from: filepath.Join(dir, "model.gguf")},
+		"no base":       {from: ""},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := lineageBase(&modelfile{From: tt.from}, dir)
+			if len(got) != len(tt.want) || (len(got) == 1 && got[0] != tt.want[0]) {
+				t.Errorf("lineageBase(%q) = %v, want %v", tt.from, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLineageNil(t *testing.T) {
+	var l *lineageEmitter
+	run := l.Start(context.Background(), lineageJobPull, nil, []lineageDataset{{Name: "llama3"}})
+	if run != nil {
+		t.Fatalf("Start() of a nil emitter = %v, want nil", run)
+	}
+	run.Finish(context.Background(), nil)
+}
@@ -0,0 +1,553 @@
+package provider
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/ollama/ollama/api"
+)
+
+const (
+	lockBackendFile = "file"
+	lockBackendHost = "host"
+
+	defaultLockTimeout    = 5 * time.Minute
+	defaultLockStaleAfter = time.Hour
+	defaultLockMarker     = "terraform-provider-ollama-lock:latest"
+
+	// minLockHeartbeat bounds how often a held lock is refreshed when
+	// stale_after is very short.
+	minLockHeartbeat = 10 * time.Millisecond
+
+	// lockMarkerPrefix starts the line of the marker system message that
+	// holds the lock record.
+	lockMarkerPrefix = "terraform-provider-ollama-lock "
+)
+
+// lockPollInterval is how often a run waiting for a lock checks it again.
+var lockPollInterval = 2 * time.Second
+
+// lockRecord identifies the holder of a host lock.
+type lockRecord struct {
+	ID       string    `json:"id"`
+	Holder   string    `json:"holder"`
+	Host     string    `json:"host"`
+	Acquired time.Time `json:"acquired"`
+	// Heartbeat is when the holder last refreshed the record. Records of
+	// older versions do not have it.
+	Heartbeat time.Time `json:"heartbeat,omitempty"`
+}
+
+func (r *lockRecord) String() string {
+	return fmt.Sprintf("%s (since %s)", r.Holder, r.Acquired.Format(time.RFC3339))
+}
+
+// lastSeen returns when the holder was last known to be alive.
+func (r *lockRecord) lastSeen() time.Time {
+	if r.Heartbeat.After(r.Acquired) {
+		return r.Heartbeat
+	}
+	return r.Acquired
+}
+
+// lockBackend stores the lock record of a host.
+type lockBackend interface {
+	// Current returns the record of the current holder, or nil if the lock
+	// is free.
+	Current(ctx context.Context) (*lockRecord, error)
+	// TryAcquire takes the lock for rec unless it is held. It returns the
+	// holder if it is held.
+	TryAcquire(ctx context.Context, rec *lockRecord) (*lockRecord, error)
+	// Refresh replaces the stored record with rec if rec holds the lock. It
+	// returns false if the lock is free or held by someone else.
+	Refresh(ctx context.Context, rec *lockRecord) (bool, error)
+	// Release frees the lock if rec holds it.
+	Release(ctx context.Context, rec *lockRecord) error
+}
+
+// hostLock is an advisory lock on an Ollama host, taken around operations that
+// change or depend on the models on the host so concurrent Terraform runs do
+// not interleave.
+//
+// Within a run the lock is shared: it is acquired when the first operation
+// starts and released when the last one finishes. It is not held for the
+// whole run, so operations of another run can still take place between two
+// operations of this one; what the lock rules out is two runs working on the
+// host at the same time. Locks not refreshed for staleAfter are considered
+// abandoned and taken over; while the lock is held, a heartbeat refreshes it
+// every quarter of staleAfter, so long pulls and creates keep it.
+type hostLock struct {
+	backend    lockBackend
+	record     lockRecord
+	timeout    time.Duration
+	staleAfter time.Duration
+	heartbeat  time.Duration
+
+	mu   sync.Mutex
+	refs int
+	// acquiring is closed once the operation that is waiting for the backend
+	// got the lock or gave up. Other operations wait for it without holding mu.
+	acquiring chan struct{}
+	// stopHeartbeat ends the heartbeat of the held lock, which closes
+	// heartbeatDone once it returned.
+	stopHeartbeat chan struct{}
+	heartbeatDone chan struct{}
+}
+
+// errLockHeld is returned when the lock could not be acquired in time.
+type errLockHeld struct {
+	host    string
+	holder  *lockRecord
+	timeout time.Duration
+}
+
+func (e *errLockHeld) Error() string {
+	return fmt.Sprintf("host %s is locked by %s, gave up after waiting %s", e.host, e.holder, e.timeout)
+}
+
+func newHostLock(backend lockBackend, host, holder string, timeout, staleAfter time.Duration) (*hostLock, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("could not generate lock id: %w", err)
+	}
+
+	return &hostLock{
+		backend: backend,
+		record: lockRecord{
+			ID:     hex.EncodeToString(id),
+			Holder: holder,
+			Host:   host,
+		},
+		timeout:    timeout,
+		staleAfter: staleAfter,
+		heartbeat:  max(staleAfter/4, minLockHeartbeat),
+	}, nil
+}
+
+// defaultLockHolder describes this process, e.g. "alice@ci-runner-3 (pid 4711)".
+func defaultLockHolder() string {
+	user := os.Getenv("USER")
+	if user == "" {
+		user = os.Getenv("USERNAME")
+	}
+	hostname, _ := os.Hostname()
+	return fmt.Sprintf("%s@%s (pid %d)", user, hostname, os.Getpid())
+}
+
+// Acquire takes the lock, waiting up to the timeout for another holder to
+// release it, and returns the function that releases it again. A nil lock
+// is a no-op, so callers do not need to check whether locking is enabled.
+func (l *hostLock) Acquire(ctx context.Context) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	l.mu.Lock()
+	for l.refs == 0 && l.acquiring != nil {
+		acquiring := l.acquiring
+		l.mu.Unlock()
+		select {
+		case <-acquiring:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		l.mu.Lock()
+	}
+
+	if l.refs == 0 {
+		acquiring := make(chan struct{})
+		l.acquiring = acquiring
+		l.mu.Unlock()
+
+		rec, err := l.acquire(ctx)
+
+		l.mu.Lock()
+		l.acquiring = nil
+		close(acquiring)
+		if err != nil {
+			l.mu.Unlock()
+			return nil, err
+		}
+		l.record = rec
+		l.stopHeartbeat = make(chan struct{})
+		l.heartbeatDone = make(chan struct{})
+		go l.keepAlive(context.WithoutCancel(ctx), rec, l.stopHeartbeat, l.heartbeatDone)
+	}
+	l.refs++
+	l.mu.Unlock()
+
+	// release even if the operation was cancelled
+	ctx = context.WithoutCancel(ctx)
+
+	var once sync.Once
+	return func() { once.Do(func() { l.release(ctx) }) }, nil
+}
+
+// lockHost acquires l and adds a diagnostic naming the holder if that fails.
+func lockHost(ctx context.Context, l *hostLock, diags *diag.Diagnostics) (func(), bool) {
+	unlock, err := l.Acquire(ctx)
+	if err == nil {
+		return unlock, true
+	}
+
+	var held *errLockHeld
+	if errors.As(err, &held) {
+		// holders the backend could not read have no id
+		id := ""
+		if held.holder.ID != "" {
+			id = ", id " + held.holder.ID
+		}
+		diags.AddError(
+			"Ollama host is locked",
+			fmt.Sprintf("Another run holds the lock on %s: %s%s. Retry once it finished, or remove the lock if that run is gone.", held.host, held.holder, id),
+		)
+	} else {
+		diags.AddError("Error locking Ollama host", err.Error())
+	}
+	return nil, false
+}
+
+// acquire polls the backend until it grants the lock and returns the record
+// it was granted for. It is called without holding l.mu, at most once at a
+// time, and only reads the fields of l that never change.
+func (l *hostLock) acquire(ctx context.Context) (lockRecord, error) {
+	deadline := time.Now().Add(l.timeout)
+	host := l.record.Host
+
+	for {
+		rec := lockRecord{ID: l.record.ID, Holder: l.record.Holder, Host: host, Acquired: time.Now().UTC()}
+
+		holder, err := l.backend.TryAcquire(ctx, &rec)
+		if err != nil {
+			return lockRecord{}, fmt.Errorf("could not acquire lock on %s: %w", host, err)
+		}
+		if holder == nil {
+			tflog.Debug(ctx, fmt.Sprintf("acquired lock on %s as %s", rec.Host, rec.Holder))
+			return rec, nil
+		}
+
+		if time.Since(holder.lastSeen()) > l.staleAfter {
+			tflog.Warn(ctx, fmt.Sprintf("taking over stale lock on %s held by %s", host, holder))
+			if err := l.backend.Release(ctx, holder); err != nil {
+				return lockRecord{}, fmt.Errorf("could not release stale lock on %s: %w", host, err)
+			}
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return lockRecord{}, &errLockHeld{host: host, holder: holder, timeout: l.timeout}
+		}
+
+		tflog.Info(ctx, fmt.Sprintf("waiting for lock on %s held by %s", host, holder))
+		select {
+		case <-time.After(lockPollInterval):
+		case <-ctx.Done():
+			return lockRecord{}, ctx.Err()
+		}
+	}
+}
+
+func (l *hostLock) release(ctx context.Context) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.refs--
+	if l.refs > 0 {
+		return
+	}
+
+	// the heartbeat must not write the record back after it was released
+	close(l.stopHeartbeat)
+	<-l.heartbeatDone
+
+	if err := l.backend.Release(ctx, &l.record); err != nil {
+		tflog.Warn(ctx, fmt.Sprintf("could not release lock on %s: %s", l.record.Host, err))
+		return
+	}
+	tflog.Debug(ctx, fmt.Sprintf("released lock on %s", l.record.Host))
+}
+
+// keepAlive refreshes the record of the held lock until stop is closed, so
+// other runs do not take it over as stale while an operation takes long.
+func (l *hostLock) keepAlive(ctx context.Context, rec lockRecord, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(l.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		rec.Heartbeat = time.Now().UTC()
+		held, err := l.backend.Refresh(ctx, &rec)
+		if err != nil {
+			tflog.Warn(ctx, fmt.Sprintf("could not refresh lock on %s: %s", rec.Host, err))
+			continue
+		}
+		if !held {
+			tflog.Warn(ctx, fmt.Sprintf("lost lock on %s, another run took it over", rec.Host))
+			return
+		}
+	}
+}
+
+// fileLockBackend keeps the lock record in a local file, which serializes runs
+// on a single machine or on runners sharing a file system.
+type fileLockBackend struct {
+	path string
+}
+
+func newFileLockBackend(dir, host string) (*fileLockBackend, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256([]byte(host))
+	return &fileLockBackend{path: filepath.Join(dir, hex.EncodeToString(sum[:8])+".lock")}, nil
+}
+
+func (b *fileLockBackend) Current(ctx context.Context) (*lockRecord, error) {
+	data, err := os.ReadFile(b.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var rec lockRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		// a holder that is writing the file right now, or crashed doing so
+		rec = lockRecord{Holder: "unknown holder of " + b.path, Acquired: time.Now()}
+		if info, err := os.Stat(b.path); err == nil {
+			rec.Acquired = info.ModTime()
+		}
+	}
+	return &rec, nil
+}
+
+func (b *fileLockBackend) TryAcquire(ctx context.Context, rec *lockRecord) (*lockRecord, error) {
+	f, err := os.OpenFile(b.path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if errors.Is(err, fs.ErrExist) {
+		holder, err := b.Current(ctx)
+		if holder == nil && err == nil {
+			// released in the meantime, try again on the next poll
+			holder = &lockRecord{Holder: "another run", Acquired: time.Now()}
+		}
+		return holder, err
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.NewEncoder(f).Encode(rec); err != nil {
+		f.Close()
+		os.Remove(b.path)
+		return nil, err
+	}
+	return nil, f.Close()
+}
+
+func (b *fileLockBackend) Refresh(ctx context.Context, rec *lockRecord) (bool, error) {
+	current, err := b.Current(ctx)
+	if err != nil || current == nil || current.ID != rec.ID {
+		return false, err
+	}
+
+	// replace the file at once, so nobody reads a partial record
+	f, err := os.CreateTemp(filepath.Dir(b.path), filepath.Base(b.path)+".*")
+	if err != nil {
+		return false, err
+	}
+	if err := json.NewEncoder(f).Encode(rec); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return false, err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return false, err
+	}
+	if err := os.Chmod(f.Name(), 0o644); err != nil {
+		os.Remove(f.Name())
+		return false, err
+	}
+	if err := os.Rename(f.Name(), b.path); err != nil {
+		os.Remove(f.Name())
+		return false, err
+	}
+	return true, nil
+}
+
+func (b *fileLockBackend) Release(ctx context.Context, rec *lockRecord) error {
+	current, err := b.Current(ctx)
+	if err != nil || current == nil {
+		return err
+	}
+	if current.ID != rec.ID {
+		return nil
+	}
+
+	err = os.Remove(b.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// hostLockBackend keeps the lock record on the Ollama host itself, as the
+// system message of a marker model, so runs on different machines see each
+// other.
+//
+// This is best effort only. The API has no atomic create, so two runs that
+// find no marker at the same moment both create one, the last writer wins,
+// and both may read back their own record before seeing the other one. It
+// keeps runs that start minutes apart from overlapping, but does not provide
+// mutual exclusion like the file backend does for runs on one machine.
+type hostLockBackend struct {
+	client *api.Client
+	marker string
+	base   string
+}
+
+func (b *hostLockBackend) Current(ctx context.Context) (*lockRecord, error) {
+	rsp, err := b.client.Show(ctx, &api.ShowRequest{Model: b.marker})
+	var statusErr api.StatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == 404 {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	for _, line := range strings.Split(rsp.System, "\n") {
+		if rest, ok := strings.CutPrefix(line, lockMarkerPrefix); ok {
+			var rec lockRecord
+			if err := json.Unmarshal([]byte(rest), &rec); err == nil {
+				return &rec, nil
+			}
+		}
+	}
+
+	// never stale, a model of that name that is no marker must be removed by hand
+	return &lockRecord{Holder: "unknown holder of marker model " + b.marker, Acquired: time.Now()}, nil
+}
+
+func (b *hostLockBackend) TryAcquire(ctx context.Context, rec *lockRecord) (*lockRecord, error) {
+	if holder, err := b.Current(ctx); holder != nil || err != nil {
+		return holder, err
+	}
+
+	if err := b.write(ctx, rec); err != nil {
+		return nil, err
+	}
+
+	holder, err := b.Current(ctx)
+	if err != nil || holder == nil || holder.ID == rec.ID {
+		return nil, err
+	}
+	return holder, nil
+}
+
+func (b *hostLockBackend) Refresh(ctx context.Context, rec *lockRecord) (bool, error) {
+	current, err := b.Current(ctx)
+	if err != nil || current == nil || current.ID != rec.ID {
+		return false, err
+	}
+	return true, b.write(ctx, rec)
+}
+
+// write creates the marker model holding rec, replacing an existing one.
+func (b *hostLockBackend) write(ctx context.Context, rec *lockRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	mf := &modelfile{From: b.base, System: lockMarkerPrefix + string(data)}
+	noStream := false
+	err = b.client.Create(ctx, &api.CreateRequest{Model: b.marker, Modelfile: mf.String(), Stream: &noStream}, PullResponseFn)
+	if err != nil {
+		return fmt.Errorf("could not create lock marker %s: %w", b.marker, err)
+	}
+	return nil
+}
+
+func (b *hostLockBackend) Release(ctx context.Context, rec *lockRecord) error {
+	current, err := b.Current(ctx)
+	if err != nil || current == nil {
+		return err
+	}
+	if current.ID != rec.ID {
+		return nil
+	}
+
+	err = b.client.Delete(ctx, &api.DeleteRequest{Model: b.marker})
+	var statusErr api.StatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == 404 {
+		return nil
+	}
+	return err
+}
+
+// configureHostLock builds the host lock from the provider configuration.
+func configureHostLock(config *OllamaProviderLockModel, client *api.Client, host string) (*hostLock, error) {
+	timeout, err := optionalDuration(config.Timeout.ValueString(), defaultLockTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timeout: %w", err)
+	}
+	staleAfter, err := optionalDuration(config.StaleAfter.ValueString(), defaultLockStaleAfter)
+	if err != nil {
+		return nil, fmt.Errorf("invalid stale_after: %w", err)
+	}
+
+	holder := config.Holder.ValueString()
+	if holder == "" {
+		holder = defaultLockHolder()
+	}
+
+	var backend lockBackend
+	switch config.Backend.ValueString() {
+	case "", lockBackendFile:
+		dir := config.Directory.ValueString()
+		if dir == "" {
+			dir = filepath.Join(os.TempDir(), "terraform-provider-ollama-locks")
+		}
+		if backend, err = newFileLockBackend(dir, host); err != nil {
+			return nil, fmt.Errorf("could not create lock directory: %w", err)
+		}
+	case lockBackendHost:
+		if config.MarkerBase.ValueString() == "" {
+			return nil, errors.New("marker_base is required for the host backend")
+		}
+		marker := config.MarkerModel.ValueString()
+		if marker == "" {
+			marker = defaultLockMarker
+		}
+		backend = &hostLockBackend{client: client, marker: marker, base: config.MarkerBase.ValueString()}
+	default:
+		return nil, fmt.Errorf("backend must be %q or %q, got %q", lockBackendFile, lockBackendHost, config.Backend.ValueString())
+	}
+
+	return newHostLock(backend, host, holder, timeout, staleAfter)
+}
+
+func optionalDuration(s string, def time.Duration) (time.Duration, error) {
+	if s == "" {
+		return def, nil
+	}
+	return time.ParseDuration(s)
+}
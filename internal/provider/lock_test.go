@@ -0,0 +1,334 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+)
+
+func newTestFileLock(t *testing.T, dir, holder string, timeout, staleAfter time.Duration) *hostLock {
+	t.Helper()
+
+	backend, err := newFileLockBackend(dir, "http://127.0.0.1:11434")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := newHostLock(backend, "http://127.0.0.1:11434", holder, timeout, staleAfter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func TestFileLockBackend(t *testing.T) {
+	ctx := context.Background()
+	b, err := newFileLockBackend(t.TempDir(), "http://127.0.0.1:11434")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := &lockRecord{ID: "first", Holder: "first run", Acquired: time.Now()}
+	second := &lockRecord{ID: "second", Holder: "second run", Acquired: time.Now()}
+
+	if holder, err := b.TryAcquire(ctx, first); err != nil || holder != nil {
+		t.Fatalf("TryAcquire(first) = %v, %v, want the lock", holder, err)
+	}
+	if holder, err := b.TryAcquire(ctx, second); err != nil || holder == nil || holder.ID != "first" {
+		t.Fatalf("TryAcquire(second) = %v, %v, want held by first", holder, err)
+	}
+
+	if err := b.Release(ctx, second); err != nil {
+		t.Fatal(err)
+	}
+	if current, err := b.Current(ctx); err != nil || current == nil || current.ID != "first" {
+		t.Fatalf("Current() after release by another holder = %v, %v, want first", current, err)
+	}
+
+	if err := b.Release(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+	if current, err := b.Current(ctx); err != nil || current != nil {
+		t.Fatalf("Current() after release = %v, %v, want free", current, err)
+	}
+	if err := b.Release(ctx, first); err != nil {
+		t.Errorf("Release() of a free lock = %v, want nil", err)
+	}
+
+	if holder, err := b.TryAcquire(ctx, second); err != nil || holder != nil {
+		t.Fatalf("TryAcquire(second) after release = %v, %v, want the lock", holder, err)
+	}
+}
+
+func TestFileLockBackendCorruptFile(t *testing.T) {
+	ctx := context.Background()
+	b, err := newFileLockBackend(t.TempDir(), "http://127.0.0.1:11434")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(b.path, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	current, err := b.Current(ctx)
+	if err != nil || current == nil {
+		t.Fatalf("Current() of a corrupt lock file = %v, %v, want an unknown holder", current, err)
+	}
+	if current.ID != "" {
+		t.Errorf("unknown holder id = %q, want empty", current.ID)
+	}
+}
+
+func TestHostLockShared(t *testing.T) {
+	ctx := context.Background()
+	l := newTestFileLock(t, t.TempDir(), "run", time.Second, time.Hour)
+	b := l.backend.(*fileLockBackend)
+
+	unlock1, err := l.Acquire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlock2, err := l.Acquire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unlock1()
+	unlock1()
+	if current, _ := b.Current(ctx); current == nil {
+		t.Fatal("lock released while another operation still holds it")
+	}
+
+	unlock2()
+	if current, _ := b.Current(ctx); current != nil {
+		t.Fatalf("lock still held by %v after the last release", current)
+	}
+}
+
+func TestHostLockHeld(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	other := newTestFileLock(t, dir, "other run", time.Second, time.Hour)
+	unlock, err := other.Acquire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+
+	l := newTestFileLock(t, dir, "this run", 0, time.Hour)
+	_, err = l.Acquire(ctx)
+
+	var held *errLockHeld
+	if !errors.As(err, &held) {
+		t.Fatalf("Acquire() = %v, want errLockHeld", err)
+	}
+	if held.holder.Holder != "other run" {
+		t.Errorf("holder = %q, want other run", held.holder.Holder)
+	}
+
+	// a failed acquire must not leave the lock half taken
+	if _, err := l.Acquire(ctx); !errors.As(err, &held) {
+		t.Errorf("second Acquire() = %v, want errLockHeld", err)
+	}
+}
+
+func TestHostLockStaleTakeover(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	crashed := newTestFileLock(t, dir, "crashed run", time.Second, time.Hour)
+	if _, err := crashed.Acquire(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	l := newTestFileLock(t, dir, "this run", 0, time.Nanosecond)
+	unlock, err := l.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("Acquire() of a stale lock = %v, want takeover", err)
+	}
+	defer unlock()
+
+	current, err := l.backend.Current(ctx)
+	if err != nil || current == nil || current.Holder != "this run" {
+		t.Errorf("holder after takeover = %v, %v, want this run", current, err)
+	}
+}
+
+func TestHostLockHeartbeat(t *testing.T) {
+	staleAfter := 200 * time.Millisecond
+
+	tests := map[string]struct {
+		heartbeat time.Duration
+		wantHeld  bool
+	}{
+		"refreshed lock is kept": {heartbeat: staleAfter / 4, wantHeld: true},
+		// a run that crashed, or one from before heartbeats
+		"unrefreshed lock is taken over": {heartbeat: time.Hour, wantHeld: false},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			dir := t.TempDir()
+
+			long := newTestFileLock(t, dir, "long run", time.Second, staleAfter)
+			long.heartbeat = tt.heartbeat
+			unlock, err := long.Acquire(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// an operation that runs longer than stale_after
+			time.Sleep(3 * staleAfter)
+
+			l := newTestFileLock(t, dir, "this run", 0, staleAfter)
+			unlockThis, err := l.Acquire(ctx)
+
+			var held *errLockHeld
+			if tt.wantHeld {
+				if !errors.As(err, &held) {
+					t.Fatalf("Acquire() = %v, want errLockHeld", err)
+				}
+				if held.holder.Holder != "long run" {
+					t.Errorf("holder = %q, want long run", held.holder.Holder)
+				}
+				unlock()
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Acquire() = %v, want takeover", err)
+			}
+			defer unlockThis()
+
+			// the release of the run that lost the lock leaves it alone
+			unlock()
+			current, err := l.backend.Current(ctx)
+			if err != nil || current == nil || current.Holder != "this run" {
+				t.Errorf("holder after takeover = %v, %v, want this run", current, err)
+			}
+		})
+	}
+}
+
+func TestLockHostDiagnostic(t *testing.T) {
+	tests := map[string]struct {
+		lockFile string
+		want     string
+	}{
+		"known holder": {
+			want: "Another run holds the lock on http://127.0.0.1:11434: other run (since ",
+		},
+		"unknown holder": {
+			lockFile: "{",
+			want:     "Another run holds the lock on http://127.0.0.1:11434: unknown holder of ",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			dir := t.TempDir()
+
+			var id string
+			if tt.lockFile != "" {
+				b, err := newFileLockBackend(dir, "http://127.0.0.1:11434")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(b.path, []byte(tt.lockFile), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			} else {
+				other := newTestFileLock(t, dir, "other run", time.Second, time.Hour)
+				unlock, err := other.Acquire(ctx)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer unlock()
+				id = other.record.ID
+			}
+
+			var diags diag.Diagnostics
+			if _, ok := lockHost(ctx, newTestFileLock(t, dir, "this run", 0, time.Hour), &diags); ok {
+				t.Fatal("lockHost() took a held lock")
+			}
+			detail := diags[0].Detail()
+			if !strings.HasPrefix(detail, tt.want) {
+				t.Errorf("detail = %q, want prefix %q", detail, tt.want)
+			}
+			if id != "" && !strings.Contains(detail, ", id "+id+".") {
+				t.Errorf("detail = %q, want the id %s", detail, id)
+			}
+			if id == "" && strings.Contains(detail, ", id") {
+				t.Errorf("detail = %q, want no id", detail)
+			}
+		})
+	}
+}
+
+func TestHostLockCancelled(t *testing.T) {
+	dir := t.TempDir()
+
+	other := newTestFileLock(t, dir, "other run", time.Second, time.Hour)
+	unlock, err := other.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	l := newTestFileLock(t, dir, "this run", time.Minute, time.Hour)
+	if _, err := l.Acquire(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Acquire() = %v, want context.Canceled", err)
+	}
+}
+
+func TestHostLockConcurrent(t *testing.T) {
+	ctx := context.Background()
+	l := newTestFileLock(t, t.TempDir(), "run", time.Second, time.Hour)
+	b := l.backend.(*fileLockBackend)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			unlock, err := l.Acquire(ctx)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if current, _ := b.Current(ctx); current == nil || current.ID != l.record.ID {
+				t.Errorf("operation runs while the lock is held by %v", current)
+			}
+			unlock()
+		}()
+	}
+	wg.Wait()
+
+	if current, _ := b.Current(ctx); current != nil {
+		t.Errorf("lock still held by %v after all operations finished", current)
+	}
+	if l.refs != 0 {
+		t.Errorf("refs = %d, want 0", l.refs)
+	}
+}
+
+func TestHostLockNil(t *testing.T) {
+	var l *hostLock
+	unlock, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlock()
+}
@@ -0,0 +1,177 @@
+package provider
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+	"github.com/ollama/ollama/parser"
+)
+
+// modelfile is the structured form of a Modelfile.
+type modelfile struct {
+	From       string
+	System     string
+	Template   string
+	Parameters map[string]string
+	Stop       []string
+	Messages   []api.Message
+	Adapters   []string
+	License    []string
+}
+
+// parseModelfile parses Modelfile text, such as the one returned by Show.
+// Repeated parameters other than stop keep their last value.
+func parseModelfile(text string) (*modelfile, error) {
+	commands, err := parser.Parse(strings.NewReader(text))
+	if err != nil {
+		return nil, err
+	}
+
+	mf := &modelfile{Parameters: map[string]string{}}
+	for _, c := range commands {
+		switch c.Name {
+		case "model":
+			mf.From = c.Args
+		case "adapter":
+			mf.Adapters = append(mf.Adapters, c.Args)
+		case "system":
+			mf.System = c.Args
+		case "template":
+			mf.Template = c.Args
+		case "message":
+			role, content, _ := strings.Cut(c.Args, ": ")
+			mf.Messages = append(mf.Messages, api.Message{Role: role, Content: content})
+		case "license":
+			mf.License = append(mf.License, c.Args)
+		case "stop":
+			mf.Stop = append(mf.Stop, c.Args)
+		default:
+			mf.Parameters[c.Name] = c.Args
+		}
+	}
+
+	return mf, nil
+}
+
+// String renders the Modelfile with a stable directive order.
+func (mf *modelfile) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "FROM %s\n", mf.From)
+	for _, a := range mf.Adapters {
+		fmt.Fprintf(&b, "ADAPTER %s\n", a)
+	}
+	if mf.Template != "" {
+		fmt.Fprintf(&b, "TEMPLATE %s\n", quoteModelfileValue(mf.Template))
+	}
+	if mf.System != "" {
+		fmt.Fprintf(&b, "SYSTEM %s\n", quoteModelfileValue(mf.System))
+	}
+
+	keys := make([]string, 0, len(mf.Parameters))
+	for k := range mf.Parameters {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, "PARAMETER %s %s\n", k, quoteModelfileValue(mf.Parameters[k]))
+	}
+	for _, s := range mf.Stop {
+		fmt.Fprintf(&b, "PARAMETER stop %s\n", quoteModelfileValue(s))
+	}
+
+	for _, m := range mf.Messages {
+		fmt.Fprintf(&b, "MESSAGE %s %s\n", m.Role, quoteModelfileValue(m.Content))
+	}
+	for _, l := range mf.License {
+		fmt.Fprintf(&b, "LICENSE %s\n", quoteModelfileValue(l))
+	}
+
+	return b.String()
+}
+
+// quoteModelfileValue quotes values the Modelfile parser would otherwise split
+// or trim. The parser does not unescape, so values are wrapped as they are.
+func quoteModelfileValue(v string) string {
+	switch {
+	case strings.Contains(v, "\n") || strings.Contains(v, `"`):
+		return `"""` + v + `"""`
+	case v == "" || strings.ContainsAny(v, " \t") || strings.TrimSpace(v) != v:
+		return `"` + v + `"`
+	default:
+		return v
+	}
+}
+
+// isLocalModelPath reports whether a FROM or ADAPTER argument refers to a file
+// on the machine running Terraform rather than to a model or server-side blob.
+// Relative paths are resolved against dir.
+func isLocalModelPath(arg, dir string) bool {
+	if strings.HasPrefix(arg, "@") {
+		return false
+	}
+	if !filepath.IsAbs(arg) && !strings.HasPrefix(arg, ".") {
+		return false
+	}
+
+	info, err := os.Stat(resolveModelPath(arg, dir))
+	return err == nil && !info.IsDir()
+}
+
+// isServerBlob reports whether a FROM or ADAPTER argument is a blob in the
+// models store of the server, which is how Show reports the base of a model.
+func isServerBlob(arg string) bool {
+	return strings.Contains(filepath.ToSlash(arg), "/blobs/sha256")
+}
+
+// covers reports whether the instructions of configured are in effect in mf,
+// a Modelfile read from the server. Instructions configured leaves out are
+// inherited from the base model and not compared, and neither is a FROM the
+// server reports as a blob. ADAPTER files are compared by their digest, the
+// server renames them.
+func (mf *modelfile) covers(configured *modelfile) bool {
+	if !isServerBlob(mf.From) && !isLocalModelPath(configured.From, "") && !sameModelName(mf.From, configured.From) {
+		return false
+	}
+	if configured.Template != "" && configured.Template != mf.Template {
+		return false
+	}
+	if configured.System != "" && configured.System != mf.System {
+		return false
+	}
+	for k, v := range configured.Parameters {
+		if mf.Parameters[k] != v {
+			return false
+		}
+	}
+	if len(configured.Stop) > 0 {
+		want, got := append([]string{}, configured.Stop...), append([]string{}, mf.Stop...)
+		sort.Strings(want)
+		sort.Strings(got)
+		if !slices.Equal(want, got) {
+			return false
+		}
+	}
+	if len(configured.Adapters) > 0 && !sameAdapters(configured.Adapters, mf.Adapters) {
+		return false
+	}
+	if len(configured.Messages) > 0 {
+		equal := func(a, b api.Message) bool { return a.Role == b.Role && a.Content == b.Content }
+		if !slices.EqualFunc(configured.Messages, mf.Messages, equal) {
+			return false
+		}
+	}
+	return true
+}
+
+func resolveModelPath(arg, dir string) string {
+	if dir == "" || filepath.IsAbs(arg) {
+		return arg
+	}
+	return filepath.Join(dir, arg)
+}
@@ -0,0 +1,157 @@
+package provider
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ollama/ollama/api"
+)
+
+func TestParseModelfile(t *testing.T) {
+	tests := map[string]struct {
+		text string
+		want *modelfile
+	}{
+		"from only": {
+			text: "FROM llama3\n",
+			want: &modelfile{From: "llama3", Parameters: map[string]string{}},
+		},
+		"all directives": {
+			text: `FROM llama3:8b
+ADAPTER ./adapter.gguf
+TEMPLATE """{{ .System }}
+{{ .Prompt }}"""
+SYSTEM You are a helpful assistant.
+PARAMETER temperature 0.7
+PARAMETER num_ctx 4096
+PARAMETER stop <|eot_id|>
+PARAMETER stop "<|end of text|>"
+MESSAGE user Hello
+MESSAGE assistant """Hi, how can I help?"""
+LICENSE MIT
+`,
+			want: &modelfile{
+				From:       "llama3:8b",
+				Adapters:   []string{"./adapter.gguf"},
+				Template:   "{{ .System }}\n{{ .Prompt }}",
+				System:     "You are a helpful assistant.",
+				Parameters: map[string]string{"temperature": "0.7", "num_ctx": "4096"},
+				Stop:       []string{"<|eot_id|>", "<|end of text|>"},
+				Messages: []api.Message{
+					{Role: "user", Content: "Hello"},
+					{Role: "assistant", Content: "Hi, how can I help?"},
+				},
+				License: []string{"MIT"},
+			},
+		},
+		"repeated parameter keeps the last value": {
+			text: "FROM llama3\nPARAMETER temperature 0.2\nPARAMETER temperature 0.9\n",
+			want: &modelfile{From: "llama3", Parameters: map[string]string{"temperature": "0.9"}},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := parseModelfile(tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseModelfile() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestModelfileStringRoundTrip(t *testing.T) {
+	tests := map[string]*modelfile{
+		"from only": {
+			From:       "llama3",
+			Parameters: map[string]string{},
+		},
+		"blob reference": {
+			From:       "@sha256:0123456789abcdef",
+			Adapters:   []string{"@sha256:fedcba9876543210"},
+			Parameters: map[string]string{},
+		},
+		"multi-line template": {
+			From:       "llama3",
+			Template:   "{{ if .System }}<|system|>{{ .System }}{{ end }}\n<|user|>{{ .Prompt }}",
+			Parameters: map[string]string{},
+		},
+		"values with spaces and quotes": {
+			From:       "llama3",
+			System:     `Answer with "yes" or "no".`,
+			Parameters: map[string]string{"temperature": "0", "num_predict": "128"},
+			Stop:       []string{"<|eot_id|>", "end of turn"},
+		},
+		"messages and licenses": {
+			From:       "llama3",
+			Parameters: map[string]string{},
+			Messages: []api.Message{
+				{Role: "user", Content: "Is the sky blue?"},
+				{Role: "assistant", Content: "Yes."},
+			},
+			License: []string{"Apache License\nVersion 2.0"},
+		},
+	}
+
+	for name, mf := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := parseModelfile(mf.String())
+			if err != nil {
+				t.Fatalf("parseModelfile(%q): %s", mf.String(), err)
+			}
+			if !reflect.DeepEqual(got, mf) {
+				t.Errorf("round trip of\n%s\n= %#v, want %#v", mf.String(), got, mf)
+			}
+			if got.String() != mf.String() {
+				t.Errorf("String() is not stable:\n%s\nvs\n%s", got.String(), mf.String())
+			}
+		})
+	}
+}
+
+func TestQuoteModelfileValue(t *testing.T) {
+	tests := map[string]string{
+		"plain":            "plain",
+		"two words":        `"two words"`,
+		"":                 `""`,
+		" padded":          `" padded"`,
+		"line\nbreak":      `"""line` + "\n" + `break"""`,
+		`say "hi"`:         `"""say "hi""""`,
+		"tab\tseparated":   "\"tab\tseparated\"",
+		"<|start_header|>": "<|start_header|>",
+	}
+
+	for in, want := range tests {
+		if got := quoteModelfileValue(in); got != want {
+			t.Errorf("quoteModelfileValue(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestIsLocalModelPath(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "model.gguf"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]bool{
+		"./model.gguf":                   true,
+		filepath.Join(dir, "model.gguf"): true,
+		"./missing.gguf":                 false,
+		".":                              false,
+		"llama3":                         false,
+		"model.gguf":                     false,
+		"@sha256:0123456789abcdef":       false,
+	}
+
+	for arg, want := range tests {
+		if got := isLocalModelPath(arg, dir); got != want {
+			t.Errorf("isLocalModelPath(%q) = %v, want %v", arg, got, want)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/attr/xattr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ basetypes.StringTypable                    = ModelfileType{}
+	_ basetypes.StringValuableWithSemanticEquals = ModelfileValue{}
+	_ xattr.ValidateableAttribute                = ModelfileValue{}
+)
+
+// ModelfileType is a string attribute holding Modelfile text. Values that
+// parse to the same instructions are semantically equal, so differences in
+// whitespace, comments, quoting or directive order do not show up as diffs.
+// Show reports the base of a model as a blob of the server, so a FROM naming
+// a blob matches any FROM of a Modelfile that names a model or file instead.
+type ModelfileType struct {
+	basetypes.StringType
+}
+
+func (t ModelfileType) Equal(o attr.Type) bool {
+	other, ok := o.(ModelfileType)
+	if !ok {
+		return false
+	}
+	return t.StringType.Equal(other.StringType)
+}
+
+func (t ModelfileType) String() string {
+	return "ModelfileType"
+}
+
+func (t ModelfileType) ValueFromString(ctx context.Context, in basetypes.StringValue) (basetypes.StringValuable, diag.Diagnostics) {
+	return ModelfileValue{StringValue: in}, nil
+}
+
+func (t ModelfileType) ValueFromTerraform(ctx context.Context, in tftypes.Value) (attr.Value, error) {
+	attrValue, err := t.StringType.ValueFromTerraform(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+
+	stringValue, ok := attrValue.(basetypes.StringValue)
+	if !ok {
+		return nil, fmt.Errorf("unexpected value type of %T", attrValue)
+	}
+
+	return ModelfileValue{StringValue: stringValue}, nil
+}
+
+func (t ModelfileType) ValueType(ctx context.Context) attr.Value {
+	return ModelfileValue{}
+}
+
+// ModelfileValue is a value of ModelfileType.
+type ModelfileValue struct {
+	basetypes.StringValue
+}
+
+// NewModelfileValue returns a known Modelfile value.
+func NewModelfileValue(text string) ModelfileValue {
+	return ModelfileValue{StringValue: basetypes.NewStringValue(text)}
+}
+
+func (v ModelfileValue) Equal(o attr.Value) bool {
+	other, ok := o.(ModelfileValue)
+	if !ok {
+		return false
+	}
+	return v.StringValue.Equal(other.StringValue)
+}
+
+func (v ModelfileValue) Type(ctx context.Context) attr.Type {
+	return ModelfileType{}
+}
+
+// StringSemanticEquals compares the canonical forms of both Modelfiles. Text
+// that does not parse is only equal to the same text.
+func (v ModelfileValue) StringSemanticEquals(ctx context.Context, newValuable basetypes.StringValuable) (bool, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	newValue, ok := newValuable.(ModelfileValue)
+	if !ok {
+		diags.AddError(
+			"Semantic Equality Check Error",
+			fmt.Sprintf("Expected value type %T but got value type %T. Please report this to the provider developers.", v, newValuable),
+		)
+		return false, diags
+	}
+
+	if v.ValueString() == newValue.ValueString() {
+		return true, diags
+	}
+
+	prior, err := parseModelfile(v.ValueString())
+	if err != nil {
+		return false, diags
+	}
+	current, err := parseModelfile(newValue.ValueString())
+	if err != nil {
+		return false, diags
+	}
+
+	if isServerBlob(prior.From) != isServerBlob(current.From) {
+		prior.From, current.From = "", ""
+	}
+
+	return canonicalModelfile(prior) == canonicalModelfile(current), diags
+}
+
+// ValidateAttribute rejects text the Modelfile parser cannot read.
+func (v ModelfileValue) ValidateAttribute(ctx context.Context, req xattr.ValidateAttributeRequest, resp *xattr.ValidateAttributeResponse) {
+	if v.IsNull() || v.IsUnknown() {
+		return
+	}
+
+	if _, err := parseModelfile(v.ValueString()); err != nil {
+		resp.Diagnostics.AddAttributeError(req.Path, "Invalid Modelfile", fmt.Sprintf("The Modelfile could not be parsed: %s", err))
+	}
+}
+
+// canonicalModelfile renders mf in a normal form. Directives are ordered by
+// modelfile.String and stop sequences, which the server treats as a set, are
+// sorted.
+func canonicalModelfile(mf *modelfile) string {
+	canonical := *mf
+	canonical.Stop = append([]string{}, mf.Stop...)
+	sort.Strings(canonical.Stop)
+
+	return canonical.String()
+}
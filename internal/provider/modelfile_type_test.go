@@ -0,0 +1,205 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// showMario is what Show returns for a model created from llama3 with the
+// system message and temperature of the custom model example, shortened to
+// the first lines of the license.
+const showMario = `# Modelfile generated by "ollama show"
+# To build a new Modelfile based on this, replace FROM with:
+# FROM mario:latest
+
+FROM /usr/share/ollama/.ollama/models/blobs/sha256-6a0746a1ec1aef3e7ec53868f220ff6e389f6f8ef87a01d77c96807de94ca2aa
+TEMPLATE """{{ if .System }}<|start_header_id|>system<|end_header_id|>
+
+{{ .System }}<|eot_id|>{{ end }}{{ if .Prompt }}<|start_header_id|>user<|end_header_id|>
+
+{{ .Prompt }}<|eot_id|>{{ end }}<|start_header_id|>assistant<|end_header_id|>
+
+{{ .Response }}<|eot_id|>"""
+SYSTEM """You are Mario from Super Mario Bros."""
+PARAMETER num_keep 24
+PARAMETER stop "<|start_header_id|>"
+PARAMETER stop "<|end_header_id|>"
+PARAMETER stop "<|eot_id|>"
+PARAMETER temperature 1
+LICENSE """META LLAMA 3 COMMUNITY LICENSE AGREEMENT
+Meta Llama 3 Version Release Date: April 18, 2024"""
+`
+
+// handWrittenMario is showMario as someone would write it: FROM names the
+// base, the directives are in another order and quoted differently.
+const handWrittenMario = `# Mario, see https://ollama.com/library/llama3
+FROM llama3
+
+SYSTEM You are Mario from Super Mario Bros.
+PARAMETER temperature 1
+PARAMETER stop <|eot_id|>
+PARAMETER stop <|start_header_id|>
+PARAMETER stop <|end_header_id|>
+PARAMETER num_keep 24
+
+TEMPLATE """{{ if .System }}<|start_header_id|>system<|end_header_id|>
+
+{{ .System }}<|eot_id|>{{ end }}{{ if .Prompt }}<|start_header_id|>user<|end_header_id|>
+
+{{ .Prompt }}<|eot_id|>{{ end }}<|start_header_id|>assistant<|end_header_id|>
+
+{{ .Response }}<|eot_id|>"""
+LICENSE """META LLAMA 3 COMMUNITY LICENSE AGREEMENT
+Meta Llama 3 Version Release Date: April 18, 2024"""
+`
+
+// minimalMario only has the instructions the example configures; all others
+// are inherited from llama3.
+const minimalMario = `FROM llama3:latest
+SYSTEM "You are Mario from Super Mario Bros."
+PARAMETER temperature 1
+`
+
+func TestModelfileSemanticEquals(t *testing.T) {
+	tests := map[string]struct {
+		a, b string
+		want bool
+	}{
+		"identical": {
+			a: showMario, b: showMario, want: true,
+		},
+		"show output and hand-written equivalent": {
+			a: showMario, b: handWrittenMario, want: true,
+		},
+		"hand-written equivalent and show output": {
+			a: handWrittenMario, b: showMario, want: true,
+		},
+		"different parameter": {
+			a: showMario, b: replaceOnce(handWrittenMario, "temperature 1", "temperature 0.5"),
+		},
+		"different system message": {
+			a: showMario, b: replaceOnce(handWrittenMario, "Mario from", "Luigi from"),
+		},
+		"stop sequences in another order": {
+			a:    "FROM llama3\nPARAMETER stop a\nPARAMETER stop b\n",
+			b:    "FROM llama3\nPARAMETER stop \"b\"\nPARAMETER stop a\n",
+			want: true,
+		},
+		"different bases": {
+			a: "FROM llama3\n", b: "FROM mistral\n",
+		},
+		"different blobs": {
+			a: showMario, b: replaceOnce(showMario, "sha256-6a07", "sha256-0000"),
+		},
+		"instructions the hand-written one leaves out": {
+			a: showMario, b: minimalMario,
+		},
+		"unparsable": {
+			a: showMario, b: "SYSTEM no FROM",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, diags := NewModelfileValue(tt.a).StringSemanticEquals(context.Background(), NewModelfileValue(tt.b))
+			if diags.HasError() {
+				t.Fatal(diags)
+			}
+			if got != tt.want {
+				t.Errorf("StringSemanticEquals() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestModelfileInEffect(t *testing.T) {
+	server, err := parseModelfile(showMario)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		configured ModelfileValue
+		want       bool
+	}{
+		"minimal":                 {configured: NewModelfileValue(minimalMario), want: true},
+		"hand-written equivalent": {configured: NewModelfileValue(handWrittenMario), want: true},
+		"changed system message":  {configured: NewModelfileValue(replaceOnce(minimalMario, "Mario from", "Luigi from"))},
+		"changed parameter":       {configured: NewModelfileValue(replaceOnce(minimalMario, "temperature 1", "temperature 0.5"))},
+		"changed stop sequences":  {configured: NewModelfileValue(minimalMario + "PARAMETER stop <|eot_id|>\n")},
+		"message not on server":   {configured: NewModelfileValue(minimalMario + "MESSAGE user Hello\n")},
+		// the Modelfile of a previous read is replaced, so any change shows
+		"reported by the server": {configured: NewModelfileValue(showMario)},
+		"not configured":         {configured: ModelfileValue{StringValue: types.StringNull()}},
+		"unknown":                {configured: ModelfileValue{StringValue: types.StringUnknown()}},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := modelfileInEffect(tt.configured, server); got != tt.want {
+				t.Errorf("modelfileInEffect() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOllamaCustomModelResourceValidateConfig(t *testing.T) {
+	r := &ollamaCustomModelResource{}
+	s := resourceSchema(t, r).Schema
+
+	config := func(from, modelfile, system *string) *OllamaCustomModelResource {
+		return &OllamaCustomModelResource{
+			Name:       types.StringValue("mario"),
+			From:       types.StringPointerValue(from),
+			System:     types.StringPointerValue(system),
+			Template:   types.StringNull(),
+			Parameters: types.MapNull(types.StringType),
+			Stop:       types.ListNull(types.StringType),
+			Messages:   types.ListNull(ollamaMessageType),
+			Adapters:   types.ListNull(types.StringType),
+			Modelfile:  ModelfileValue{StringValue: types.StringPointerValue(modelfile)},
+			Digest:     NewDigestNull(),
+		}
+	}
+	str := func(s string) *string { return &s }
+
+	tests := map[string]struct {
+		config  *OllamaCustomModelResource
+		wantErr bool
+	}{
+		"from":                  {config: config(str("llama3"), nil, str("You are Mario."))},
+		"modelfile":             {config: config(nil, str(minimalMario), nil)},
+		"neither":               {config: config(nil, nil, nil), wantErr: true},
+		"from and modelfile":    {config: config(str("llama3"), str(minimalMario), nil), wantErr: true},
+		"system with modelfile": {config: config(nil, str(minimalMario), str("You are Luigi.")), wantErr: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			raw := tfsdk.State{Schema: s}
+			if diags := raw.Set(ctx, tt.config); diags.HasError() {
+				t.Fatal(diags)
+			}
+
+			var resp resource.ValidateConfigResponse
+			r.ValidateConfig(ctx, resource.ValidateConfigRequest{Config: tfsdk.Config{Schema: s, Raw: raw.Raw}}, &resp)
+			if got := resp.Diagnostics.HasError(); got != tt.wantErr {
+				t.Errorf("ValidateConfig() error = %v, want %v: %v", got, tt.wantErr, resp.Diagnostics)
+			}
+		})
+	}
+}
+
+func replaceOnce(s, old, new string) string {
+	if !strings.Contains(s, old) {
+		panic("replaceOnce: " + old + " not found")
+	}
+	return strings.Replace(s, old, new, 1)
+}
@@ -0,0 +1,86 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/ollama/ollama/api"
+)
+
+// normalizeModelName appends the implicit ":latest" tag, so "llama3" and
+// "llama3:latest" compare equal.
+func normalizeModelName(name string) string {
+	if i := strings.LastIndex(name, "/"); strings.Contains(name[i+1:], ":") {
+		return name
+	}
+	return name + ":latest"
+}
+
+func sameModelName(a, b string) bool {
+	return normalizeModelName(a) == normalizeModelName(b)
+}
+
+// lookupModel returns the model listed under name, or nil if the server has no
+// such model.
+func lookupModel(ctx context.Context, client *api.Client, name string) (*api.ModelResponse, error) {
+	rsp, err := client.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not list models: %w", err)
+	}
+
+	return findModel(rsp, name), nil
+}
+
+// findModel returns the model called name from a List response, or nil if it
+// is not listed.
+func findModel(list *api.ListResponse, name string) *api.ModelResponse {
+	for i := range list.Models {
+		if sameModelName(list.Models[i].Name, name) {
+			return &list.Models[i]
+		}
+	}
+	return nil
+}
+
+// plannedModelDigest plans the digest of model for resources that record the
+// digest they last ran against. A digest that differs from prior is planned as
+// unknown, which triggers the update and is filled in once it ran.
+func plannedModelDigest(ctx context.Context, client *api.Client, model types.String, prior DigestValue) (DigestValue, error) {
+	if model.IsUnknown() || prior.IsNull() || prior.IsUnknown() {
+		return NewDigestUnknown(), nil
+	}
+
+	listed, err := lookupModel(ctx, client, model.ValueString())
+	if err != nil {
+		return NewDigestUnknown(), err
+	}
+
+	// the model may be pulled later in the same apply
+	if listed != nil && prior.SameDigest(listed.Digest) {
+		return prior, nil
+	}
+
+	return NewDigestUnknown(), nil
+}
+
+// verifyModelLoads asks the server to load the model and predict a single
+// token, then unload it right away. A pull can succeed for a model the server
+// cannot run, e.g. because of a corrupt layer or an unsupported architecture.
+//
+// keep_alive is always 0 rather than configurable: the check runs during
+// apply, where nothing uses the model afterwards, and keeping a model that was
+// only loaded to be checked would take memory from the models being served.
+func verifyModelLoads(ctx context.Context, client *api.Client, budget *inferenceBudget, name string) error {
+	_, err := generate(ctx, client, budget, &api.GenerateRequest{
+		Model:     name,
+		Prompt:    "hi",
+		Options:   map[string]any{"num_predict": 1},
+		KeepAlive: &api.Duration{Duration: 0},
+	})
+	if err != nil {
+		return fmt.Errorf("model %s failed to load: %w", name, err)
+	}
+	return nil
+}
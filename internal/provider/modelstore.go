@@ -0,0 +1,162 @@
+package provider
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	defaultRegistry  = "registry.ollama.ai"
+	defaultNamespace = "library"
+	defaultTag       = "latest"
+
+	mediaTypeModel    = "application/vnd.ollama.image.model"
+	mediaTypeTemplate = "application/vnd.ollama.image.template"
+	mediaTypeParams   = "application/vnd.ollama.image.params"
+)
+
+// modelStore reads the models directory of a local Ollama installation.
+type modelStore struct {
+	dir string
+}
+
+// manifestLayer is a layer of a model manifest.
+type manifestLayer struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+}
+
+// modelManifest is the manifest stored for every pulled or created model.
+type modelManifest struct {
+	Config manifestLayer   `json:"config"`
+	Layers []manifestLayer `json:"layers"`
+}
+
+// Layer returns the first layer of the given media type, or nil.
+func (m *modelManifest) Layer(mediaType string) *manifestLayer {
+	for i := range m.Layers {
+		if m.Layers[i].MediaType == mediaType {
+			return &m.Layers[i]
+		}
+	}
+	return nil
+}
+
+// defaultModelsDir returns the models directory the Ollama server uses unless
+// OLLAMA_MODELS says otherwise.
+func defaultModelsDir() (string, error) {
+	if dir := os.Getenv("OLLAMA_MODELS"); dir != "" {
+		return dir, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".ollama", "models"), nil
+}
+
+// manifestPath returns the manifest file of a model name such as "llama3",
+// "user/model:tag" or "host/namespace/model:tag".
+func (s *modelStore) manifestPath(name string) (string, error) {
+	tag := defaultTag
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, tag = name[:i], name[i+1:]
+	}
+
+	parts := strings.Split(name, "/")
+	switch len(parts) {
+	case 1:
+		parts = []string{defaultRegistry, defaultNamespace, parts[0]}
+	case 2:
+		parts = []string{defaultRegistry, parts[0], parts[1]}
+	case 3:
+	default:
+		return "", fmt.Errorf("invalid model name %q", name)
+	}
+
+	for _, p := range append(parts, tag) {
+		if p == "" || p == "." || p == ".." {
+			return "", fmt.Errorf("invalid model name %q", name)
+		}
+	}
+
+	return filepath.Join(append([]string{s.dir, "manifests"}, append(parts, tag)...)...), nil
+}
+
+// Manifest reads the manifest of the named model.
+func (s *modelStore) Manifest(name string) (*modelManifest, error) {
+	p, err := s.manifestPath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("model %s not found in %s: %w", name, s.dir, err)
+	} else if err != nil {
+		return nil, err
+	}
+
+	var m modelManifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("could not parse manifest %s: %w", p, err)
+	}
+	return &m, nil
+}
+
+// BlobPath returns the file a blob is stored in. Blob files use "-" in place
+// of the ":" of the digest.
+func (s *modelStore) BlobPath(digest string) string {
+	return filepath.Join(s.dir, "blobs", strings.Replace(digest, ":", "-", 1))
+}
+
+// osLink is os.Link, replaced in tests to simulate file systems without
+// hardlinks.
+var osLink = os.Link
+
+// linkOrCopy writes src to dst, as a hardlink if link is set and the file
+// system allows it, otherwise as a copy, and reports whether it linked. dst
+// is replaced atomically.
+func linkOrCopy(src, dst string, link bool) (bool, error) {
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return false, err
+	}
+
+	tmp := dst + ".tmp"
+	os.Remove(tmp)
+
+	if link {
+		if err := osLink(src, tmp); err == nil {
+			return true, os.Rename(tmp, dst)
+		}
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return false, err
+	}
+	defer in.Close()
+
+	out, err := os.Create(tmp)
+	if err != nil {
+		return false, err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return false, err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return false, err
+	}
+
+	return false, os.Rename(tmp, dst)
+}
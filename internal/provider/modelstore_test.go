@@ -0,0 +1,99 @@
+package provider
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestModelStoreManifestPath(t *testing.T) {
+	s := &modelStore{dir: "/models"}
+
+	tests := map[string]struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		"library model":           {name: "llama3", want: "/models/manifests/registry.ollama.ai/library/llama3/latest"},
+		"library model with tag":  {name: "llama3:8b", want: "/models/manifests/registry.ollama.ai/library/llama3/8b"},
+		"user model":              {name: "user/model:v1", want: "/models/manifests/registry.ollama.ai/user/model/v1"},
+		"registry":                {name: "example.com/team/model:v1", want: "/models/manifests/example.com/team/model/v1"},
+		"registry with port":      {name: "example.com:5000/team/model", want: "/models/manifests/example.com:5000/team/model/latest"},
+		"too many parts":          {name: "a/b/c/d", wantErr: true},
+		"parent directory":        {name: "../model", wantErr: true},
+		"parent directory inside": {name: "registry.ollama.ai/../model", wantErr: true},
+		"parent directory as tag": {name: "model:..", wantErr: true},
+		"empty part":              {name: "user//model", wantErr: true},
+		"empty tag":               {name: "model:", wantErr: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := s.manifestPath(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("manifestPath(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if err == nil && got != filepath.FromSlash(tt.want) {
+				t.Errorf("manifestPath(%q) = %s, want %s", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinkOrCopy(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "blob")
+	if err := os.WriteFile(src, []byte("weights"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		link       bool
+		linkFails  bool
+		wantLinked bool
+	}{
+		"copy":                    {link: false, wantLinked: false},
+		"hardlink":                {link: true, wantLinked: true},
+		"hardlink falls back":     {link: true, linkFails: true, wantLinked: false},
+		"copy ignores link error": {link: false, linkFails: true, wantLinked: false},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if tt.linkFails {
+				osLink = func(string, string) error { return errors.New("cross-device link") }
+				t.Cleanup(func() { osLink = os.Link })
+			}
+
+			dst := filepath.Join(t.TempDir(), "out", "model.gguf")
+			// an existing export is replaced
+			if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(dst, []byte("old"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			linked, err := linkOrCopy(src, dst, tt.link)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if linked != tt.wantLinked {
+				t.Errorf("linkOrCopy() linked = %v, want %v", linked, tt.wantLinked)
+			}
+
+			if b, err := os.ReadFile(dst); err != nil || string(b) != "weights" {
+				t.Errorf("output = %q, %v, want the weights", b, err)
+			}
+			srcInfo, _ := os.Stat(src)
+			dstInfo, _ := os.Stat(dst)
+			if os.SameFile(srcInfo, dstInfo) != tt.wantLinked {
+				t.Errorf("output shares the inode of the blob: %v, want %v", !tt.wantLinked, tt.wantLinked)
+			}
+			if _, err := os.Stat(dst + ".tmp"); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("temporary file left behind: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,388 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64default"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/ollama/ollama/api"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ resource.Resource                   = &ollamaBatchInferenceResource{}
+	_ resource.ResourceWithConfigure      = &ollamaBatchInferenceResource{}
+	_ resource.ResourceWithModifyPlan     = &ollamaBatchInferenceResource{}
+	_ resource.ResourceWithValidateConfig = &ollamaBatchInferenceResource{}
+)
+
+// NewOllamaBatchInferenceResource is a helper function to simplify the provider implementation.
+func NewOllamaBatchInferenceResource() resource.Resource {
+	return &ollamaBatchInferenceResource{}
+}
+
+// ollamaBatchInferenceResource runs a prompt template over a JSONL dataset.
+type ollamaBatchInferenceResource struct {
+	client     *api.Client
+	cache      *responseCache
+	budget     *inferenceBudget
+	host       string
+	eol        *eolPolicy
+	advisories *advisoryDatabase
+	lock       *hostLock
+}
+
+func (r *ollamaBatchInferenceResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*OllamaProviderData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *OllamaProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.client = data.Client
+	r.cache = data.Cache
+	r.budget = data.Budget
+	r.host = data.Host
+	r.eol = data.EOL
+	r.advisories = data.Advisories
+	r.lock = data.Lock
+}
+
+// Metadata returns the resource type name.
+func (r *ollamaBatchInferenceResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_batch_inference"
+}
+
+func (r *ollamaBatchInferenceResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Runs every row of a local JSONL dataset through a model and writes the results to a JSONL file. " +
+			"Progress is checkpointed, so interrupted runs resume, and only rows whose prompt or the model digest changed are run again.",
+
+		Attributes: map[string]schema.Attribute{
+			"model": schema.StringAttribute{
+				Description: "The model to run the rows through.",
+				Required:    true,
+			},
+			"mode": schema.StringAttribute{
+				Description: "Whether rows are sent to `generate` or `chat`. Defaults to `generate`.",
+				Optional:    true,
+				Computed:    true,
+				Default:     stringdefault.StaticString(batchModeGenerate),
+			},
+			"input_file": schema.StringAttribute{
+				Description: "Path of the JSONL dataset, one JSON object per line.",
+				Required:    true,
+			},
+			"output_file": schema.StringAttribute{
+				Description: "Path the results are written to, one JSON object with `index`, `input` and `output` per input row.",
+				Required:    true,
+			},
+			"checkpoint_file": schema.StringAttribute{
+				Description: "Path of the checkpoint file. Defaults to the output file with a `.checkpoint` suffix.",
+				Optional:    true,
+			},
+			"prompt_template": schema.StringAttribute{
+				Description: "Go template rendered with the fields of each row, e.g. `Classify: {{ .text }}`.",
+				Required:    true,
+			},
+			"system": schema.StringAttribute{
+				Description: "System message sent with every row.",
+				Optional:    true,
+			},
+			"options": schema.MapAttribute{
+				Description: "Model options such as `temperature` or `seed`.",
+				Optional:    true,
+				ElementType: types.StringType,
+			},
+			"hosts": schema.ListAttribute{
+				Description: "Ollama hosts to spread the rows across. Each host must serve the model with the same digest. " +
+					"A row that fails on one host is retried on the others. Must not be empty, leave it unset to use the provider host.",
+				Optional:    true,
+				ElementType: types.StringType,
+			},
+			"concurrency": schema.Int64Attribute{
+				Description: "Number of rows run at the same time on each host. Defaults to 4.",
+				Optional:    true,
+				Computed:    true,
+				Default:     int64default.StaticInt64(defaultBatchConcurrency),
+			},
+			"keep_alive": schema.StringAttribute{
+				Description: "How long the model stays loaded after the run, as a duration such as `10m` or a number of seconds. A negative value keeps it loaded. Defaults to the server setting.",
+				Optional:    true,
+				CustomType:  KeepAliveType{},
+			},
+			"input_sha256": schema.StringAttribute{
+				Description: "SHA-256 of the input file. A change triggers a new run.",
+				Computed:    true,
+			},
+			"model_digest": schema.StringAttribute{
+				Description: "Digest of the model the results were produced with. A change triggers a new run.",
+				Computed:    true,
+				CustomType:  DigestType{},
+			},
+			"rows_total": schema.Int64Attribute{
+				Description: "Number of rows in the output file.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+func (r *ollamaBatchInferenceResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var config OllamaBatchInferenceResource
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if mode := config.Mode.ValueString(); !config.Mode.IsUnknown() && !config.Mode.IsNull() && mode != batchModeGenerate && mode != batchModeChat {
+		resp.Diagnostics.AddAttributeError(path.Root("mode"), "Invalid mode", fmt.Sprintf("mode must be %q or %q, got %q.", batchModeGenerate, batchModeChat, mode))
+	}
+	if !config.Concurrency.IsNull() && !config.Concurrency.IsUnknown() && config.Concurrency.ValueInt64() < 1 {
+		resp.Diagnostics.AddAttributeError(path.Root("concurrency"), "Invalid concurrency", "concurrency must be at least 1.")
+	}
+}
+
+// ModifyPlan hashes the input file and looks up the model digest, so changes
+// to either show up in the plan.
+func (r *ollamaBatchInferenceResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() || r.client == nil {
+		return
+	}
+
+	if r.advisories.Check(ctx, &resp.Diagnostics); resp.Diagnostics.HasError() {
+		return
+	}
+
+	var plan OllamaBatchInferenceResource
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if r.eol.Check(path.Root("model"), plan.Model, &resp.Diagnostics); resp.Diagnostics.HasError() {
+		return
+	}
+
+	plan.InputSHA256 = types.StringUnknown()
+	if !plan.InputFile.IsUnknown() {
+		sum, err := fileSHA256(plan.InputFile.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("input_file"), "Error reading input file", err.Error())
+			return
+		}
+		plan.InputSHA256 = types.StringValue(sum)
+	}
+
+	// Check covers the provider host only, the hosts of a pool are checked here
+	var pool *hostPool
+	if !plan.Hosts.IsUnknown() {
+		var d diag.Diagnostics
+		pool, d = newHostPool(ctx, plan.Hosts, r.client, r.host, plan.Concurrency)
+		if resp.Diagnostics.Append(d...); resp.Diagnostics.HasError() {
+			return
+		}
+		if pool.CheckAdvisories(ctx, r.advisories, &resp.Diagnostics); resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	if !req.State.Raw.IsNull() {
+		var state OllamaBatchInferenceResource
+		resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+
+		plan.ModelDigest = NewDigestUnknown()
+		if pool != nil {
+			digest, err := plannedModelDigest(ctx, pool.Primary(), plan.Model, state.ModelDigest)
+			if err != nil {
+				resp.Diagnostics.AddError("Error reading model", err.Error())
+				return
+			}
+			plan.ModelDigest = digest
+		}
+
+		if plan.InputSHA256.Equal(state.InputSHA256) {
+			plan.RowsTotal = state.RowsTotal
+		} else {
+			plan.RowsTotal = types.Int64Unknown()
+		}
+	}
+
+	resp.Diagnostics.Append(resp.Plan.Set(ctx, plan)...)
+}
+
+// Create creates the resource and sets the initial Terraform state.
+func (r *ollamaBatchInferenceResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	unlock, ok := lockHost(ctx, r.lock, &resp.Diagnostics)
+	if !ok {
+		return
+	}
+	defer unlock()
+
+	var plan OllamaBatchInferenceResource
+	diags := req.Plan.Get(ctx, &plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(r.run(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	diags = resp.State.Set(ctx, plan)
+	resp.Diagnostics.Append(diags...)
+}
+
+// Read removes the resource when the output file is gone, so it is run again.
+func (r *ollamaBatchInferenceResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state OllamaBatchInferenceResource
+	diags := req.State.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if _, err := os.Stat(state.OutputFile.ValueString()); errors.Is(err, fs.ErrNotExist) {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+}
+
+// Update runs the rows that changed since the last run.
+func (r *ollamaBatchInferenceResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	unlock, ok := lockHost(ctx, r.lock, &resp.Diagnostics)
+	if !ok {
+		return
+	}
+	defer unlock()
+
+	var plan OllamaBatchInferenceResource
+	diags := req.Plan.Get(ctx, &plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(r.run(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	diags = resp.State.Set(ctx, plan)
+	resp.Diagnostics.Append(diags...)
+}
+
+// Delete removes the output and checkpoint files.
+func (r *ollamaBatchInferenceResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state OllamaBatchInferenceResource
+	diags := req.State.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	for _, p := range []string{state.OutputFile.ValueString(), batchCheckpointPath(&state)} {
+		if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			resp.Diagnostics.AddError("Error deleting batch inference output", err.Error())
+		}
+	}
+}
+
+func batchCheckpointPath(m *OllamaBatchInferenceResource) string {
+	if !m.CheckpointFile.IsNull() {
+		return m.CheckpointFile.ValueString()
+	}
+	return m.OutputFile.ValueString() + ".checkpoint"
+}
+
+func (r *ollamaBatchInferenceResource) run(ctx context.Context, plan *OllamaBatchInferenceResource) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	rows, err := readJSONL(plan.InputFile.ValueString())
+	if err != nil {
+		diags.AddAttributeError(path.Root("input_file"), "Error reading input file", err.Error())
+		return diags
+	}
+
+	prompts, err := renderPrompts(plan.PromptTemplate.ValueString(), rows)
+	if err != nil {
+		diags.AddAttributeError(path.Root("prompt_template"), "Error rendering prompt template", err.Error())
+		return diags
+	}
+
+	options, d := requestOptions(ctx, plan.Options)
+	if diags.Append(d...); diags.HasError() {
+		return diags
+	}
+
+	pool, d := newHostPool(ctx, plan.Hosts, r.client, r.host, plan.Concurrency)
+	if diags.Append(d...); diags.HasError() {
+		return diags
+	}
+
+	digest, err := pool.ModelDigest(ctx, plan.Model.ValueString())
+	if err != nil {
+		diags.AddAttributeError(path.Root("model"), "Error reading model", err.Error())
+		return diags
+	}
+
+	sum, err := fileSHA256(plan.InputFile.ValueString())
+	if err != nil {
+		diags.AddAttributeError(path.Root("input_file"), "Error reading input file", err.Error())
+		return diags
+	}
+
+	job := &batchJob{
+		pool:       pool,
+		cache:      r.cache,
+		budget:     r.budget,
+		model:      plan.Model.ValueString(),
+		digest:     digest,
+		mode:       plan.Mode.ValueString(),
+		system:     plan.System.ValueString(),
+		options:    options,
+		keepAlive:  plan.KeepAlive.Duration(),
+		checkpoint: batchCheckpointPath(plan),
+	}
+
+	results, err := job.Run(ctx, rows, prompts)
+	r.budget.LogUsage(ctx)
+	if err != nil {
+		diags.AddError("Error running batch inference", fmt.Sprintf("Batch inference stopped, finished rows are kept in the checkpoint: %s", err))
+		return diags
+	}
+
+	if err := writeJSONL(plan.OutputFile.ValueString(), results); err != nil {
+		diags.AddAttributeError(path.Root("output_file"), "Error writing output file", err.Error())
+		return diags
+	}
+
+	plan.InputSHA256 = types.StringValue(sum)
+	plan.ModelDigest = NewDigestValue(digest)
+	plan.RowsTotal = types.Int64Value(int64(len(results)))
+
+	return diags
+}
@@ -0,0 +1,155 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &OllamaCatalogDataSource{}
+
+const defaultCatalogEnvironment = "default"
+
+func NewOllamaCatalogDataSource() datasource.DataSource {
+	return &OllamaCatalogDataSource{}
+}
+
+// OllamaCatalogDataSource resolves logical model roles from a catalog file.
+type OllamaCatalogDataSource struct{}
+
+// OllamaCatalogDataSourceModel describes the data source data model.
+type OllamaCatalogDataSourceModel struct {
+	File                types.String                  `tfsdk:"file"`
+	Environment         types.String                  `tfsdk:"environment"`
+	FallbackEnvironment types.String                  `tfsdk:"fallback_environment"`
+	Roles               []types.String                `tfsdk:"roles"`
+	Models              map[string]types.String       `tfsdk:"models"`
+	Entries             map[string]OllamaCatalogEntry `tfsdk:"entries"`
+}
+
+func (d *OllamaCatalogDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_catalog"
+}
+
+func (d *OllamaCatalogDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Resolves logical model roles such as `chat-default` to concrete models for an environment, " +
+			"from a JSON or YAML catalog file. Every entry of the catalog is validated against the model name grammar.",
+
+		Attributes: map[string]schema.Attribute{
+			"file": schema.StringAttribute{
+				Description: "The catalog file. Files ending in `.yaml` or `.yml` are read as YAML, all others as JSON. " +
+					"The file holds a `roles` object mapping each role to environments, and each environment to a `model` and an optional pinned `digest`.",
+				Required: true,
+			},
+			"environment": schema.StringAttribute{
+				Description: "The environment to resolve the roles for, e.g. `prod`.",
+				Required:    true,
+			},
+			"fallback_environment": schema.StringAttribute{
+				Description: "The environment used for roles without an entry for `environment`. Defaults to `default`.",
+				Optional:    true,
+			},
+			"roles": schema.ListAttribute{
+				Description: "Roles that must resolve. Reading fails if one of them has no entry. By default all roles that resolve are returned.",
+				Optional:    true,
+				ElementType: types.StringType,
+			},
+			"models": schema.MapAttribute{
+				Description: "The model name for each resolved role, for use as the name of `ollama_model`.",
+				Computed:    true,
+				ElementType: types.StringType,
+			},
+			"entries": schema.MapNestedAttribute{
+				Description: "The resolved catalog entry for each role.",
+				Computed:    true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"model": schema.StringAttribute{
+							Description: "The model name.",
+							Computed:    true,
+						},
+						"digest": schema.StringAttribute{
+							Description: "The pinned digest, if any.",
+							Computed:    true,
+							CustomType:  DigestType{},
+						},
+						"environment": schema.StringAttribute{
+							Description: "The environment the entry was taken from, `environment` or `fallback_environment`.",
+							Computed:    true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (d *OllamaCatalogDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data OllamaCatalogDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	catalog, err := loadCatalog(data.File.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("file"), "Error reading model catalog", err.Error())
+		return
+	}
+
+	fallback := defaultCatalogEnvironment
+	if !data.FallbackEnvironment.IsNull() {
+		fallback = data.FallbackEnvironment.ValueString()
+	}
+
+	roles := sortedKeys(catalog.Roles)
+	if data.Roles != nil {
+		roles = nil
+		for _, r := range data.Roles {
+			roles = append(roles, r.ValueString())
+		}
+	}
+
+	data.Models = map[string]types.String{}
+	data.Entries = map[string]OllamaCatalogEntry{}
+	for _, role := range roles {
+		entry, env, ok := catalog.Resolve(role, data.Environment.ValueString(), fallback)
+		if !ok {
+			if data.Roles != nil {
+				resp.Diagnostics.AddAttributeError(
+					path.Root("roles"),
+					"Unresolved model role",
+					fmt.Sprintf("Role %q has no entry for environment %q or %q in %s.", role, data.Environment.ValueString(), fallback, data.File.ValueString()),
+				)
+			}
+			continue
+		}
+
+		digest := NewDigestNull()
+		if entry.Digest != "" {
+			digest = NewDigestValue(entry.Digest)
+		}
+
+		data.Models[role] = types.StringValue(entry.Model)
+		data.Entries[role] = OllamaCatalogEntry{
+			Model:       types.StringValue(entry.Model),
+			Digest:      digest,
+			Environment: types.StringValue(env),
+		}
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	diags := resp.State.Set(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+}
@@ -0,0 +1,642 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/ollama/ollama/api"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ resource.Resource                = &ollamaCustomModelResource{}
+	_ resource.ResourceWithConfigure   = &ollamaCustomModelResource{}
+	_ resource.ResourceWithImportState = &ollamaCustomModelResource{}
+	_ resource.ResourceWithModifyPlan  = &ollamaCustomModelResource{}
+
+	_ resource.ResourceWithValidateConfig = &ollamaCustomModelResource{}
+)
+
+var ollamaMessageType = types.ObjectType{AttrTypes: map[string]attr.Type{
+	"role":    types.StringType,
+	"content": types.StringType,
+}}
+
+// NewOllamaCustomModelResource is a helper function to simplify the provider implementation.
+func NewOllamaCustomModelResource() resource.Resource {
+	return &ollamaCustomModelResource{}
+}
+
+// ollamaCustomModelResource creates models from Modelfile instructions, like `ollama create`.
+type ollamaCustomModelResource struct {
+	client     *api.Client
+	uploader   *blobUploader
+	eol        *eolPolicy
+	lock       *hostLock
+	lineage    *lineageEmitter
+	advisories *advisoryDatabase
+}
+
+func (r *ollamaCustomModelResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*OllamaProviderData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *OllamaProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.client = data.Client
+	r.uploader = newBlobUploader(data.Client, data.Base, data.HTTP)
+	r.eol = data.EOL
+	r.lock = data.Lock
+	r.lineage = data.Lineage
+	r.advisories = data.Advisories
+}
+
+// ModifyPlan checks the host for known vulnerabilities and warns about base
+// models that are scheduled for retirement.
+func (r *ollamaCustomModelResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	if r.advisories.Check(ctx, &resp.Diagnostics); resp.Diagnostics.HasError() || r.eol == nil {
+		return
+	}
+
+	var (
+		from      types.String
+		modelfile ModelfileValue
+	)
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("from"), &from)...)
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("modelfile"), &modelfile)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	fromPath := path.Root("from")
+	if from.IsUnknown() && !modelfile.IsNull() && !modelfile.IsUnknown() {
+		mf, err := parseModelfile(modelfile.ValueString())
+		if err != nil {
+			return
+		}
+		from, fromPath = types.StringValue(mf.From), path.Root("modelfile")
+	}
+	if from.IsUnknown() || isLocalModelPath(from.ValueString(), "") {
+		return
+	}
+
+	r.eol.Check(fromPath, from, &resp.Diagnostics)
+}
+
+// Metadata returns the resource type name.
+func (r *ollamaCustomModelResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_custom_model"
+}
+
+func (r *ollamaCustomModelResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Creates a custom Ollama model from Modelfile instructions, like `ollama create`. " +
+			"Models created by hand can be imported by name.",
+
+		Attributes: map[string]schema.Attribute{
+			"name": schema.StringAttribute{
+				Description: "The name of the model to create.",
+				Required:    true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"from": schema.StringAttribute{
+				Description: "The base model, or the path of a local GGUF file which is uploaded to the server. Required unless `modelfile` is set.",
+				Optional:    true,
+				Computed:    true,
+			},
+			"system": schema.StringAttribute{
+				Description: "The system message. Inherited from the base model when not set.",
+				Optional:    true,
+				Computed:    true,
+			},
+			"template": schema.StringAttribute{
+				Description: "The prompt template. Inherited from the base model when not set.",
+				Optional:    true,
+				Computed:    true,
+			},
+			"parameters": schema.MapAttribute{
+				Description: "Model parameters such as `temperature` or `num_ctx`. Inherited from the base model when not set.",
+				Optional:    true,
+				Computed:    true,
+				ElementType: types.StringType,
+			},
+			"stop": schema.ListAttribute{
+				Description: "Stop sequences. Inherited from the base model when not set.",
+				Optional:    true,
+				Computed:    true,
+				ElementType: types.StringType,
+			},
+			"messages": schema.ListNestedAttribute{
+				Description: "Message history the model starts conversations with.",
+				Optional:    true,
+				Computed:    true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"role": schema.StringAttribute{
+							Description: "The role of the message, one of `system`, `user` or `assistant`.",
+							Required:    true,
+						},
+						"content": schema.StringAttribute{
+							Description: "The content of the message.",
+							Required:    true,
+						},
+					},
+				},
+			},
+			"adapters": schema.ListAttribute{
+				Description: "LoRA adapters to apply, as local file paths which are uploaded to the server and compared by their digest. Adapters that are not configured, such as those of a `modelfile`, are not tracked.",
+				Optional:    true,
+				ElementType: types.StringType,
+			},
+			"modelfile": schema.StringAttribute{
+				Description: "A Modelfile to create the model from, instead of `from` and the other instruction attributes, which must not be set with it. " +
+					"Instructions it leaves out are inherited from the base model and not tracked. When not set, the Modelfile the model was created from as reported by the server. " +
+					"Changes in formatting, comments, quoting or directive order are not reported as changes.",
+				Optional:   true,
+				Computed:   true,
+				CustomType: ModelfileType{},
+			},
+			"digest": schema.StringAttribute{
+				Description: "The digest of the created model.",
+				Computed:    true,
+				CustomType:  DigestType{},
+			},
+			"security_scan": securityScanAttribute(),
+		},
+	}
+}
+
+// ValidateConfig requires either from or modelfile, and rejects instruction
+// attributes next to modelfile.
+func (r *ollamaCustomModelResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var config OllamaCustomModelResource
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() || config.Modelfile.IsUnknown() {
+		return
+	}
+
+	if config.Modelfile.IsNull() {
+		if config.From.IsNull() {
+			resp.Diagnostics.AddAttributeError(path.Root("from"), "Missing base model", "Either from or modelfile must be set.")
+		}
+		return
+	}
+
+	for _, a := range []struct {
+		name  string
+		value attr.Value
+	}{
+		{"from", config.From},
+		{"system", config.System},
+		{"template", config.Template},
+		{"parameters", config.Parameters},
+		{"stop", config.Stop},
+		{"messages", config.Messages},
+		{"adapters", config.Adapters},
+	} {
+		if !a.value.IsNull() {
+			resp.Diagnostics.AddAttributeError(
+				path.Root(a.name),
+				"Conflicting model instructions",
+				fmt.Sprintf("%s cannot be set together with modelfile, set it in the Modelfile instead.", a.name),
+			)
+		}
+	}
+}
+
+// Create creates the resource and sets the initial Terraform state.
+func (r *ollamaCustomModelResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	unlock, ok := lockHost(ctx, r.lock, &resp.Diagnostics)
+	if !ok {
+		return
+	}
+	defer unlock()
+
+	var plan OllamaCustomModelResource
+	diags := req.Plan.Get(ctx, &plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(r.createModel(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	diags = resp.State.Set(ctx, plan)
+	resp.Diagnostics.Append(diags...)
+}
+
+// Read refreshes the Terraform state with the latest data.
+func (r *ollamaCustomModelResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state OllamaCustomModelResource
+	diags := req.State.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	found, diags := r.readModel(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !found {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	diags = resp.State.Set(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+}
+
+// Update re-creates the model in place, `ollama create` overwrites existing models.
+func (r *ollamaCustomModelResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	unlock, ok := lockHost(ctx, r.lock, &resp.Diagnostics)
+	if !ok {
+		return
+	}
+	defer unlock()
+
+	var plan OllamaCustomModelResource
+	diags := req.Plan.Get(ctx, &plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(r.createModel(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	diags = resp.State.Set(ctx, plan)
+	resp.Diagnostics.Append(diags...)
+}
+
+// Delete deletes the resource and removes the Terraform state on success.
+func (r *ollamaCustomModelResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	unlock, ok := lockHost(ctx, r.lock, &resp.Diagnostics)
+	if !ok {
+		return
+	}
+	defer unlock()
+
+	var state OllamaCustomModelResource
+	diags := req.State.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := r.client.Delete(ctx, &api.DeleteRequest{Model: state.Name.ValueString()})
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Error deleting Ollama Model",
+			"Could not delete ollama model "+state.Name.ValueString()+": "+err.Error(),
+		)
+		return
+	}
+}
+
+// ImportState adopts an existing model by name, Read fills in the rest from its Modelfile.
+//
+// Show reports the base of a model created from a local GGUF file as the
+// server blob the file was uploaded to, so such a model is imported as
+// <name>,<path>. The file is checked against the blob and kept as from, like
+// it would be after a create.
+func (r *ollamaCustomModelResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	parts := strings.Split(req.ID, ",")
+	name, from, adapters := parts[0], "", []string(nil)
+	if len(parts) > 1 {
+		from, adapters = parts[1], parts[2:]
+	}
+	if name == "" || (len(parts) == 2 && from == "") || slices.Contains(adapters, "") {
+		resp.Diagnostics.AddError(
+			"Invalid import ID",
+			fmt.Sprintf("Expected a model name, optionally followed by the path of the GGUF file it was created from and the paths of its adapters, "+
+				"as <name>,<path>,<adapter>,... with an empty path for a model created from another model, got %q.", req.ID),
+		)
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("name"), name)...)
+	if len(parts) == 1 {
+		return
+	}
+
+	show, err := r.client.Show(ctx, &api.ShowRequest{Model: name})
+	if err != nil {
+		resp.Diagnostics.AddError("Error importing Ollama Model", "Could not read ollama model "+name+": "+err.Error())
+		return
+	}
+	mf, err := parseModelfile(show.Modelfile)
+	if err != nil {
+		resp.Diagnostics.AddError("Error importing Ollama Model", "Could not parse modelfile of ollama model "+name+": "+err.Error())
+		return
+	}
+
+	if from != "" {
+		if !isServerBlob(mf.From) {
+			resp.Diagnostics.AddError(
+				"Error importing Ollama Model",
+				fmt.Sprintf("Model %s is created from %s, not from a local file. Import it by name only.", name, mf.From),
+			)
+			return
+		}
+
+		digest, _, err := fileDigest(from)
+		if err != nil {
+			resp.Diagnostics.AddError("Error importing Ollama Model", fmt.Sprintf("Could not hash %s: %s", from, err))
+			return
+		}
+		if normalizeDigest(digest) != normalizeDigest(filepath.Base(mf.From)) {
+			resp.Diagnostics.AddError(
+				"Error importing Ollama Model",
+				fmt.Sprintf("Model %s is not created from %s: the file has digest %s, the model is created from blob %s.", name, from, digest, filepath.Base(mf.From)),
+			)
+			return
+		}
+
+		resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("from"), from)...)
+	}
+
+	if len(adapters) > 0 {
+		if len(adapters) != len(mf.Adapters) {
+			resp.Diagnostics.AddError(
+				"Error importing Ollama Model",
+				fmt.Sprintf("Model %s has %d adapters, the import ID lists %d.", name, len(mf.Adapters), len(adapters)),
+			)
+			return
+		}
+		for i, a := range adapters {
+			same, err := fileIsBlob(a, mf.Adapters[i])
+			if err != nil {
+				resp.Diagnostics.AddError("Error importing Ollama Model", fmt.Sprintf("Could not hash %s: %s", a, err))
+				return
+			}
+			if !same {
+				resp.Diagnostics.AddError(
+					"Error importing Ollama Model",
+					fmt.Sprintf("Adapter %d of model %s is %s, not the file %s.", i+1, name, mf.Adapters[i], a),
+				)
+				return
+			}
+		}
+		resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("adapters"), adapters)...)
+	}
+}
+
+// createModel builds the Modelfile from the plan, uploads local files, creates
+// the model and refreshes the computed attributes in plan.
+func (r *ollamaCustomModelResource) createModel(ctx context.Context, plan *OllamaCustomModelResource) diag.Diagnostics {
+	mf, diags := r.planModelfile(ctx, plan)
+	if diags.HasError() {
+		return diags
+	}
+
+	if plan.SecurityScan != nil {
+		if diags.Append(scanBeforeCreate(mf, "", plan.SecurityScan)...); diags.HasError() {
+			return diags
+		}
+	}
+
+	if err := r.uploader.UploadModelfileFiles(ctx, mf, ""); err != nil {
+		diags.AddError("Error uploading model files", err.Error())
+		return diags
+	}
+
+	tflog.Debug(ctx, fmt.Sprintf("creating model %s from modelfile:\n%s", plan.Name.ValueString(), mf))
+
+	run := r.lineage.Start(ctx, lineageJobCreate, lineageBase(mf, ""), []lineageDataset{{Name: plan.Name.ValueString()}})
+	noStream := false
+	err := r.client.Create(ctx, &api.CreateRequest{
+		Model:     plan.Name.ValueString(),
+		Modelfile: mf.String(),
+		Stream:    &noStream,
+	}, PullResponseFn)
+	run.Finish(ctx, err)
+	if err != nil {
+		diags.AddError(
+			"Error creating model",
+			fmt.Sprintf("Could not create model %s, unexpected error: %s", plan.Name.ValueString(), err.Error()),
+		)
+		return diags
+	}
+
+	found, readDiags := r.readModel(ctx, plan)
+	if diags.Append(readDiags...); diags.HasError() {
+		return diags
+	}
+	if !found {
+		diags.AddError("Error creating model", fmt.Sprintf("Model %s was not found after it was created.", plan.Name.ValueString()))
+	}
+
+	return diags
+}
+
+// planModelfile parses the configured modelfile, or converts the known
+// attributes of plan into a modelfile. Unknown attributes are left out so they
+// are inherited from the base model.
+func (r *ollamaCustomModelResource) planModelfile(ctx context.Context, plan *OllamaCustomModelResource) (*modelfile, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	if !plan.Modelfile.IsNull() && !plan.Modelfile.IsUnknown() {
+		mf, err := parseModelfile(plan.Modelfile.ValueString())
+		if err != nil {
+			diags.AddAttributeError(path.Root("modelfile"), "Invalid Modelfile", fmt.Sprintf("The Modelfile could not be parsed: %s", err))
+		}
+		return mf, diags
+	}
+
+	mf := &modelfile{
+		From:       plan.From.ValueString(),
+		System:     plan.System.ValueString(),
+		Template:   plan.Template.ValueString(),
+		Parameters: map[string]string{},
+	}
+
+	if !plan.Parameters.IsNull() && !plan.Parameters.IsUnknown() {
+		diags.Append(plan.Parameters.ElementsAs(ctx, &mf.Parameters, false)...)
+	}
+	if !plan.Stop.IsNull() && !plan.Stop.IsUnknown() {
+		diags.Append(plan.Stop.ElementsAs(ctx, &mf.Stop, false)...)
+	}
+	if !plan.Adapters.IsNull() && !plan.Adapters.IsUnknown() {
+		diags.Append(plan.Adapters.ElementsAs(ctx, &mf.Adapters, false)...)
+	}
+	if !plan.Messages.IsNull() && !plan.Messages.IsUnknown() {
+		var messages []OllamaMessage
+		diags.Append(plan.Messages.ElementsAs(ctx, &messages, false)...)
+		for _, m := range messages {
+			mf.Messages = append(mf.Messages, api.Message{Role: m.Role.ValueString(), Content: m.Content.ValueString()})
+		}
+	}
+
+	return mf, diags
+}
+
+// sameAdapters reports whether the configured adapters are the ones the
+// server applies, in the same order. A local file matches the server blob
+// with its digest.
+func sameAdapters(configured, current []string) bool {
+	if len(configured) != len(current) {
+		return false
+	}
+	for i, a := range configured {
+		if a == current[i] {
+			continue
+		}
+		if same, err := fileIsBlob(a, current[i]); err != nil || !same {
+			return false
+		}
+	}
+	return true
+}
+
+// fileIsBlob reports whether the local file at path has the content of blob, a
+// path in the models store of the server as Show reports it.
+func fileIsBlob(path, blob string) (bool, error) {
+	if !isServerBlob(blob) {
+		return false, nil
+	}
+	digest, _, err := fileDigest(path)
+	if err != nil {
+		return false, err
+	}
+	return normalizeDigest(digest) == normalizeDigest(filepath.Base(blob)), nil
+}
+
+// modelfileInEffect reports whether v is a configured Modelfile whose
+// instructions are all in effect in current, the Modelfile of the server.
+func modelfileInEffect(v ModelfileValue, current *modelfile) bool {
+	if v.IsNull() || v.IsUnknown() {
+		return false
+	}
+	configured, err := parseModelfile(v.ValueString())
+	if err != nil || isServerBlob(configured.From) {
+		return false
+	}
+	return current.covers(configured)
+}
+
+// readModel refreshes model from the server and reports whether it exists.
+// Configured values that refer to the same thing as the server's, such as a
+// local file for FROM or a name without tag, are kept.
+func (r *ollamaCustomModelResource) readModel(ctx context.Context, model *OllamaCustomModelResource) (bool, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	show, err := r.client.Show(ctx, &api.ShowRequest{Model: model.Name.ValueString()})
+	if err != nil {
+		if apiErr, ok := err.(api.StatusError); ok && apiErr.StatusCode == 404 {
+			return false, diags
+		}
+
+		diags.AddError(
+			"Error Reading Ollama Model",
+			"Could not read ollama model "+model.Name.ValueString()+": "+err.Error(),
+		)
+		return false, diags
+	}
+
+	mf, err := parseModelfile(show.Modelfile)
+	if err != nil {
+		diags.AddError(
+			"Error Reading Ollama Model",
+			"Could not parse modelfile of ollama model "+model.Name.ValueString()+": "+err.Error(),
+		)
+		return false, diags
+	}
+
+	if model.From.IsNull() || !(isLocalModelPath(model.From.ValueString(), "") || sameModelName(model.From.ValueString(), mf.From)) {
+		model.From = types.StringValue(mf.From)
+	}
+
+	// adapters are only tracked when configured, those of a Modelfile are
+	// part of it; configured files are kept while the server applies them
+	var d diag.Diagnostics
+	if !model.Adapters.IsNull() && !model.Adapters.IsUnknown() {
+		var adapters []string
+		diags.Append(model.Adapters.ElementsAs(ctx, &adapters, false)...)
+		if !sameAdapters(adapters, mf.Adapters) {
+			model.Adapters, d = types.ListValueFrom(ctx, types.StringType, append([]string{}, mf.Adapters...))
+			diags.Append(d...)
+		}
+	}
+
+	model.System = types.StringValue(show.System)
+	model.Template = types.StringValue(show.Template)
+
+	// a configured Modelfile is kept while its instructions are in effect;
+	// one the server reported, with a blob for FROM, is replaced by the
+	// current one so that any change shows
+	if !modelfileInEffect(model.Modelfile, mf) {
+		model.Modelfile = NewModelfileValue(show.Modelfile)
+	}
+
+	// Parameters of the base model are inherited, so when parameters are
+	// configured only those are tracked.
+	parameters := mf.Parameters
+	if !model.Parameters.IsNull() && !model.Parameters.IsUnknown() {
+		parameters = map[string]string{}
+		for k := range model.Parameters.Elements() {
+			if v, ok := mf.Parameters[k]; ok {
+				parameters[k] = v
+			}
+		}
+	}
+	model.Parameters, d = types.MapValueFrom(ctx, types.StringType, parameters)
+	diags.Append(d...)
+
+	model.Stop, d = types.ListValueFrom(ctx, types.StringType, append([]string{}, mf.Stop...))
+	diags.Append(d...)
+
+	messages := []OllamaMessage{}
+	for _, m := range show.Messages {
+		messages = append(messages, OllamaMessage{Role: types.StringValue(m.Role), Content: types.StringValue(m.Content)})
+	}
+	model.Messages, d = types.ListValueFrom(ctx, ollamaMessageType, messages)
+	diags.Append(d...)
+
+	listed, err := lookupModel(ctx, r.client, model.Name.ValueString())
+	if err != nil {
+		diags.AddError("Error Reading Ollama Model", err.Error())
+		return false, diags
+	}
+	if listed == nil {
+		return false, diags
+	}
+	model.Digest = NewDigestValue(listed.Digest)
+
+	return true, diags
+}
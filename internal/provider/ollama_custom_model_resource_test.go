@@ -0,0 +1,223 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/ollama/ollama/api"
+)
+
+func testCustomModel(name, from string) *OllamaCustomModelResource {
+	return &OllamaCustomModelResource{
+		Name:       types.StringValue(name),
+		From:       types.StringValue(from),
+		System:     types.StringNull(),
+		Template:   types.StringNull(),
+		Parameters: types.MapNull(types.StringType),
+		Stop:       types.ListNull(types.StringType),
+		Messages:   types.ListNull(ollamaMessageType),
+		Adapters:   types.ListNull(types.StringType),
+		Modelfile:  ModelfileValue{StringValue: types.StringNull()},
+		Digest:     NewDigestNull(),
+	}
+}
+
+func withAdapters(m *OllamaCustomModelResource, adapters ...string) *OllamaCustomModelResource {
+	m.Adapters, _ = types.ListValueFrom(context.Background(), types.StringType, adapters)
+	return m
+}
+
+func newTestCustomModelResource(t *testing.T, srv *httptest.Server, client *api.Client) *ollamaCustomModelResource {
+	t.Helper()
+
+	base, err := parseHost(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &ollamaCustomModelResource{client: client, uploader: newBlobUploader(client, base, http.DefaultClient)}
+}
+
+// writeTestBlob writes content to name in a temporary directory and returns
+// its path and the path Show reports for it once uploaded.
+func writeTestBlob(t *testing.T, name, content string) (string, string) {
+	t.Helper()
+
+	p := writeTestFile(t, name, []byte(content))
+	digest, _, err := fileDigest(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p, fakeBlobDir + "sha256-" + normalizeDigest(digest)
+}
+
+func TestOllamaCustomModelResourceImport(t *testing.T) {
+	gguf, blob := writeTestBlob(t, "mario.gguf", "GGUF weights")
+	other, _ := writeTestBlob(t, "other.gguf", "other weights")
+	adapter, adapterBlob := writeTestBlob(t, "mario.safetensors", "adapter weights")
+
+	tests := map[string]struct {
+		modelfile string
+		id        string
+		config    *OllamaCustomModelResource
+		wantErr   bool
+		// wantChanges are the attributes the next plan changes
+		wantChanges []string
+	}{
+		"base model by name": {
+			modelfile: "FROM llama3:latest\nSYSTEM You are Mario.\n",
+			id:        "mario",
+			config:    testCustomModel("mario", "llama3:latest"),
+		},
+		"local file with its path": {
+			modelfile: fmt.Sprintf("FROM %s\nSYSTEM You are Mario.\n", blob),
+			id:        "mario," + gguf,
+			config:    testCustomModel("mario", gguf),
+		},
+		"local file by name only plans the path": {
+			modelfile:   fmt.Sprintf("FROM %s\n", blob),
+			id:          "mario",
+			config:      testCustomModel("mario", gguf),
+			wantChanges: []string{"from"},
+		},
+		"path of another file": {
+			modelfile: fmt.Sprintf("FROM %s\n", blob),
+			id:        "mario," + other,
+			wantErr:   true,
+		},
+		"path for a model not created from a file": {
+			modelfile: "FROM llama3:latest\n",
+			id:        "mario," + gguf,
+			wantErr:   true,
+		},
+		"empty path": {
+			modelfile: fmt.Sprintf("FROM %s\n", blob),
+			id:        "mario,",
+			wantErr:   true,
+		},
+		"adapter with its path": {
+			modelfile: fmt.Sprintf("FROM llama3:latest\nADAPTER %s\n", adapterBlob),
+			id:        "mario,," + adapter,
+			config:    withAdapters(testCustomModel("mario", "llama3:latest"), adapter),
+		},
+		"adapter by name only plans the path": {
+			modelfile:   fmt.Sprintf("FROM llama3:latest\nADAPTER %s\n", adapterBlob),
+			id:          "mario",
+			config:      withAdapters(testCustomModel("mario", "llama3:latest"), adapter),
+			wantChanges: []string{"adapters"},
+		},
+		"local file and adapter": {
+			modelfile: fmt.Sprintf("FROM %s\nADAPTER %s\n", blob, adapterBlob),
+			id:        "mario," + gguf + "," + adapter,
+			config:    withAdapters(testCustomModel("mario", gguf), adapter),
+		},
+		"path of another adapter": {
+			modelfile: fmt.Sprintf("FROM llama3:latest\nADAPTER %s\n", adapterBlob),
+			id:        "mario,," + other,
+			wantErr:   true,
+		},
+		"more adapters than the model has": {
+			modelfile: fmt.Sprintf("FROM llama3:latest\nADAPTER %s\n", adapterBlob),
+			id:        "mario,," + adapter + "," + adapter,
+			wantErr:   true,
+		},
+		"empty adapter": {
+			modelfile: fmt.Sprintf("FROM llama3:latest\nADAPTER %s\n", adapterBlob),
+			id:        "mario,," + adapter + ",",
+			wantErr:   true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			f, srv, client := newFakeOllama(t)
+			f.AddModel("mario")
+			f.Modelfiles["mario:latest"] = tt.modelfile
+			r := newTestCustomModelResource(t, srv, client)
+
+			var state OllamaCustomModelResource
+			diags := importResource(t, r, tt.id, &state)
+			if tt.wantErr {
+				if !diags.HasError() {
+					t.Fatal("import succeeded, want an error")
+				}
+				return
+			}
+			if diags.HasError() {
+				t.Fatal(diags)
+			}
+
+			if got := plannedChanges(t, r, tt.config, &state); !reflect.DeepEqual(got, tt.wantChanges) {
+				t.Errorf("next plan changes %v, want %v", got, tt.wantChanges)
+			}
+		})
+	}
+}
+
+func TestOllamaCustomModelResourceAdapters(t *testing.T) {
+	adapter, _ := writeTestBlob(t, "mario.safetensors", "adapter weights")
+	_, otherBlob := writeTestBlob(t, "luigi.safetensors", "other adapter weights")
+
+	fromModelfile := testCustomModel("mario", "")
+	fromModelfile.From = types.StringNull()
+	fromModelfile.Modelfile = NewModelfileValue(fmt.Sprintf("FROM llama3:latest\nADAPTER %s\n", adapter))
+
+	tests := map[string]struct {
+		config *OllamaCustomModelResource
+		// wantAdapters are the adapters in state, nil when not tracked
+		wantAdapters []string
+	}{
+		"adapter in a modelfile": {
+			config: fromModelfile,
+		},
+		"configured adapter": {
+			config:       withAdapters(testCustomModel("mario", "llama3:latest"), adapter),
+			wantAdapters: []string{adapter},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			f, srv, client := newFakeOllama(t)
+			f.AddModel("llama3")
+			r := newTestCustomModelResource(t, srv, client)
+
+			var state OllamaCustomModelResource
+			if diags := applyResource(t, r, nil, tt.config, &state); diags.HasError() {
+				t.Fatal(diags)
+			}
+			var adapters []string
+			if !state.Adapters.IsNull() {
+				state.Adapters.ElementsAs(context.Background(), &adapters, false)
+			}
+			if !reflect.DeepEqual(adapters, tt.wantAdapters) {
+				t.Errorf("adapters = %v, want %v", adapters, tt.wantAdapters)
+			}
+
+			var refreshed OllamaCustomModelResource
+			if _, diags := readResource(t, r, &state, &refreshed); diags.HasError() {
+				t.Fatal(diags)
+			}
+			if got := plannedChanges(t, r, tt.config, &refreshed); got != nil {
+				t.Errorf("next plan changes %v, want none", got)
+			}
+
+			// the adapter was replaced outside of Terraform
+			f.Modelfiles["mario:latest"] = fmt.Sprintf("FROM llama3:latest\nADAPTER %s\n", otherBlob)
+			if _, diags := readResource(t, r, &refreshed, &refreshed); diags.HasError() {
+				t.Fatal(diags)
+			}
+			want := []string{"modelfile"}
+			if tt.wantAdapters != nil {
+				want = []string{"adapters"}
+			}
+			if got := plannedChanges(t, r, tt.config, &refreshed); !reflect.DeepEqual(got, want) {
+				t.Errorf("plan after the adapter changed changes %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,333 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/ollama/ollama/api"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var (
+	_ datasource.DataSource              = &OllamaDeterminismCheckDataSource{}
+	_ datasource.DataSourceWithConfigure = &OllamaDeterminismCheckDataSource{}
+)
+
+const defaultDeterminismSeed = 42
+
+func NewOllamaDeterminismCheckDataSource() datasource.DataSource {
+	return &OllamaDeterminismCheckDataSource{}
+}
+
+// OllamaDeterminismCheckDataSource runs the same prompts on several hosts and
+// reports which hosts deviate from the majority output.
+type OllamaDeterminismCheckDataSource struct {
+	budget     *inferenceBudget
+	advisories *advisoryDatabase
+}
+
+// OllamaDeterminismCheckDataSourceModel describes the data source data model.
+type OllamaDeterminismCheckDataSourceModel struct {
+	Hosts          []types.String                `tfsdk:"hosts"`
+	Model          types.String                  `tfsdk:"model"`
+	Prompts        []types.String                `tfsdk:"prompts"`
+	Seed           types.Int64                   `tfsdk:"seed"`
+	KeepAlive      KeepAliveValue                `tfsdk:"keep_alive"`
+	Results        []OllamaDeterminismHostResult `tfsdk:"results"`
+	DeviatingHosts []types.String                `tfsdk:"deviating_hosts"`
+	Consistent     types.Bool                    `tfsdk:"consistent"`
+}
+
+func (d *OllamaDeterminismCheckDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*OllamaProviderData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *OllamaProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.budget = data.Budget
+	d.advisories = data.Advisories
+}
+
+func (d *OllamaDeterminismCheckDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_determinism_check"
+}
+
+func (d *OllamaDeterminismCheckDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Runs a set of prompts with a fixed seed and temperature 0 on every host in a list and reports which hosts deviate from the majority output. On a tie, the output of the host listed first is the majority.",
+
+		Attributes: map[string]schema.Attribute{
+			"hosts": schema.ListAttribute{
+				Description: "The Ollama hosts to compare. The provider host is not included unless listed.",
+				Required:    true,
+				ElementType: types.StringType,
+				Validators: []validator.List{
+					listvalidator.SizeAtLeast(1),
+				},
+			},
+			"model": schema.StringAttribute{
+				Description: "The model to run the prompts with.",
+				Required:    true,
+			},
+			"prompts": schema.ListAttribute{
+				Description: "The prompts to run on every host.",
+				Required:    true,
+				ElementType: types.StringType,
+				Validators: []validator.List{
+					listvalidator.SizeAtLeast(1),
+				},
+			},
+			"seed": schema.Int64Attribute{
+				Description: "The seed used for every prompt. Defaults to 42.",
+				Optional:    true,
+			},
+			"keep_alive": schema.StringAttribute{
+				Description: "How long the model stays loaded on each host after the check, as a duration such as `10m` or a number of seconds. A negative value keeps it loaded. Defaults to the server setting.",
+				Optional:    true,
+				CustomType:  KeepAliveType{},
+			},
+			"results": schema.ListNestedAttribute{
+				Description: "The outcome per host, in the order of `hosts`.",
+				Computed:    true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"host": schema.StringAttribute{
+							Description: "The host.",
+							Computed:    true,
+						},
+						"server_version": schema.StringAttribute{
+							Description: "The Ollama version the host runs. Null if it could not be read.",
+							Computed:    true,
+						},
+						"digest": schema.StringAttribute{
+							Description: "The digest of the model on the host. Null if it could not be read.",
+							Computed:    true,
+							CustomType:  DigestType{},
+						},
+						"output_hashes": schema.ListAttribute{
+							Description: "SHA-256 of the output for each prompt.",
+							Computed:    true,
+							ElementType: types.StringType,
+						},
+						"deviates": schema.BoolAttribute{
+							Description: "Whether any output differs from the majority of hosts.",
+							Computed:    true,
+						},
+						"deviating_prompts": schema.ListAttribute{
+							Description: "Indexes of the prompts whose output differs from the majority.",
+							Computed:    true,
+							ElementType: types.Int64Type,
+						},
+						"error": schema.StringAttribute{
+							Description: "Why the host could not be checked. Such hosts are left out of the majority.",
+							Computed:    true,
+						},
+					},
+				},
+			},
+			"deviating_hosts": schema.ListAttribute{
+				Description: "Hosts whose output differs from the majority.",
+				Computed:    true,
+				ElementType: types.StringType,
+			},
+			"consistent": schema.BoolAttribute{
+				Description: "Whether all reachable hosts produced identical outputs. False if no host could be checked.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// hostRun holds what was collected from a single host.
+type hostRun struct {
+	version string
+	digest  string
+	outputs []string
+	err     error
+}
+
+func (d *OllamaDeterminismCheckDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data OllamaDeterminismCheckDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	seed := int64(defaultDeterminismSeed)
+	if !data.Seed.IsNull() {
+		seed = data.Seed.ValueInt64()
+	}
+
+	prompts := make([]string, len(data.Prompts))
+	for i, p := range data.Prompts {
+		prompts[i] = p.ValueString()
+	}
+
+	runs := make([]hostRun, len(data.Hosts))
+	var wg sync.WaitGroup
+	for i, h := range data.Hosts {
+		wg.Add(1)
+		go func(i int, host string) {
+			defer wg.Done()
+			runs[i] = runOnHost(ctx, host, d.budget, data.Model.ValueString(), prompts, seed, data.KeepAlive.Duration())
+		}(i, h.ValueString())
+	}
+	wg.Wait()
+	d.budget.LogUsage(ctx)
+
+	majority := majorityOutputs(runs, len(prompts))
+
+	data.Results = nil
+	data.DeviatingHosts = []types.String{}
+	failed := 0
+	for i, run := range runs {
+		host := data.Hosts[i].ValueString()
+		result := OllamaDeterminismHostResult{
+			Host:          types.StringValue(host),
+			ServerVersion: types.StringNull(),
+			Digest:        NewDigestNull(),
+			Deviates:      types.BoolValue(false),
+			Error:         types.StringNull(),
+		}
+
+		// the versions are read by the runs, so advisories are reported after them
+		if run.version != "" {
+			result.ServerVersion = types.StringValue(run.version)
+			d.advisories.CheckVersion(ctx, host, run.version, &resp.Diagnostics)
+		}
+		if run.digest != "" {
+			result.Digest = NewDigestValue(run.digest)
+		}
+
+		hashes := []string{}
+		deviating := []int64{}
+		if run.err != nil {
+			failed++
+			result.Error = types.StringValue(run.err.Error())
+			tflog.Warn(ctx, fmt.Sprintf("determinism check skipped %s: %s", host, run.err))
+		} else {
+			for p, output := range run.outputs {
+				sum := sha256.Sum256([]byte(output))
+				hashes = append(hashes, hex.EncodeToString(sum[:]))
+				if output != majority[p] {
+					deviating = append(deviating, int64(p))
+				}
+			}
+		}
+
+		if len(deviating) > 0 {
+			result.Deviates = types.BoolValue(true)
+			data.DeviatingHosts = append(data.DeviatingHosts, types.StringValue(host))
+		}
+
+		var diags diag.Diagnostics
+		result.OutputHashes, diags = types.ListValueFrom(ctx, types.StringType, hashes)
+		resp.Diagnostics.Append(diags...)
+		result.DeviatingPrompts, diags = types.ListValueFrom(ctx, types.Int64Type, deviating)
+		resp.Diagnostics.Append(diags...)
+
+		data.Results = append(data.Results, result)
+	}
+
+	// with every host down there is nothing to compare, which must not pass
+	// a check for consistency
+	if failed == len(runs) {
+		resp.Diagnostics.AddWarning(
+			"No host could be checked",
+			fmt.Sprintf("None of the %d hosts could run the prompts, see the error of each result.", len(runs)),
+		)
+	}
+	data.Consistent = types.BoolValue(failed < len(runs) && len(data.DeviatingHosts) == 0)
+
+	diags := resp.State.Set(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+}
+
+// majorityOutputs returns the most common output per prompt among the runs
+// that succeeded. Ties go to the output of the host listed first, so the
+// result does not depend on the order hosts answered in.
+func majorityOutputs(runs []hostRun, prompts int) []string {
+	majority := make([]string, prompts)
+	for p := range majority {
+		counts := map[string]int{}
+		for _, run := range runs {
+			if run.err == nil {
+				counts[run.outputs[p]]++
+			}
+		}
+		best := 0
+		for _, run := range runs {
+			if run.err == nil && counts[run.outputs[p]] > best {
+				majority[p], best = run.outputs[p], counts[run.outputs[p]]
+			}
+		}
+	}
+	return majority
+}
+
+// runOnHost runs all prompts on host, deterministic as far as the server allows.
+func runOnHost(ctx context.Context, host string, budget *inferenceBudget, model string, prompts []string, seed int64, keepAlive *api.Duration) hostRun {
+	var run hostRun
+
+	client, _, err := NewClient(host)
+	if err != nil {
+		run.err = err
+		return run
+	}
+
+	if run.version, err = client.Version(ctx); err != nil {
+		run.err = fmt.Errorf("could not get server version: %w", err)
+		return run
+	}
+
+	listed, err := lookupModel(ctx, client, model)
+	if err != nil {
+		run.err = err
+		return run
+	}
+	if listed == nil {
+		run.err = fmt.Errorf("model %s not found", model)
+		return run
+	}
+	run.digest = listed.Digest
+
+	for _, prompt := range prompts {
+		rsp, err := generate(ctx, client, budget, &api.GenerateRequest{
+			Model:     model,
+			Prompt:    prompt,
+			Options:   map[string]any{"seed": seed, "temperature": 0},
+			KeepAlive: keepAlive,
+		})
+		if err != nil {
+			run.err = fmt.Errorf("could not generate: %w", err)
+			return run
+		}
+		run.outputs = append(run.outputs, rsp.Response)
+	}
+
+	return run
+}
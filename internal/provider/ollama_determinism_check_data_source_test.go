@@ -0,0 +1,107 @@
+package provider
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestMajorityOutputs(t *testing.T) {
+	failed := hostRun{err: errors.New("connection refused")}
+
+	tests := map[string]struct {
+		runs []hostRun
+		want []string
+	}{
+		"unanimous": {
+			runs: []hostRun{{outputs: []string{"a", "b"}}, {outputs: []string{"a", "b"}}},
+			want: []string{"a", "b"},
+		},
+		"one host deviates": {
+			runs: []hostRun{{outputs: []string{"x"}}, {outputs: []string{"a"}}, {outputs: []string{"a"}}},
+			want: []string{"a"},
+		},
+		"tie goes to the first host": {
+			runs: []hostRun{{outputs: []string{"z"}}, {outputs: []string{"a"}}},
+			want: []string{"z"},
+		},
+		"tie of pairs goes to the first host": {
+			runs: []hostRun{{outputs: []string{"z"}}, {outputs: []string{"a"}}, {outputs: []string{"a"}}, {outputs: []string{"z"}}},
+			want: []string{"z"},
+		},
+		"failed hosts are left out": {
+			runs: []hostRun{failed, {outputs: []string{"b"}}, failed},
+			want: []string{"b"},
+		},
+		"all hosts failed": {
+			runs: []hostRun{failed, failed},
+			want: []string{""},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := majorityOutputs(tt.runs, len(tt.want)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("majorityOutputs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOllamaDeterminismCheckDataSourceRead(t *testing.T) {
+	up, srvUp, _ := newFakeOllama(t)
+	up.AddModel("llama3")
+	_, srvDown, _ := newFakeOllama(t)
+	srvDown.Close()
+
+	config := func(hosts ...string) *OllamaDeterminismCheckDataSourceModel {
+		c := &OllamaDeterminismCheckDataSourceModel{
+			Model:     types.StringValue("llama3"),
+			Prompts:   []types.String{types.StringValue("Count to 3.")},
+			Seed:      types.Int64Null(),
+			KeepAlive: KeepAliveValue{StringValue: types.StringNull()},
+		}
+		for _, h := range hosts {
+			c.Hosts = append(c.Hosts, types.StringValue(h))
+		}
+		return c
+	}
+
+	tests := map[string]struct {
+		hosts          []string
+		wantConsistent bool
+	}{
+		"unreachable host is left out": {
+			hosts:          []string{srvUp.URL, srvDown.URL},
+			wantConsistent: true,
+		},
+		"no reachable host": {
+			hosts:          []string{srvDown.URL, srvDown.URL},
+			wantConsistent: false,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var got OllamaDeterminismCheckDataSourceModel
+			if diags := readDataSource(t, &OllamaDeterminismCheckDataSource{}, config(tt.hosts...), &got); diags.HasError() {
+				t.Fatal(diags)
+			}
+
+			if got.Consistent.ValueBool() != tt.wantConsistent {
+				t.Errorf("consistent = %v, want %v", got.Consistent.ValueBool(), tt.wantConsistent)
+			}
+			for _, r := range got.Results {
+				if r.Host.ValueString() != srvDown.URL {
+					continue
+				}
+				if !r.ServerVersion.IsNull() || !r.Digest.IsNull() || r.Error.IsNull() {
+					t.Errorf("result of the unreachable host = version %s, digest %s, error %s, want null, null and an error",
+						r.ServerVersion, r.Digest, r.Error)
+				}
+			}
+		})
+	}
+}
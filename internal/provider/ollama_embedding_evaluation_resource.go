@@ -0,0 +1,453 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/float64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64default"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/ollama/ollama/api"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ resource.Resource                   = &ollamaEmbeddingEvaluationResource{}
+	_ resource.ResourceWithConfigure      = &ollamaEmbeddingEvaluationResource{}
+	_ resource.ResourceWithModifyPlan     = &ollamaEmbeddingEvaluationResource{}
+	_ resource.ResourceWithValidateConfig = &ollamaEmbeddingEvaluationResource{}
+)
+
+const defaultRetrievalK = 10
+
+// NewOllamaEmbeddingEvaluationResource is a helper function to simplify the provider implementation.
+func NewOllamaEmbeddingEvaluationResource() resource.Resource {
+	return &ollamaEmbeddingEvaluationResource{}
+}
+
+// ollamaEmbeddingEvaluationResource measures retrieval quality of an embedding model.
+type ollamaEmbeddingEvaluationResource struct {
+	client     *api.Client
+	cache      *responseCache
+	budget     *inferenceBudget
+	host       string
+	eol        *eolPolicy
+	advisories *advisoryDatabase
+}
+
+func (r *ollamaEmbeddingEvaluationResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*OllamaProviderData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *OllamaProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.client = data.Client
+	r.cache = data.Cache
+	r.budget = data.Budget
+	r.host = data.Host
+	r.eol = data.EOL
+	r.advisories = data.Advisories
+}
+
+// Metadata returns the resource type name.
+func (r *ollamaEmbeddingEvaluationResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_embedding_evaluation"
+}
+
+func (r *ollamaEmbeddingEvaluationResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Evaluates the retrieval quality of an embedding model on a labeled dataset and reports recall@k, MRR and nDCG@k. " +
+			"The apply fails when a metric falls below its threshold or regresses against a baseline model.",
+
+		Attributes: map[string]schema.Attribute{
+			"model": schema.StringAttribute{
+				Description: "The embedding model to evaluate.",
+				Required:    true,
+			},
+			"baseline_model": schema.StringAttribute{
+				Description: "An embedding model to compare against, such as the one currently in use.",
+				Optional:    true,
+			},
+			"dataset_file": schema.StringAttribute{
+				Description: "Path of a JSON file with a `documents` object mapping document IDs to text and a `queries` list of objects with a `query` and the `relevant` document IDs.",
+				Required:    true,
+			},
+			"k": schema.Int64Attribute{
+				Description: "Cut-off rank for recall@k and nDCG@k. Defaults to 10.",
+				Optional:    true,
+				Computed:    true,
+				Default:     int64default.StaticInt64(defaultRetrievalK),
+				Validators: []validator.Int64{
+					int64validator.AtLeast(1),
+				},
+			},
+			"hosts": schema.ListAttribute{
+				Description: "Ollama hosts to spread the embedding requests across. Each host must serve the models with the same digests. " +
+					"A request that fails on one host is retried on the others. Must not be empty, leave it unset to use the provider host.",
+				Optional:    true,
+				ElementType: types.StringType,
+			},
+			"concurrency": schema.Int64Attribute{
+				Description: "Number of embedding requests run at the same time on each host. Defaults to 4.",
+				Optional:    true,
+				Computed:    true,
+				Default:     int64default.StaticInt64(defaultEmbeddingConcurrency),
+				Validators: []validator.Int64{
+					int64validator.AtLeast(1),
+				},
+			},
+			"keep_alive": schema.StringAttribute{
+				Description: "How long the models stay loaded after the evaluation, as a duration such as `10m` or a number of seconds. A negative value keeps them loaded. Defaults to the server setting.",
+				Optional:    true,
+				CustomType:  KeepAliveType{},
+			},
+			"min_recall_at_k": schema.Float64Attribute{
+				Description: "Fail when recall@k is below this value.",
+				Optional:    true,
+			},
+			"min_mrr": schema.Float64Attribute{
+				Description: "Fail when MRR is below this value.",
+				Optional:    true,
+			},
+			"min_ndcg": schema.Float64Attribute{
+				Description: "Fail when nDCG@k is below this value.",
+				Optional:    true,
+			},
+			"max_regression": schema.Float64Attribute{
+				Description: "How far a metric may fall below the baseline model's before the apply fails. Defaults to 0. Requires `baseline_model`.",
+				Optional:    true,
+				Validators: []validator.Float64{
+					float64validator.AtLeast(0),
+				},
+			},
+			"dataset_sha256": schema.StringAttribute{
+				Description: "SHA-256 of the dataset file. A change triggers a new evaluation.",
+				Computed:    true,
+			},
+			"model_digest": schema.StringAttribute{
+				Description: "Digest of the evaluated model. A change triggers a new evaluation.",
+				Computed:    true,
+				CustomType:  DigestType{},
+			},
+			"baseline_model_digest": schema.StringAttribute{
+				Description: "Digest of the baseline model. A change triggers a new evaluation.",
+				Computed:    true,
+				CustomType:  DigestType{},
+			},
+			"recall_at_k": schema.Float64Attribute{
+				Description: "Mean fraction of relevant documents ranked in the top k.",
+				Computed:    true,
+			},
+			"mrr": schema.Float64Attribute{
+				Description: "Mean reciprocal rank of the first relevant document.",
+				Computed:    true,
+			},
+			"ndcg": schema.Float64Attribute{
+				Description: "Mean normalized discounted cumulative gain at k.",
+				Computed:    true,
+			},
+			"baseline_recall_at_k": schema.Float64Attribute{
+				Description: "recall@k of the baseline model.",
+				Computed:    true,
+			},
+			"baseline_mrr": schema.Float64Attribute{
+				Description: "MRR of the baseline model.",
+				Computed:    true,
+			},
+			"baseline_ndcg": schema.Float64Attribute{
+				Description: "nDCG@k of the baseline model.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+func (r *ollamaEmbeddingEvaluationResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var config OllamaEmbeddingEvaluationResource
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !config.MaxRegression.IsNull() && config.BaselineModel.IsNull() {
+		resp.Diagnostics.AddAttributeError(path.Root("max_regression"), "Missing baseline model", "max_regression requires baseline_model to be set.")
+	}
+}
+
+// ModifyPlan hashes the dataset and looks up the model digests, so the
+// evaluation re-runs when either changes and keeps its metrics otherwise.
+func (r *ollamaEmbeddingEvaluationResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() || r.client == nil {
+		return
+	}
+
+	if r.advisories.Check(ctx, &resp.Diagnostics); resp.Diagnostics.HasError() {
+		return
+	}
+
+	var plan OllamaEmbeddingEvaluationResource
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	r.eol.Check(path.Root("model"), plan.Model, &resp.Diagnostics)
+	r.eol.Check(path.Root("baseline_model"), plan.BaselineModel, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	plan.DatasetSHA256 = types.StringUnknown()
+	if !plan.DatasetFile.IsUnknown() {
+		sum, err := fileSHA256(plan.DatasetFile.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("dataset_file"), "Error reading dataset file", err.Error())
+			return
+		}
+		plan.DatasetSHA256 = types.StringValue(sum)
+	}
+
+	// Check covers the provider host only, the hosts of a pool are checked here
+	var pool *hostPool
+	if !plan.Hosts.IsUnknown() {
+		var d diag.Diagnostics
+		pool, d = newHostPool(ctx, plan.Hosts, r.client, r.host, plan.Concurrency)
+		if resp.Diagnostics.Append(d...); resp.Diagnostics.HasError() {
+			return
+		}
+		if pool.CheckAdvisories(ctx, r.advisories, &resp.Diagnostics); resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	if !req.State.Raw.IsNull() {
+		var state OllamaEmbeddingEvaluationResource
+		resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+
+		plan.ModelDigest, plan.BaselineModelDigest = NewDigestUnknown(), NewDigestNull()
+		if !plan.BaselineModel.IsNull() {
+			plan.BaselineModelDigest = NewDigestUnknown()
+		}
+
+		if pool != nil {
+			var err error
+			if plan.ModelDigest, err = plannedModelDigest(ctx, pool.Primary(), plan.Model, state.ModelDigest); err != nil {
+				resp.Diagnostics.AddError("Error reading model", err.Error())
+				return
+			}
+
+			if !plan.BaselineModel.IsNull() {
+				if plan.BaselineModelDigest, err = plannedModelDigest(ctx, pool.Primary(), plan.BaselineModel, state.BaselineModelDigest); err != nil {
+					resp.Diagnostics.AddError("Error reading model", err.Error())
+					return
+				}
+			}
+		}
+
+		if sameRetrievalInputs(&plan, &state) {
+			plan.RecallAtK, plan.MRR, plan.NDCG = state.RecallAtK, state.MRR, state.NDCG
+			plan.BaselineRecallAtK, plan.BaselineMRR, plan.BaselineNDCG = state.BaselineRecallAtK, state.BaselineMRR, state.BaselineNDCG
+		} else {
+			plan.RecallAtK, plan.MRR, plan.NDCG = types.Float64Unknown(), types.Float64Unknown(), types.Float64Unknown()
+			plan.BaselineRecallAtK, plan.BaselineMRR, plan.BaselineNDCG = types.Float64Unknown(), types.Float64Unknown(), types.Float64Unknown()
+		}
+	}
+
+	resp.Diagnostics.Append(resp.Plan.Set(ctx, plan)...)
+}
+
+// sameRetrievalInputs reports whether the metrics in state still apply to plan.
+func sameRetrievalInputs(plan, state *OllamaEmbeddingEvaluationResource) bool {
+	return plan.DatasetSHA256.Equal(state.DatasetSHA256) &&
+		plan.Model.Equal(state.Model) && plan.ModelDigest.Equal(state.ModelDigest) &&
+		plan.BaselineModel.Equal(state.BaselineModel) && plan.BaselineModelDigest.Equal(state.BaselineModelDigest) &&
+		plan.K.Equal(state.K)
+}
+
+// Create creates the resource and sets the initial Terraform state.
+func (r *ollamaEmbeddingEvaluationResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan OllamaEmbeddingEvaluationResource
+	diags := req.Plan.Get(ctx, &plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(r.evaluate(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(checkRetrievalThresholds(&plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	diags = resp.State.Set(ctx, plan)
+	resp.Diagnostics.Append(diags...)
+}
+
+// Read keeps the recorded metrics, they only change when the inputs do.
+func (r *ollamaEmbeddingEvaluationResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+}
+
+// Update re-evaluates when the inputs changed and re-checks the thresholds.
+func (r *ollamaEmbeddingEvaluationResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan OllamaEmbeddingEvaluationResource
+	diags := req.Plan.Get(ctx, &plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if plan.RecallAtK.IsUnknown() || plan.ModelDigest.IsUnknown() || plan.BaselineModelDigest.IsUnknown() {
+		resp.Diagnostics.Append(r.evaluate(ctx, &plan)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	resp.Diagnostics.Append(checkRetrievalThresholds(&plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	diags = resp.State.Set(ctx, plan)
+	resp.Diagnostics.Append(diags...)
+}
+
+// Delete only removes the resource from state.
+func (r *ollamaEmbeddingEvaluationResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+}
+
+func (r *ollamaEmbeddingEvaluationResource) evaluate(ctx context.Context, plan *OllamaEmbeddingEvaluationResource) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	sum, err := fileSHA256(plan.DatasetFile.ValueString())
+	if err != nil {
+		diags.AddAttributeError(path.Root("dataset_file"), "Error reading dataset file", err.Error())
+		return diags
+	}
+
+	ds, err := readRetrievalDataset(plan.DatasetFile.ValueString())
+	if err != nil {
+		diags.AddAttributeError(path.Root("dataset_file"), "Error reading dataset file", err.Error())
+		return diags
+	}
+
+	pool, d := newHostPool(ctx, plan.Hosts, r.client, r.host, plan.Concurrency)
+	if diags.Append(d...); diags.HasError() {
+		return diags
+	}
+
+	metrics, digest, d := r.evaluateModel(ctx, pool, plan.Model, plan, ds)
+	if diags.Append(d...); diags.HasError() {
+		return diags
+	}
+
+	plan.DatasetSHA256 = types.StringValue(sum)
+	plan.ModelDigest = NewDigestValue(digest)
+	plan.RecallAtK = types.Float64Value(metrics.RecallAtK)
+	plan.MRR = types.Float64Value(metrics.MRR)
+	plan.NDCG = types.Float64Value(metrics.NDCG)
+
+	plan.BaselineModelDigest = NewDigestNull()
+	plan.BaselineRecallAtK, plan.BaselineMRR, plan.BaselineNDCG = types.Float64Null(), types.Float64Null(), types.Float64Null()
+	if !plan.BaselineModel.IsNull() {
+		baseline, digest, d := r.evaluateModel(ctx, pool, plan.BaselineModel, plan, ds)
+		if diags.Append(d...); diags.HasError() {
+			return diags
+		}
+
+		plan.BaselineModelDigest = NewDigestValue(digest)
+		plan.BaselineRecallAtK = types.Float64Value(baseline.RecallAtK)
+		plan.BaselineMRR = types.Float64Value(baseline.MRR)
+		plan.BaselineNDCG = types.Float64Value(baseline.NDCG)
+	}
+
+	return diags
+}
+
+func (r *ollamaEmbeddingEvaluationResource) evaluateModel(ctx context.Context, pool *hostPool, name types.String, plan *OllamaEmbeddingEvaluationResource, ds *retrievalDataset) (retrievalMetrics, string, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	digest, err := pool.ModelDigest(ctx, name.ValueString())
+	if err != nil {
+		diags.AddError("Error reading model", err.Error())
+		return retrievalMetrics{}, "", diags
+	}
+
+	e := &embedder{
+		pool:      pool,
+		cache:     r.cache,
+		budget:    r.budget,
+		model:     name.ValueString(),
+		digest:    digest,
+		keepAlive: plan.KeepAlive.Duration(),
+	}
+
+	metrics, err := evaluateRetrieval(ctx, e, ds, int(plan.K.ValueInt64()))
+	r.budget.LogUsage(ctx)
+	if err != nil {
+		diags.AddError("Error evaluating embedding model", err.Error())
+		return retrievalMetrics{}, "", diags
+	}
+
+	return metrics, digest, diags
+}
+
+// checkRetrievalThresholds fails when a metric is below its minimum or has
+// regressed against the baseline by more than max_regression.
+func checkRetrievalThresholds(plan *OllamaEmbeddingEvaluationResource) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	checks := []struct {
+		name     string
+		value    types.Float64
+		min      types.Float64
+		baseline types.Float64
+	}{
+		{"recall@k", plan.RecallAtK, plan.MinRecallAtK, plan.BaselineRecallAtK},
+		{"MRR", plan.MRR, plan.MinMRR, plan.BaselineMRR},
+		{"nDCG@k", plan.NDCG, plan.MinNDCG, plan.BaselineNDCG},
+	}
+
+	for _, c := range checks {
+		value := c.value.ValueFloat64()
+
+		if !c.min.IsNull() && value < c.min.ValueFloat64() {
+			diags.AddError(
+				"Retrieval quality below threshold",
+				fmt.Sprintf("%s of %s is %.4f, below the minimum of %.4f.", c.name, plan.Model.ValueString(), value, c.min.ValueFloat64()),
+			)
+		}
+
+		if !c.baseline.IsNull() && value < c.baseline.ValueFloat64()-plan.MaxRegression.ValueFloat64() {
+			diags.AddError(
+				"Retrieval quality regressed",
+				fmt.Sprintf("%s of %s is %.4f, the baseline %s reaches %.4f.", c.name, plan.Model.ValueString(), value, plan.BaselineModel.ValueString(), c.baseline.ValueFloat64()),
+			)
+		}
+	}
+
+	return diags
+}
@@ -0,0 +1,69 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestOllamaEmbeddingEvaluationResourceValidateConfig(t *testing.T) {
+	r := &ollamaEmbeddingEvaluationResource{}
+	s := resourceSchema(t, r).Schema
+
+	config := func(baseline *string, maxRegression *float64) *OllamaEmbeddingEvaluationResource {
+		return &OllamaEmbeddingEvaluationResource{
+			Model:               types.StringValue("nomic-embed-text"),
+			BaselineModel:       types.StringPointerValue(baseline),
+			DatasetFile:         types.StringValue("retrieval.jsonl"),
+			K:                   types.Int64Null(),
+			Hosts:               types.ListNull(types.StringType),
+			Concurrency:         types.Int64Null(),
+			KeepAlive:           KeepAliveValue{StringValue: types.StringNull()},
+			MinRecallAtK:        types.Float64Null(),
+			MinMRR:              types.Float64Null(),
+			MinNDCG:             types.Float64Null(),
+			MaxRegression:       types.Float64PointerValue(maxRegression),
+			DatasetSHA256:       types.StringNull(),
+			ModelDigest:         NewDigestNull(),
+			BaselineModelDigest: NewDigestNull(),
+			RecallAtK:           types.Float64Null(),
+			MRR:                 types.Float64Null(),
+			NDCG:                types.Float64Null(),
+			BaselineRecallAtK:   types.Float64Null(),
+			BaselineMRR:         types.Float64Null(),
+			BaselineNDCG:        types.Float64Null(),
+		}
+	}
+	str := func(s string) *string { return &s }
+	float := func(f float64) *float64 { return &f }
+
+	tests := map[string]struct {
+		config  *OllamaEmbeddingEvaluationResource
+		wantErr bool
+	}{
+		"no baseline":                     {config: config(nil, nil)},
+		"baseline":                        {config: config(str("all-minilm"), nil)},
+		"max regression with baseline":    {config: config(str("all-minilm"), float(0.05))},
+		"max regression without baseline": {config: config(nil, float(0.05)), wantErr: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			raw := tfsdk.State{Schema: s}
+			if diags := raw.Set(ctx, tt.config); diags.HasError() {
+				t.Fatal(diags)
+			}
+
+			var resp resource.ValidateConfigResponse
+			r.ValidateConfig(ctx, resource.ValidateConfigRequest{Config: tfsdk.Config{Schema: s, Raw: raw.Raw}}, &resp)
+			if got := resp.Diagnostics.HasError(); got != tt.wantErr {
+				t.Errorf("ValidateConfig() error = %v, want %v: %v", got, tt.wantErr, resp.Diagnostics)
+			}
+		})
+	}
+}
@@ -0,0 +1,320 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/boolplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ resource.Resource               = &ollamaGGUFExportResource{}
+	_ resource.ResourceWithConfigure  = &ollamaGGUFExportResource{}
+	_ resource.ResourceWithModifyPlan = &ollamaGGUFExportResource{}
+)
+
+// NewOllamaGGUFExportResource is a helper function to simplify the provider implementation.
+func NewOllamaGGUFExportResource() resource.Resource {
+	return &ollamaGGUFExportResource{}
+}
+
+// ollamaGGUFExportResource writes the weights of a model in the local models
+// store out as a standalone GGUF file. It works on the file system only and
+// does not talk to the server, but takes the host lock so the model is not
+// replaced or deleted by another run while it is copied.
+type ollamaGGUFExportResource struct {
+	eol  *eolPolicy
+	lock *hostLock
+}
+
+func (r *ollamaGGUFExportResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*OllamaProviderData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *OllamaProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.eol = data.EOL
+	r.lock = data.Lock
+}
+
+// Metadata returns the resource type name.
+func (r *ollamaGGUFExportResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_gguf_export"
+}
+
+func (r *ollamaGGUFExportResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Writes the weights of a model in a local Ollama models store to a standalone `.gguf` file, " +
+			"with the template and parameters of the model as sidecar files, for use with other runtimes. " +
+			"The export is recreated when the weights of the model change.",
+
+		Attributes: map[string]schema.Attribute{
+			"model": schema.StringAttribute{
+				Description: "The model to export, e.g. `llama3` or `user/model:tag`.",
+				Required:    true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"models_dir": schema.StringAttribute{
+				Description: "The models directory of the Ollama installation. Defaults to `OLLAMA_MODELS`, or `~/.ollama/models` if that is not set.",
+				Optional:    true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"output_file": schema.StringAttribute{
+				Description: "Path of the `.gguf` file. The sidecars are written next to it with the `.gguf` suffix replaced by `.template` and `.params.json`.",
+				Required:    true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"hardlink": schema.BoolAttribute{
+				Description: "Hardlink the weights instead of copying them. Falls back to a copy when the output is on another file system. Defaults to false. " +
+					"A hardlinked export shares its inode with the blob in the models store: writing to the export changes the weights of the model and corrupts the store.",
+				Optional: true,
+				Computed: true,
+				Default:  booldefault.StaticBool(false),
+				PlanModifiers: []planmodifier.Bool{
+					boolplanmodifier.RequiresReplace(),
+				},
+			},
+			"digest": schema.StringAttribute{
+				Description: "Digest of the exported weights.",
+				Computed:    true,
+				CustomType:  DigestType{},
+			},
+			"size": schema.Int64Attribute{
+				Description: "Size of the exported weights in bytes.",
+				Computed:    true,
+			},
+			"template_file": schema.StringAttribute{
+				Description: "Path of the template sidecar, if the model has a template.",
+				Computed:    true,
+			},
+			"parameters_file": schema.StringAttribute{
+				Description: "Path of the parameters sidecar, a JSON object, if the model has parameters.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// ModifyPlan warns about models that are scheduled for retirement.
+func (r *ollamaGGUFExportResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() || r.eol == nil {
+		return
+	}
+
+	var model types.String
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("model"), &model)...)
+	r.eol.Check(path.Root("model"), model, &resp.Diagnostics)
+}
+
+// Create creates the resource and sets the initial Terraform state.
+func (r *ollamaGGUFExportResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	unlock, ok := lockHost(ctx, r.lock, &resp.Diagnostics)
+	if !ok {
+		return
+	}
+	defer unlock()
+
+	var plan OllamaGGUFExportResource
+	diags := req.Plan.Get(ctx, &plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(r.export(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	diags = resp.State.Set(ctx, plan)
+	resp.Diagnostics.Append(diags...)
+}
+
+// Read removes the resource when the output file is gone or the weights of the
+// model changed, so the export is written again.
+func (r *ollamaGGUFExportResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state OllamaGGUFExportResource
+	diags := req.State.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if _, err := os.Stat(state.OutputFile.ValueString()); errors.Is(err, fs.ErrNotExist) {
+		tflog.Debug(ctx, fmt.Sprintf("exported file %s was removed", state.OutputFile.ValueString()))
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	store, err := ggufExportStore(&state)
+	if err != nil {
+		resp.Diagnostics.AddError("Error Reading Models Directory", err.Error())
+		return
+	}
+
+	manifest, err := store.Manifest(state.Model.ValueString())
+	if errors.Is(err, fs.ErrNotExist) {
+		tflog.Debug(ctx, err.Error())
+		resp.State.RemoveResource(ctx)
+		return
+	} else if err != nil {
+		resp.Diagnostics.AddError("Error Reading Model Manifest", err.Error())
+		return
+	}
+
+	if weights := manifest.Layer(mediaTypeModel); weights == nil || !state.Digest.SameDigest(weights.Digest) {
+		tflog.Debug(ctx, fmt.Sprintf("weights of model %s changed since export", state.Model.ValueString()))
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	diags = resp.State.Set(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+}
+
+// Update writes the export again. All inputs require replacement, so this
+// only runs if the framework plans an in-place update of computed values.
+func (r *ollamaGGUFExportResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	unlock, ok := lockHost(ctx, r.lock, &resp.Diagnostics)
+	if !ok {
+		return
+	}
+	defer unlock()
+
+	var plan OllamaGGUFExportResource
+	diags := req.Plan.Get(ctx, &plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(r.export(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	diags = resp.State.Set(ctx, plan)
+	resp.Diagnostics.Append(diags...)
+}
+
+// Delete removes the exported file and its sidecars.
+func (r *ollamaGGUFExportResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state OllamaGGUFExportResource
+	diags := req.State.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	for _, p := range []types.String{state.OutputFile, state.TemplateFile, state.ParametersFile} {
+		if p.IsNull() {
+			continue
+		}
+		if err := os.Remove(p.ValueString()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			resp.Diagnostics.AddError("Error Deleting Export", fmt.Sprintf("Could not remove %s: %s", p.ValueString(), err))
+		}
+	}
+}
+
+func ggufExportStore(m *OllamaGGUFExportResource) (*modelStore, error) {
+	if !m.ModelsDir.IsNull() {
+		return &modelStore{dir: m.ModelsDir.ValueString()}, nil
+	}
+
+	dir, err := defaultModelsDir()
+	if err != nil {
+		return nil, fmt.Errorf("could not determine models directory: %w", err)
+	}
+	return &modelStore{dir: dir}, nil
+}
+
+// export writes the weights and sidecars of the model and fills in the
+// computed attributes of m.
+func (r *ollamaGGUFExportResource) export(ctx context.Context, m *OllamaGGUFExportResource) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	store, err := ggufExportStore(m)
+	if err != nil {
+		diags.AddError("Error Reading Models Directory", err.Error())
+		return diags
+	}
+
+	manifest, err := store.Manifest(m.Model.ValueString())
+	if err != nil {
+		diags.AddError("Error Reading Model Manifest", err.Error())
+		return diags
+	}
+
+	weights := manifest.Layer(mediaTypeModel)
+	if weights == nil {
+		diags.AddError("Error Exporting Model", fmt.Sprintf("Model %s has no model weights layer.", m.Model.ValueString()))
+		return diags
+	}
+
+	output := m.OutputFile.ValueString()
+	linked, err := linkOrCopy(store.BlobPath(weights.Digest), output, m.Hardlink.ValueBool())
+	if err != nil {
+		diags.AddError("Error Exporting Model", fmt.Sprintf("Could not write %s: %s", output, err))
+		return diags
+	}
+	if m.Hardlink.ValueBool() && !linked {
+		tflog.Warn(ctx, fmt.Sprintf("could not hardlink %s, copied the weights instead", output))
+	}
+
+	tflog.Info(ctx, fmt.Sprintf("exported %s (%s) to %s", m.Model.ValueString(), weights.Digest, output))
+
+	m.Digest = NewDigestValue(weights.Digest)
+	m.Size = types.Int64Value(weights.Size)
+
+	base := strings.TrimSuffix(output, ".gguf")
+	m.TemplateFile = exportSidecar(store, manifest.Layer(mediaTypeTemplate), base+".template", &diags)
+	m.ParametersFile = exportSidecar(store, manifest.Layer(mediaTypeParams), base+".params.json", &diags)
+
+	return diags
+}
+
+// exportSidecar copies layer to path and returns the path, or null if the
+// model has no such layer.
+func exportSidecar(store *modelStore, layer *manifestLayer, path string, diags *diag.Diagnostics) types.String {
+	if layer == nil {
+		os.Remove(path)
+		return types.StringNull()
+	}
+
+	if _, err := linkOrCopy(store.BlobPath(layer.Digest), path, false); err != nil {
+		diags.AddError("Error Exporting Model", fmt.Sprintf("Could not write %s: %s", path, err))
+		return types.StringNull()
+	}
+
+	return types.StringValue(path)
+}
@@ -0,0 +1,157 @@
+package provider
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// writeTestManifest stores a model with the given layer contents, by media
+// type, in the models directory dir.
+func writeTestManifest(t *testing.T, dir, name string, layers map[string]string) {
+	t.Helper()
+
+	s := &modelStore{dir: dir}
+	var m modelManifest
+	for mediaType, content := range layers {
+		digest := fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(content)))
+		if err := os.MkdirAll(filepath.Join(dir, "blobs"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(s.BlobPath(digest), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		m.Layers = append(m.Layers, manifestLayer{MediaType: mediaType, Digest: digest, Size: int64(len(content))})
+	}
+
+	p, err := s.manifestPath(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testGGUFExport(modelsDir, output string, hardlink bool) *OllamaGGUFExportResource {
+	return &OllamaGGUFExportResource{
+		Model:          types.StringValue("user/model:v1"),
+		ModelsDir:      types.StringValue(modelsDir),
+		OutputFile:     types.StringValue(output),
+		Hardlink:       types.BoolValue(hardlink),
+		Digest:         NewDigestUnknown(),
+		Size:           types.Int64Unknown(),
+		TemplateFile:   types.StringUnknown(),
+		ParametersFile: types.StringUnknown(),
+	}
+}
+
+func TestOllamaGGUFExportResource(t *testing.T) {
+	modelsDir := t.TempDir()
+	writeTestManifest(t, modelsDir, "user/model:v1", map[string]string{
+		mediaTypeModel:    "GGUF weights",
+		mediaTypeTemplate: "{{ .Prompt }}",
+		mediaTypeParams:   `{"temperature":0.2}`,
+	})
+
+	r := &ollamaGGUFExportResource{}
+	output := filepath.Join(t.TempDir(), "model.gguf")
+
+	var state OllamaGGUFExportResource
+	if diags := applyResource(t, r, nil, testGGUFExport(modelsDir, output, true), &state); diags.HasError() {
+		t.Fatal(diags)
+	}
+
+	for p, want := range map[string]string{
+		output: "GGUF weights",
+		filepath.Join(filepath.Dir(output), "model.template"):    "{{ .Prompt }}",
+		filepath.Join(filepath.Dir(output), "model.params.json"): `{"temperature":0.2}`,
+	} {
+		if b, err := os.ReadFile(p); err != nil || string(b) != want {
+			t.Errorf("%s = %q, %v, want %q", filepath.Base(p), b, err, want)
+		}
+	}
+	if want := fmt.Sprintf("sha256:%x", sha256.Sum256([]byte("GGUF weights"))); !state.Digest.SameDigest(want) {
+		t.Errorf("digest = %s, want %s", state.Digest.ValueString(), want)
+	}
+	blob, _ := os.Stat((&modelStore{dir: modelsDir}).BlobPath(state.Digest.ValueString()))
+	exported, _ := os.Stat(output)
+	if !os.SameFile(blob, exported) {
+		t.Error("hardlinked export does not share the inode of the blob")
+	}
+	if state.Size.ValueInt64() != int64(len("GGUF weights")) {
+		t.Errorf("size = %d, want %d", state.Size.ValueInt64(), len("GGUF weights"))
+	}
+
+	t.Run("unchanged export is kept", func(t *testing.T) {
+		var read OllamaGGUFExportResource
+		found, diags := readResource(t, r, &state, &read)
+		if diags.HasError() {
+			t.Fatal(diags)
+		}
+		if !found {
+			t.Error("Read dropped an export whose weights did not change")
+		}
+	})
+
+	t.Run("removed output is exported again", func(t *testing.T) {
+		removed := filepath.Join(t.TempDir(), "gone.gguf")
+		prior := state
+		prior.OutputFile = types.StringValue(removed)
+
+		var read OllamaGGUFExportResource
+		if found, diags := readResource(t, r, &prior, &read); diags.HasError() || found {
+			t.Errorf("Read of an export whose file is gone = found %v, %v, want removed", found, diags)
+		}
+	})
+
+	t.Run("changed weights are exported again", func(t *testing.T) {
+		changed := t.TempDir()
+		writeTestManifest(t, changed, "user/model:v1", map[string]string{mediaTypeModel: "new GGUF weights"})
+		prior := state
+		prior.ModelsDir = types.StringValue(changed)
+
+		var read OllamaGGUFExportResource
+		if found, diags := readResource(t, r, &prior, &read); diags.HasError() || found {
+			t.Errorf("Read of an export with changed weights = found %v, %v, want removed", found, diags)
+		}
+	})
+
+	t.Run("sidecars the model lacks are removed", func(t *testing.T) {
+		plain := t.TempDir()
+		writeTestManifest(t, plain, "user/model:v1", map[string]string{mediaTypeModel: "GGUF weights"})
+
+		var updated OllamaGGUFExportResource
+		if diags := applyResource(t, r, &state, testGGUFExport(plain, output, false), &updated); diags.HasError() {
+			t.Fatal(diags)
+		}
+		if !updated.TemplateFile.IsNull() || !updated.ParametersFile.IsNull() {
+			t.Errorf("sidecars = %s, %s, want null", updated.TemplateFile, updated.ParametersFile)
+		}
+		if _, err := os.Stat(state.TemplateFile.ValueString()); !os.IsNotExist(err) {
+			t.Errorf("template sidecar still exists: %v", err)
+		}
+	})
+
+	t.Run("delete removes the export and sidecars", func(t *testing.T) {
+		if diags := applyResource(t, r, &state, nil, &state); diags.HasError() {
+			t.Fatal(diags)
+		}
+		entries, _ := os.ReadDir(filepath.Dir(output))
+		if len(entries) != 0 {
+			t.Errorf("files left after delete: %v", entries)
+		}
+	})
+}
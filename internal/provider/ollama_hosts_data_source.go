@@ -0,0 +1,235 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var (
+	_ datasource.DataSource                   = &OllamaHostsDataSource{}
+	_ datasource.DataSourceWithConfigure      = &OllamaHostsDataSource{}
+	_ datasource.DataSourceWithValidateConfig = &OllamaHostsDataSource{}
+)
+
+const defaultSRVScheme = "http"
+
+func NewOllamaHostsDataSource() datasource.DataSource {
+	return &OllamaHostsDataSource{}
+}
+
+// OllamaHostsDataSource discovers Ollama endpoints from DNS SRV records or an
+// inventory file.
+type OllamaHostsDataSource struct {
+	advisories *advisoryDatabase
+}
+
+// OllamaHostsDataSourceModel describes the data source data model.
+type OllamaHostsDataSourceModel struct {
+	SRV              types.String         `tfsdk:"srv"`
+	Scheme           types.String         `tfsdk:"scheme"`
+	InventoryFile    types.String         `tfsdk:"inventory_file"`
+	Labels           types.Map            `tfsdk:"labels"`
+	HealthCheck      types.Bool           `tfsdk:"health_check"`
+	HeartbeatTimeout types.String         `tfsdk:"heartbeat_timeout"`
+	Hosts            []types.String       `tfsdk:"hosts"`
+	Endpoints        []OllamaHostEndpoint `tfsdk:"endpoints"`
+}
+
+func (d *OllamaHostsDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*OllamaProviderData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *OllamaProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.advisories = data.Advisories
+}
+
+func (d *OllamaHostsDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_hosts"
+}
+
+func (d *OllamaHostsDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Discovers Ollama endpoints from DNS SRV records or an inventory file, optionally keeping only hosts that answer a heartbeat. " +
+			"The resulting `hosts` can be passed to the `hosts` of resources that spread work across several hosts.",
+
+		Attributes: map[string]schema.Attribute{
+			"srv": schema.StringAttribute{
+				Description: "The SRV record to look up, such as `_ollama._tcp.inference.example.com`. Conflicts with `inventory_file`.",
+				Optional:    true,
+			},
+			"scheme": schema.StringAttribute{
+				Description: "The URL scheme of hosts found through `srv`. Defaults to `http`.",
+				Optional:    true,
+			},
+			"inventory_file": schema.StringAttribute{
+				Description: "An inventory file with a `hosts` list of objects with a `url` and optional `labels`. " +
+					"Files ending in `.yaml` or `.yml` are read as YAML, all others as JSON. A host listed more than once is returned once, with the labels of its first entry. Conflicts with `srv`.",
+				Optional: true,
+			},
+			"labels": schema.MapAttribute{
+				Description: "Only return inventory hosts that have all of these labels. Conflicts with `srv`, whose hosts have no labels.",
+				Optional:    true,
+				ElementType: types.StringType,
+			},
+			"health_check": schema.BoolAttribute{
+				Description: "Ask every host for its version and only return the hosts that answer within `heartbeat_timeout`.",
+				Optional:    true,
+			},
+			"heartbeat_timeout": schema.StringAttribute{
+				Description: "How long to wait for a host to answer the heartbeat, such as `500ms`. Defaults to `2s`.",
+				Optional:    true,
+			},
+			"hosts": schema.ListAttribute{
+				Description: "The URLs of the discovered hosts, ordered by SRV priority or as listed in the inventory.",
+				Computed:    true,
+				ElementType: types.StringType,
+			},
+			"endpoints": schema.ListNestedAttribute{
+				Description: "The discovered hosts with their labels, in the order of `hosts`.",
+				Computed:    true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"url": schema.StringAttribute{
+							Description: "The URL of the host.",
+							Computed:    true,
+						},
+						"labels": schema.MapAttribute{
+							Description: "The labels of the host from the inventory.",
+							Computed:    true,
+							ElementType: types.StringType,
+						},
+						"version": schema.StringAttribute{
+							Description: "The Ollama version the host runs, if `health_check` is enabled.",
+							Computed:    true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (d *OllamaHostsDataSource) ValidateConfig(ctx context.Context, req datasource.ValidateConfigRequest, resp *datasource.ValidateConfigResponse) {
+	var config OllamaHostsDataSourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !config.SRV.IsUnknown() && !config.InventoryFile.IsUnknown() && config.SRV.IsNull() == config.InventoryFile.IsNull() {
+		resp.Diagnostics.AddAttributeError(path.Root("srv"), "Invalid discovery configuration", "Exactly one of srv and inventory_file must be set.")
+	}
+	if !config.SRV.IsNull() && !config.SRV.IsUnknown() && !config.Labels.IsNull() {
+		resp.Diagnostics.AddAttributeError(path.Root("labels"), "Invalid discovery configuration", "labels only filter inventory hosts. Hosts found through srv have no labels.")
+	}
+	if scheme := config.Scheme.ValueString(); !config.Scheme.IsNull() && !config.Scheme.IsUnknown() && scheme != "http" && scheme != "https" {
+		resp.Diagnostics.AddAttributeError(path.Root("scheme"), "Invalid scheme", fmt.Sprintf("scheme must be \"http\" or \"https\", got %q.", scheme))
+	}
+	if !config.HeartbeatTimeout.IsNull() && !config.HeartbeatTimeout.IsUnknown() {
+		if timeout, err := time.ParseDuration(config.HeartbeatTimeout.ValueString()); err != nil || timeout <= 0 {
+			resp.Diagnostics.AddAttributeError(path.Root("heartbeat_timeout"), "Invalid heartbeat timeout", fmt.Sprintf("heartbeat_timeout must be a positive duration such as \"2s\", got %q.", config.HeartbeatTimeout.ValueString()))
+		}
+	}
+}
+
+func (d *OllamaHostsDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data OllamaHostsDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var (
+		hosts []discoveredHost
+		err   error
+	)
+	if !data.SRV.IsNull() {
+		scheme := defaultSRVScheme
+		if !data.Scheme.IsNull() {
+			scheme = data.Scheme.ValueString()
+		}
+		if hosts, err = lookupSRVHosts(ctx, data.SRV.ValueString(), scheme); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("srv"), "Error looking up SRV record", err.Error())
+			return
+		}
+	} else {
+		if hosts, err = loadInventory(data.InventoryFile.ValueString()); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("inventory_file"), "Error reading inventory", err.Error())
+			return
+		}
+	}
+
+	var labels map[string]string
+	if resp.Diagnostics.Append(data.Labels.ElementsAs(ctx, &labels, false)...); resp.Diagnostics.HasError() {
+		return
+	}
+
+	var matching []discoveredHost
+	for _, h := range hosts {
+		if h.hasLabels(labels) {
+			matching = append(matching, h)
+		}
+	}
+
+	if data.HealthCheck.ValueBool() {
+		timeout := defaultHeartbeatTimeout
+		if !data.HeartbeatTimeout.IsNull() {
+			timeout, _ = time.ParseDuration(data.HeartbeatTimeout.ValueString())
+		}
+		checkHeartbeats(ctx, matching, timeout)
+	}
+
+	data.Hosts = []types.String{}
+	data.Endpoints = []OllamaHostEndpoint{}
+	for _, h := range matching {
+		if data.HealthCheck.ValueBool() && !h.Healthy {
+			continue
+		}
+
+		hostLabels, diags := types.MapValueFrom(ctx, types.StringType, h.Labels)
+		if resp.Diagnostics.Append(diags...); resp.Diagnostics.HasError() {
+			return
+		}
+
+		version := types.StringNull()
+		if h.Healthy {
+			version = types.StringValue(h.Version)
+			d.advisories.CheckVersion(ctx, h.URL, h.Version, &resp.Diagnostics)
+		}
+
+		data.Hosts = append(data.Hosts, types.StringValue(h.URL))
+		data.Endpoints = append(data.Endpoints, OllamaHostEndpoint{
+			URL:     types.StringValue(h.URL),
+			Labels:  hostLabels,
+			Version: version,
+		})
+	}
+
+	tflog.Info(ctx, fmt.Sprintf("discovered %d hosts, %d match", len(hosts), len(data.Hosts)))
+
+	diags := resp.State.Set(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+}
@@ -22,12 +22,14 @@ func NewOllamaModelDataSource() datasource.DataSource {
 
 // OllamaModelDataSource defines the data source implementation.
 type OllamaModelDataSource struct {
-	client *api.Client
+	client     *api.Client
+	advisories *advisoryDatabase
 }
 
 // OllamaModelDataSourceModel describes the data source data model.
 type OllamaModelDataSourceModel struct {
-	Models []OllamaModel `tfsdk:"models"`
+	ClassifyLicenses types.Bool    `tfsdk:"classify_licenses"`
+	Models           []OllamaModel `tfsdk:"models"`
 }
 
 func (d *OllamaModelDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
@@ -39,6 +41,10 @@ func (d *OllamaModelDataSource) Schema(ctx context.Context, req datasource.Schem
 		Description: "Provides a list of available Ollama models and their details.",
 
 		Attributes: map[string]schema.Attribute{
+			"classify_licenses": schema.BoolAttribute{
+				Description: "Read the license of every model and classify it, filling in `license`. This takes one request per model.",
+				Optional:    true,
+			},
 			"models": schema.ListNestedAttribute{
 				Description: "A list of Ollama models, each representing a distinct machine learning model.",
 				Computed:    true,
@@ -59,6 +65,7 @@ func (d *OllamaModelDataSource) Schema(ctx context.Context, req datasource.Schem
 						"digest": schema.StringAttribute{
 							Description: "A unique identifier (digest) for the version of the Ollama model.",
 							Computed:    true,
+							CustomType:  DigestType{},
 						},
 						"details": schema.SingleNestedAttribute{
 							Description: "Detailed attributes of the Ollama model, including format and family.",
@@ -87,6 +94,25 @@ func (d *OllamaModelDataSource) Schema(ctx context.Context, req datasource.Schem
 								},
 							},
 						},
+						"license": schema.SingleNestedAttribute{
+							Description: "The classified license of the model, set if `classify_licenses` is enabled.",
+							Computed:    true,
+							Attributes: map[string]schema.Attribute{
+								"spdx_id": schema.StringAttribute{
+									Description: "The SPDX identifier of the license, a `LicenseRef-` identifier for known model licenses that are not on the SPDX list, " +
+										"`NONE` for a model without license or `NOASSERTION` if the text matches no known license or states a compound SPDX expression such as `Apache-2.0 OR MIT`.",
+									Computed: true,
+								},
+								"name": schema.StringAttribute{
+									Description: "The full name of the license.",
+									Computed:    true,
+								},
+								"confidence": schema.Float64Attribute{
+									Description: "How sure the classification is, from 0 to 1. Texts stating their SPDX identifier or carrying the short header of a known license are classified with confidence 1.",
+									Computed:    true,
+								},
+							},
+						},
 					},
 				},
 			},
@@ -100,18 +126,19 @@ func (d *OllamaModelDataSource) Configure(ctx context.Context, req datasource.Co
 		return
 	}
 
-	client, ok := req.ProviderData.(*api.Client)
+	data, ok := req.ProviderData.(*OllamaProviderData)
 
 	if !ok {
 		resp.Diagnostics.AddError(
 			"Unexpected Data Source Configure Type",
-			fmt.Sprintf("Expected *http.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+			fmt.Sprintf("Expected *OllamaProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
 		)
 
 		return
 	}
 
-	d.client = client
+	d.client = data.Client
+	d.advisories = data.Advisories
 }
 
 func (d *OllamaModelDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
@@ -124,6 +151,10 @@ func (d *OllamaModelDataSource) Read(ctx context.Context, req datasource.ReadReq
 		return
 	}
 
+	if d.advisories.Check(ctx, &resp.Diagnostics); resp.Diagnostics.HasError() {
+		return
+	}
+
 	rsp, err := d.client.List(ctx)
 	if err != nil {
 		resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to read ollama models, got error: %s", err))
@@ -136,11 +167,30 @@ func (d *OllamaModelDataSource) Read(ctx context.Context, req datasource.ReadReq
 		families, familiesDiags := types.ListValueFrom(ctx, types.StringType, m.Details.Families)
 		resp.Diagnostics.Append(familiesDiags...)
 
+		var license *OllamaModelLicense
+		if data.ClassifyLicenses.ValueBool() {
+			show, err := d.client.Show(ctx, &api.ShowRequest{Model: m.Name})
+			if apiErr, ok := err.(api.StatusError); ok && apiErr.StatusCode == 404 {
+				// deleted since it was listed
+				continue
+			}
+			if err != nil {
+				resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to read license of ollama model %s, got error: %s", m.Name, err))
+				return
+			}
+			match := classifyLicense(show.License)
+			license = &OllamaModelLicense{
+				SPDXID:     types.StringValue(match.ID),
+				Name:       types.StringValue(match.Name),
+				Confidence: types.Float64Value(match.Confidence),
+			}
+		}
+
 		data.Models = append(data.Models, OllamaModel{
 			Name:       types.StringValue(m.Name),
 			ModifiedAt: types.StringValue(m.ModifiedAt.String()),
 			Size:       types.Int64Value(m.Size),
-			Digest:     types.StringValue(m.Digest),
+			Digest:     NewDigestValue(m.Digest),
 			Details: OllamaModelDetails{
 				Format:            types.StringValue(m.Details.Format),
 				Family:            types.StringValue(m.Details.Family),
@@ -148,6 +198,7 @@ func (d *OllamaModelDataSource) Read(ctx context.Context, req datasource.ReadReq
 				ParameterSize:     types.StringValue(m.Details.ParameterSize),
 				QuantizationLevel: types.StringValue(m.Details.QuantizationLevel),
 			},
+			License: license,
 		})
 
 		tflog.Debug(ctx, fmt.Sprintf("model found: %s", m.Model))
@@ -3,18 +3,30 @@ package provider
 import (
 	"context"
 	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/listplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 	"github.com/ollama/ollama/api"
 )
 
 // Ensure the implementation satisfies the expected interfaces.
 var (
-	_ resource.Resource              = &ollamaModelResource{}
-	_ resource.ResourceWithConfigure = &ollamaModelResource{}
+	_ resource.Resource                   = &ollamaModelResource{}
+	_ resource.ResourceWithConfigure      = &ollamaModelResource{}
+	_ resource.ResourceWithModifyPlan     = &ollamaModelResource{}
+	_ resource.ResourceWithValidateConfig = &ollamaModelResource{}
 )
 
+// snapshotDigestLength is the number of digest characters in snapshot names.
+const snapshotDigestLength = 12
+
 func PullResponseFn(rsp api.ProgressResponse) error {
 	tflog.Debug(context.Background(), fmt.Sprintf("ollama Progress response: %#v", rsp))
 	return nil
@@ -27,7 +39,13 @@ func NewOllamaModelResource() resource.Resource {
 
 // ollamaModelResource is the resource implementation.
 type ollamaModelResource struct {
-	client *api.Client
+	client     *api.Client
+	eol        *eolPolicy
+	budget     *inferenceBudget
+	lock       *hostLock
+	lineage    *lineageEmitter
+	license    *licensePolicy
+	advisories *advisoryDatabase
 }
 
 func (r *ollamaModelResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
@@ -35,19 +53,24 @@ func (r *ollamaModelResource) Configure(ctx context.Context, req resource.Config
 		return
 	}
 
-	client, ok := req.ProviderData.(*api.Client)
+	data, ok := req.ProviderData.(*OllamaProviderData)
 
 	if !ok {
 		resp.Diagnostics.AddError(
 			"Unexpected Data Source Configure Type",
-			fmt.Sprintf("Expected *api.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+			fmt.Sprintf("Expected *OllamaProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
 		)
 
 		return
 	}
 
-	r.client = client
-
+	r.client = data.Client
+	r.eol = data.EOL
+	r.budget = data.Budget
+	r.lock = data.Lock
+	r.lineage = data.Lineage
+	r.license = data.LicensePolicy
+	r.advisories = data.Advisories
 }
 
 // Metadata returns the resource type name.
@@ -73,15 +96,129 @@ func (r *ollamaModelResource) Schema(_ context.Context, _ resource.SchemaRequest
 				Optional:    true,
 			},
 			"digest": schema.StringAttribute{
-				Description: "A digest or checksum that uniquely identifies the specific version of the Ollama model. This attribute is optional and helps ensure the integrity of the model.",
+				Description: "A digest or checksum that uniquely identifies the specific version of the Ollama model. This attribute is optional and helps ensure the integrity of the model. Written as `sha256:<hex>`, `sha256-<hex>` or bare hex, which are all treated as the same digest.",
 				Optional:    true,
+				CustomType:  DigestType{},
+			},
+			"verify_load": schema.BoolAttribute{
+				Description: "After pulling, load the model with a one-token generate request and fail the apply if the server cannot load it.",
+				Optional:    true,
+			},
+			"delete_on_verify_failure": schema.BoolAttribute{
+				Description: "Delete the model again if `verify_load` fails and it was not on the server before the apply. Otherwise the model stays on the server.",
+				Optional:    true,
+			},
+			"snapshot_retention": schema.Int64Attribute{
+				Description: "After each pull, copy the model to an immutable snapshot named `<name>-<short digest>`, e.g. `llama3:latest-365c0bd3c000`, " +
+					"so the exact version stays available for rollback when the tag moves. Only this many of the newest snapshots are kept, older ones are deleted. " +
+					"Changing it is applied in place. Removing it deletes all snapshots.",
+				Optional: true,
+			},
+			"snapshots": schema.ListAttribute{
+				Description: "The snapshots taken of the model, newest first. They are deleted together with the model.",
+				Computed:    true,
+				ElementType: types.StringType,
+				PlanModifiers: []planmodifier.List{
+					listplanmodifier.UseStateForUnknown(),
+				},
 			},
 		},
 	}
 }
 
+func (r *ollamaModelResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var config OllamaModelResource
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !config.SnapshotRetention.IsNull() && !config.SnapshotRetention.IsUnknown() && config.SnapshotRetention.ValueInt64() < 1 {
+		resp.Diagnostics.AddAttributeError(path.Root("snapshot_retention"), "Invalid snapshot retention", "snapshot_retention must be at least 1.")
+	}
+}
+
+// ModifyPlan checks the host for known vulnerabilities, warns about models
+// that are scheduled for retirement and plans the snapshots as unknown when
+// the update takes or prunes snapshots.
+func (r *ollamaModelResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	if r.advisories.Check(ctx, &resp.Diagnostics); resp.Diagnostics.HasError() {
+		return
+	}
+
+	var plan OllamaModelResource
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if r.eol != nil {
+		r.eol.Check(path.Root("name"), plan.Name, &resp.Diagnostics)
+	}
+
+	if req.State.Raw.IsNull() {
+		return
+	}
+
+	var state OllamaModelResource
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !r.snapshotsUnchanged(ctx, &plan, &state) {
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("snapshots"), types.ListUnknown(types.StringType))...)
+	}
+}
+
+// snapshotsUnchanged reports whether applying plan keeps the snapshots of
+// state: the model keeps its name and retention, and the newest snapshot is
+// of the digest the model has now and is not pulled again.
+func (r *ollamaModelResource) snapshotsUnchanged(ctx context.Context, plan, state *OllamaModelResource) bool {
+	if plan.Name.IsUnknown() || !sameModelName(plan.Name.ValueString(), state.Name.ValueString()) || !plan.SnapshotRetention.Equal(state.SnapshotRetention) {
+		return false
+	}
+	if plan.SnapshotRetention.IsNull() {
+		return true
+	}
+	if needsPull(plan, state) {
+		return false
+	}
+
+	var snapshots []string
+	if state.Snapshots.IsNull() || state.Snapshots.ElementsAs(ctx, &snapshots, false).HasError() || len(snapshots) == 0 {
+		return false
+	}
+
+	listed, err := lookupModel(ctx, r.client, plan.Name.ValueString())
+	if err != nil || listed == nil {
+		return false
+	}
+	return sameModelName(snapshots[0], snapshotName(plan.Name.ValueString(), listed.Digest))
+}
+
+// needsPull reports whether applying plan over state pulls the model again.
+// Only verify_load, delete_on_verify_failure and snapshot_retention apply to
+// the model that is there.
+func needsPull(plan, state *OllamaModelResource) bool {
+	return !sameModelName(plan.Name.ValueString(), state.Name.ValueString()) ||
+		!plan.ModifiedAt.Equal(state.ModifiedAt) ||
+		!plan.Size.Equal(state.Size) ||
+		!plan.Digest.Equal(state.Digest)
+}
+
 // Create creates the resource and sets the initial Terraform state.
 func (r *ollamaModelResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	unlock, ok := lockHost(ctx, r.lock, &resp.Diagnostics)
+	if !ok {
+		return
+	}
+	defer unlock()
+
 	var plan OllamaModelResource
 	diags := req.Plan.Get(ctx, &plan)
 	resp.Diagnostics.Append(diags...)
@@ -91,12 +228,7 @@ func (r *ollamaModelResource) Create(ctx context.Context, req resource.CreateReq
 
 	tflog.Debug(ctx, fmt.Sprintf("model name: %s", plan.Name.String()))
 
-	noStream := false
-	ollamaReq := &api.PullRequest{
-		Stream: &noStream,
-		Name:   plan.Name.ValueString(),
-	}
-	err := r.client.Pull(ctx, ollamaReq, PullResponseFn)
+	pulled, err := r.pullModel(ctx, plan.Name.ValueString())
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Error pulling model",
@@ -105,6 +237,26 @@ func (r *ollamaModelResource) Create(ctx context.Context, req resource.CreateReq
 		return
 	}
 
+	r.checkLicense(ctx, &plan, pulled, &resp.Diagnostics)
+	if !resp.Diagnostics.HasError() {
+		r.verifyLoad(ctx, &plan, pulled, &resp.Diagnostics)
+	}
+	if !resp.Diagnostics.HasError() {
+		r.snapshot(ctx, &plan, types.ListNull(types.StringType), &resp.Diagnostics)
+	}
+
+	// a model this apply pulled that a failed check left on the host is
+	// saved, so Terraform taints it instead of leaving it unmanaged
+	if resp.Diagnostics.HasError() {
+		if pulled && r.modelExists(ctx, plan.Name.ValueString()) {
+			if plan.Snapshots.IsUnknown() {
+				plan.Snapshots = types.ListValueMust(types.StringType, []attr.Value{})
+			}
+			resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+		}
+		return
+	}
+
 	diags = resp.State.Set(ctx, plan)
 	resp.Diagnostics.Append(diags...)
 	if resp.Diagnostics.HasError() {
@@ -152,6 +304,12 @@ func (r *ollamaModelResource) Read(ctx context.Context, req resource.ReadRequest
 
 // Update updates the resource and sets the updated Terraform state on success.
 func (r *ollamaModelResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	unlock, ok := lockHost(ctx, r.lock, &resp.Diagnostics)
+	if !ok {
+		return
+	}
+	defer unlock()
+
 	// Get current state
 	var state OllamaModelResource
 	diags := req.State.Get(ctx, &state)
@@ -167,31 +325,46 @@ func (r *ollamaModelResource) Update(ctx context.Context, req resource.UpdateReq
 		return
 	}
 
-	// first delete old model
-	tflog.Debug(ctx, fmt.Sprintf("deleting old model: %#v", state.Name.ValueString()))
-	err := r.client.Delete(ctx, &api.DeleteRequest{Model: state.Name.ValueString()})
-	if err != nil {
-		resp.Diagnostics.AddError(
-			"Error deleting Ollama Model",
-			"Could not delete ollama model "+state.Name.ValueString()+": "+err.Error(),
-		)
+	// the model is pulled again, so a moved tag is picked up, unless only the
+	// post-pull steps changed; a new name is pulled next to the old model,
+	// which is only deleted once the new one passed all checks
+	renamed := !sameModelName(state.Name.ValueString(), plan.Name.ValueString())
+	pulled := false
+	if needsPull(&plan, &state) {
+		var err error
+		if pulled, err = r.pullModel(ctx, plan.Name.ValueString()); err != nil {
+			resp.Diagnostics.AddError(
+				"Error pulling model",
+				fmt.Sprintf("Could not pull model, unexpected error: %s", err.Error()),
+			)
+			return
+		}
+	}
+
+	if r.checkLicense(ctx, &plan, pulled, &resp.Diagnostics); resp.Diagnostics.HasError() {
 		return
 	}
 
-	// second pull new model
-	noStream := false
-	ollamaReq := &api.PullRequest{
-		Stream: &noStream,
-		Name:   plan.Name.ValueString(),
+	if r.verifyLoad(ctx, &plan, pulled, &resp.Diagnostics); resp.Diagnostics.HasError() {
+		return
 	}
-	if err := r.client.Pull(ctx, ollamaReq, PullResponseFn); err != nil {
-		resp.Diagnostics.AddError(
-			"Error pulling model",
-			fmt.Sprintf("Could not pull model, unexpected error: %s", err.Error()),
-		)
+
+	if r.snapshot(ctx, &plan, state.Snapshots, &resp.Diagnostics); resp.Diagnostics.HasError() {
 		return
 	}
 
+	if renamed {
+		tflog.Debug(ctx, fmt.Sprintf("deleting old model: %#v", state.Name.ValueString()))
+		err := r.client.Delete(ctx, &api.DeleteRequest{Model: state.Name.ValueString()})
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"Error deleting Ollama Model",
+				"Could not delete ollama model "+state.Name.ValueString()+": "+err.Error(),
+			)
+			return
+		}
+	}
+
 	// set new state
 	diags = resp.State.Set(ctx, plan)
 	resp.Diagnostics.Append(diags...)
@@ -203,6 +376,12 @@ func (r *ollamaModelResource) Update(ctx context.Context, req resource.UpdateReq
 
 // Delete deletes the resource and removes the Terraform state on success.
 func (r *ollamaModelResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	unlock, ok := lockHost(ctx, r.lock, &resp.Diagnostics)
+	if !ok {
+		return
+	}
+	defer unlock()
+
 	// Get current state
 	var state OllamaModelResource
 	diags := req.State.Get(ctx, &state)
@@ -219,4 +398,186 @@ func (r *ollamaModelResource) Delete(ctx context.Context, req resource.DeleteReq
 		)
 		return
 	}
+
+	var snapshots []string
+	resp.Diagnostics.Append(state.Snapshots.ElementsAs(ctx, &snapshots, false)...)
+	for _, name := range snapshots {
+		r.deleteSnapshot(ctx, name, &resp.Diagnostics)
+	}
+}
+
+// pull pulls the model and reports the pull as a lineage run.
+func (r *ollamaModelResource) pull(ctx context.Context, req *api.PullRequest) error {
+	run := r.lineage.Start(ctx, lineageJobPull, nil, []lineageDataset{{Name: req.Name}})
+	err := r.client.Pull(ctx, req, PullResponseFn)
+	run.Finish(ctx, err)
+	return err
+}
+
+// pullModel pulls name and reports whether it was new on the host, so failed
+// checks only delete models this resource brought there.
+func (r *ollamaModelResource) pullModel(ctx context.Context, name string) (bool, error) {
+	existing, err := lookupModel(ctx, r.client, name)
+	if err != nil {
+		return false, err
+	}
+
+	noStream := false
+	if err := r.pull(ctx, &api.PullRequest{Stream: &noStream, Name: name}); err != nil {
+		return false, err
+	}
+	return existing == nil, nil
+}
+
+// deleteFailedModel deletes a model that failed a check if pulled is set and
+// describes the outcome for the error detail. Models that were on the host
+// before are never deleted.
+func (r *ollamaModelResource) deleteFailedModel(ctx context.Context, name string, pulled bool) string {
+	if !pulled {
+		return "\n\nThe model was on the host before and was not deleted."
+	}
+	if err := r.client.Delete(ctx, &api.DeleteRequest{Model: name}); err != nil {
+		return fmt.Sprintf("\n\nDeleting the model failed as well: %s", err.Error())
+	}
+	return "\n\nThe model was deleted again."
+}
+
+// checkLicense classifies the license of the model and, if the license policy
+// does not allow it, deletes the model again if this apply pulled it.
+func (r *ollamaModelResource) checkLicense(ctx context.Context, plan *OllamaModelResource, pulled bool, diags *diag.Diagnostics) {
+	if r.license == nil {
+		return
+	}
+
+	name := plan.Name.ValueString()
+	show, err := r.client.Show(ctx, &api.ShowRequest{Model: name})
+	if err != nil {
+		diags.AddError("Error reading model license", fmt.Sprintf("Could not read the license of model %s: %s", name, err.Error()))
+		return
+	}
+
+	match := classifyLicense(show.License)
+	tflog.Debug(ctx, fmt.Sprintf("model %s is licensed under %s (confidence %.2f)", name, match.ID, match.Confidence))
+
+	reason := r.license.Check(match)
+	if reason == "" {
+		return
+	}
+
+	detail := fmt.Sprintf("Model %s violates the license policy: %s.", name, reason)
+	detail += r.deleteFailedModel(ctx, name, pulled)
+
+	diags.AddAttributeError(path.Root("name"), "License not allowed", detail)
+}
+
+// snapshot copies the model to a snapshot named after its digest if
+// snapshot_retention is set, and deletes the snapshots in prior beyond the
+// retention. Without snapshot_retention all snapshots in prior are deleted.
+// plan.Snapshots is set to the snapshots that are kept.
+func (r *ollamaModelResource) snapshot(ctx context.Context, plan *OllamaModelResource, prior types.List, diags *diag.Diagnostics) {
+	var snapshots []string
+	if !prior.IsNull() && !prior.IsUnknown() {
+		diags.Append(prior.ElementsAs(ctx, &snapshots, false)...)
+	}
+
+	if retention := plan.SnapshotRetention.ValueInt64(); retention > 0 {
+		name := plan.Name.ValueString()
+		listed, err := lookupModel(ctx, r.client, name)
+		if err != nil || listed == nil {
+			diags.AddError("Error taking snapshot", fmt.Sprintf("Could not read the digest of model %s: %v", name, err))
+			return
+		}
+
+		snapshot := snapshotName(name, listed.Digest)
+
+		// copied even if it was taken before, in case it was deleted since
+		tflog.Info(ctx, fmt.Sprintf("copying model %s to snapshot %s", name, snapshot))
+
+		run := r.lineage.Start(ctx, lineageJobCopy, []lineageDataset{{Name: name, Digest: listed.Digest}}, []lineageDataset{{Name: snapshot}})
+		err = r.client.Copy(ctx, &api.CopyRequest{Source: name, Destination: snapshot})
+		run.Finish(ctx, err)
+		if err != nil {
+			diags.AddError("Error taking snapshot", fmt.Sprintf("Could not copy model %s to %s: %s", name, snapshot, err.Error()))
+			return
+		}
+
+		// the current snapshot moves to the front, older ones beyond the retention are pruned
+		kept := []string{snapshot}
+		for _, s := range snapshots {
+			if sameModelName(s, snapshot) {
+				continue
+			}
+			if int64(len(kept)) < retention {
+				kept = append(kept, s)
+			} else {
+				r.deleteSnapshot(ctx, s, diags)
+			}
+		}
+		snapshots = kept
+	} else {
+		for _, s := range snapshots {
+			r.deleteSnapshot(ctx, s, diags)
+		}
+		snapshots = nil
+	}
+
+	if snapshots == nil {
+		snapshots = []string{}
+	}
+	list, d := types.ListValueFrom(ctx, types.StringType, snapshots)
+	diags.Append(d...)
+	plan.Snapshots = list
+}
+
+// snapshotName returns the snapshot of model name at digest, such as
+// llama3:latest-365c0bd3c000.
+func snapshotName(name, digest string) string {
+	digest = normalizeDigest(digest)
+	if len(digest) > snapshotDigestLength {
+		digest = digest[:snapshotDigestLength]
+	}
+	return normalizeModelName(name) + "-" + digest
+}
+
+// modelExists reports whether the host lists model name. Errors count as not
+// listed.
+func (r *ollamaModelResource) modelExists(ctx context.Context, name string) bool {
+	listed, err := lookupModel(ctx, r.client, name)
+	return err == nil && listed != nil
+}
+
+func (r *ollamaModelResource) deleteSnapshot(ctx context.Context, name string, diags *diag.Diagnostics) {
+	tflog.Info(ctx, fmt.Sprintf("deleting snapshot %s", name))
+
+	err := r.client.Delete(ctx, &api.DeleteRequest{Model: name})
+	if apiErr, ok := err.(api.StatusError); ok && apiErr.StatusCode == 404 {
+		return
+	}
+	if err != nil {
+		diags.AddError("Error deleting snapshot", "Could not delete snapshot "+name+": "+err.Error())
+	}
+}
+
+// verifyLoad checks that the model loads if verify_load is set, and deletes it
+// on failure if delete_on_verify_failure is set and this apply pulled it.
+func (r *ollamaModelResource) verifyLoad(ctx context.Context, plan *OllamaModelResource, pulled bool, diags *diag.Diagnostics) {
+	if !plan.VerifyLoad.ValueBool() {
+		return
+	}
+
+	name := plan.Name.ValueString()
+	tflog.Debug(ctx, fmt.Sprintf("verifying that model %s loads", name))
+
+	err := verifyModelLoads(ctx, r.client, r.budget, name)
+	r.budget.LogUsage(ctx)
+	if err == nil {
+		return
+	}
+
+	detail := fmt.Sprintf("The model was pulled but could not be loaded: %s", err.Error())
+	if plan.DeleteOnVerifyFailure.ValueBool() {
+		detail += r.deleteFailedModel(ctx, name, pulled)
+	}
+
+	diags.AddError("Error verifying model", detail)
 }
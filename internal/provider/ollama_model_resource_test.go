@@ -0,0 +1,443 @@
+package provider
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/ollama/ollama/api"
+)
+
+func testModel(name string) *OllamaModelResource {
+	return &OllamaModelResource{
+		Name:                  types.StringValue(name),
+		ModifiedAt:            types.StringNull(),
+		Size:                  types.Int64Null(),
+		Digest:                NewDigestNull(),
+		VerifyLoad:            types.BoolNull(),
+		DeleteOnVerifyFailure: types.BoolNull(),
+		SnapshotRetention:     types.Int64Null(),
+		Snapshots:             types.ListUnknown(types.StringType),
+	}
+}
+
+func newTestModelResource(client *api.Client) *ollamaModelResource {
+	return &ollamaModelResource{client: client}
+}
+
+func TestOllamaModelResourceUpdate(t *testing.T) {
+	t.Run("unchanged name is not pulled again", func(t *testing.T) {
+		f, _, client := newFakeOllama(t)
+		r := newTestModelResource(client)
+
+		var state OllamaModelResource
+		if diags := applyResource(t, r, nil, testModel("llama3"), &state); diags.HasError() {
+			t.Fatal(diags)
+		}
+
+		plan := testModel("llama3")
+		plan.VerifyLoad = types.BoolValue(true)
+		if diags := applyResource(t, r, &state, plan, &state); diags.HasError() {
+			t.Fatal(diags)
+		}
+
+		if got := f.Calls("/api/pull"); got != 1 {
+			t.Errorf("pulled %d times, want 1", got)
+		}
+		if got := f.Calls("/api/delete"); got != 0 {
+			t.Errorf("deleted %d times, want 0", got)
+		}
+		if got := f.Calls("/api/generate"); got != 1 {
+			t.Errorf("verify_load ran %d times, want 1", got)
+		}
+		if !f.Has("llama3") {
+			t.Error("model is gone after an in-place update")
+		}
+	})
+
+	t.Run("other changes pull again without deleting", func(t *testing.T) {
+		f, _, client := newFakeOllama(t)
+		r := newTestModelResource(client)
+
+		var state OllamaModelResource
+		if diags := applyResource(t, r, nil, testModel("llama3"), &state); diags.HasError() {
+			t.Fatal(diags)
+		}
+
+		plan := testModel("llama3")
+		plan.Digest = NewDigestValue("sha256:" + strings.Repeat("3", 64))
+		if diags := applyResource(t, r, &state, plan, &state); diags.HasError() {
+			t.Fatal(diags)
+		}
+
+		if got := f.Calls("/api/pull"); got != 2 {
+			t.Errorf("pulled %d times, want 2", got)
+		}
+		if got := f.Calls("/api/delete"); got != 0 {
+			t.Errorf("deleted %d times, want 0", got)
+		}
+		if !f.Has("llama3") {
+			t.Error("model is gone after an in-place update")
+		}
+	})
+
+	t.Run("rename pulls the new model before deleting the old one", func(t *testing.T) {
+		f, _, client := newFakeOllama(t)
+		r := newTestModelResource(client)
+
+		var state OllamaModelResource
+		if diags := applyResource(t, r, nil, testModel("llama3"), &state); diags.HasError() {
+			t.Fatal(diags)
+		}
+		if diags := applyResource(t, r, &state, testModel("mistral"), &state); diags.HasError() {
+			t.Fatal(diags)
+		}
+
+		if f.Has("llama3") || !f.Has("mistral") {
+			t.Errorf("models after rename = %v, want only mistral", f.Models())
+		}
+		if state.Name.ValueString() != "mistral" {
+			t.Errorf("state name = %s, want mistral", state.Name.ValueString())
+		}
+	})
+
+	t.Run("failed checks keep the old model", func(t *testing.T) {
+		f, _, client := newFakeOllama(t)
+		r := newTestModelResource(client)
+
+		var state OllamaModelResource
+		if diags := applyResource(t, r, nil, testModel("llama3"), &state); diags.HasError() {
+			t.Fatal(diags)
+		}
+
+		f.FailLoad = true
+		plan := testModel("mistral")
+		plan.VerifyLoad = types.BoolValue(true)
+		plan.DeleteOnVerifyFailure = types.BoolValue(true)
+		if diags := applyResource(t, r, &state, plan, &state); !diags.HasError() {
+			t.Fatal("update succeeded although the new model does not load")
+		}
+
+		if !f.Has("llama3") || f.Has("mistral") {
+			t.Errorf("models after failed rename = %v, want only llama3", f.Models())
+		}
+	})
+}
+
+func TestOllamaModelResourceFailedChecks(t *testing.T) {
+	denyMIT := &licensePolicy{Deny: []string{"MIT"}}
+
+	tests := map[string]struct {
+		// existing models are on the host before the apply
+		existing []string
+		// prior is the name in state, empty for a create
+		prior      string
+		name       string
+		license    *licensePolicy
+		failLoad   bool
+		wantModels []string
+	}{
+		"create with denied license deletes the pulled model": {
+			name:       "phi3",
+			license:    denyMIT,
+			wantModels: []string{},
+		},
+		"create with denied license keeps a model that existed": {
+			existing:   []string{"phi3"},
+			name:       "phi3",
+			license:    denyMIT,
+			wantModels: []string{"phi3:latest"},
+		},
+		"create failing verify_load keeps a model that existed": {
+			existing:   []string{"phi3"},
+			name:       "phi3",
+			failLoad:   true,
+			wantModels: []string{"phi3:latest"},
+		},
+		"rename with denied license keeps the old model": {
+			prior:      "llama3",
+			name:       "phi3",
+			license:    denyMIT,
+			wantModels: []string{"llama3:latest"},
+		},
+		"rename with denied license keeps a new model that existed": {
+			existing:   []string{"phi3"},
+			prior:      "llama3",
+			name:       "phi3",
+			license:    denyMIT,
+			wantModels: []string{"llama3:latest", "phi3:latest"},
+		},
+		"in-place update with denied license keeps the model": {
+			prior:      "phi3",
+			name:       "phi3",
+			license:    denyMIT,
+			wantModels: []string{"phi3:latest"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			f, _, client := newFakeOllama(t)
+			f.Licenses["phi3:latest"] = mitLicenseText
+			for _, m := range tt.existing {
+				f.AddModel(m)
+			}
+
+			r := newTestModelResource(client)
+
+			var prior *OllamaModelResource
+			if tt.prior != "" {
+				prior = &OllamaModelResource{}
+				if diags := applyResource(t, r, nil, testModel(tt.prior), prior); diags.HasError() {
+					t.Fatal(diags)
+				}
+			}
+
+			r.license = tt.license
+			f.mu.Lock()
+			f.FailLoad = tt.failLoad
+			f.mu.Unlock()
+
+			plan := testModel(tt.name)
+			plan.VerifyLoad = types.BoolValue(tt.failLoad)
+			plan.DeleteOnVerifyFailure = types.BoolValue(tt.failLoad)
+			if diags := applyResource(t, r, prior, plan, &OllamaModelResource{}); !diags.HasError() {
+				t.Fatal("apply succeeded, want failed check")
+			}
+
+			got := f.Models()
+			if len(got) != len(tt.wantModels) {
+				t.Fatalf("models = %v, want %v", got, tt.wantModels)
+			}
+			for i := range got {
+				if got[i] != tt.wantModels[i] {
+					t.Fatalf("models = %v, want %v", got, tt.wantModels)
+				}
+			}
+		})
+	}
+}
+
+func TestOllamaModelResourceSnapshots(t *testing.T) {
+	f, _, client := newFakeOllama(t)
+	r := newTestModelResource(client)
+
+	// move simulates the tag of llama3 moving to a new digest
+	move := func(digest string) {
+		f.mu.Lock()
+		f.Digests["llama3:latest"] = digest
+		f.mu.Unlock()
+		f.AddModel("llama3")
+	}
+	snapshots := func(state *OllamaModelResource) []string {
+		var got []string
+		if diags := state.Snapshots.ElementsAs(context.Background(), &got, false); diags.HasError() {
+			t.Fatal(diags)
+		}
+		return got
+	}
+	withRetention := func(n int64) *OllamaModelResource {
+		plan := testModel("llama3")
+		if n > 0 {
+			plan.SnapshotRetention = types.Int64Value(n)
+		}
+		return plan
+	}
+
+	move("aaaaaaaaaaaa0000")
+	var state OllamaModelResource
+	if diags := applyResource(t, r, nil, withRetention(2), &state); diags.HasError() {
+		t.Fatal(diags)
+	}
+
+	for _, digest := range []string{"bbbbbbbbbbbb0000", "cccccccccccc0000"} {
+		move(digest)
+		if diags := applyResource(t, r, &state, withRetention(2), &state); diags.HasError() {
+			t.Fatal(diags)
+		}
+	}
+
+	want := []string{"llama3:latest-cccccccccccc", "llama3:latest-bbbbbbbbbbbb"}
+	if got := snapshots(&state); !reflect.DeepEqual(got, want) {
+		t.Errorf("snapshots = %v, want %v", got, want)
+	}
+	if f.Has("llama3:latest-aaaaaaaaaaaa") {
+		t.Error("snapshot beyond the retention was not deleted")
+	}
+
+	t.Run("lower retention prunes in place", func(t *testing.T) {
+		if diags := applyResource(t, r, &state, withRetention(1), &state); diags.HasError() {
+			t.Fatal(diags)
+		}
+
+		want := []string{"llama3:latest-cccccccccccc"}
+		if got := snapshots(&state); !reflect.DeepEqual(got, want) {
+			t.Errorf("snapshots = %v, want %v", got, want)
+		}
+		if f.Has("llama3:latest-bbbbbbbbbbbb") {
+			t.Error("pruned snapshot was not deleted")
+		}
+	})
+
+	t.Run("removed retention deletes all snapshots", func(t *testing.T) {
+		if diags := applyResource(t, r, &state, withRetention(0), &state); diags.HasError() {
+			t.Fatal(diags)
+		}
+
+		if got := snapshots(&state); len(got) != 0 {
+			t.Errorf("snapshots = %v, want none", got)
+		}
+		if want := []string{"llama3:latest"}; !reflect.DeepEqual(f.Models(), want) {
+			t.Errorf("models = %v, want %v", f.Models(), want)
+		}
+	})
+
+	if got := f.Calls("/api/pull"); got != 1 {
+		t.Errorf("pulled %d times, want only on create", got)
+	}
+}
+
+func TestOllamaModelResourceSnapshotsOnPull(t *testing.T) {
+	f, _, client := newFakeOllama(t)
+	r := newTestModelResource(client)
+
+	withDigest := func(digest string) *OllamaModelResource {
+		plan := testModel("llama3")
+		plan.Digest = NewDigestValue(digest)
+		plan.SnapshotRetention = types.Int64Value(2)
+		return plan
+	}
+
+	var state OllamaModelResource
+	for _, digest := range []string{strings.Repeat("a", 64), strings.Repeat("b", 64), strings.Repeat("c", 64)} {
+		// the tag moves in the registry, the next pull brings the new digest
+		f.mu.Lock()
+		f.Digests["llama3:latest"] = digest
+		f.mu.Unlock()
+
+		prior := &state
+		if state.Name.IsNull() {
+			prior = nil
+		}
+		plan := withDigest(digest)
+		if prior != nil {
+			if r.snapshotsUnchanged(context.Background(), plan, prior) {
+				t.Fatalf("snapshots planned unchanged for a pull of %s", digest)
+			}
+		}
+		if diags := applyResource(t, r, prior, plan, &state); diags.HasError() {
+			t.Fatal(diags)
+		}
+	}
+
+	var got []string
+	if diags := state.Snapshots.ElementsAs(context.Background(), &got, false); diags.HasError() {
+		t.Fatal(diags)
+	}
+	want := []string{"llama3:latest-cccccccccccc", "llama3:latest-bbbbbbbbbbbb"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("snapshots = %v, want %v", got, want)
+	}
+	if f.Has("llama3:latest-aaaaaaaaaaaa") {
+		t.Error("snapshot beyond the retention was not deleted")
+	}
+	if got := f.Calls("/api/pull"); got != 3 {
+		t.Errorf("pulled %d times, want 3", got)
+	}
+}
+
+func TestOllamaModelResourceCreateKeepsFailedModel(t *testing.T) {
+	tests := map[string]struct {
+		existing  bool
+		delete    bool
+		wantState bool
+	}{
+		"pulled model left on the host is saved":  {wantState: true},
+		"pulled model deleted again is not saved": {delete: true},
+		"existing model is not adopted":           {existing: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			f, _, client := newFakeOllama(t)
+			if tt.existing {
+				f.AddModel("llama3")
+			}
+			f.FailLoad = true
+			r := newTestModelResource(client)
+
+			plan := testModel("llama3")
+			plan.VerifyLoad = types.BoolValue(true)
+			plan.DeleteOnVerifyFailure = types.BoolValue(tt.delete)
+
+			var state OllamaModelResource
+			if diags := applyResource(t, r, nil, plan, &state); !diags.HasError() {
+				t.Fatal("create succeeded although the model does not load")
+			}
+
+			if saved := !state.Name.IsNull(); saved != tt.wantState {
+				t.Errorf("state saved = %v, want %v", saved, tt.wantState)
+			}
+			if tt.wantState && state.Snapshots.IsUnknown() {
+				t.Error("saved state has unknown snapshots")
+			}
+		})
+	}
+}
+
+func TestOllamaModelResourceSnapshotsUnchanged(t *testing.T) {
+	f, _, client := newFakeOllama(t)
+	f.Digests["llama3:latest"] = "aaaaaaaaaaaa0000"
+	r := newTestModelResource(client)
+
+	plan := testModel("llama3")
+	plan.SnapshotRetention = types.Int64Value(2)
+	var state OllamaModelResource
+	if diags := applyResource(t, r, nil, plan, &state); diags.HasError() {
+		t.Fatal(diags)
+	}
+
+	tests := map[string]struct {
+		change func(plan *OllamaModelResource)
+		moved  bool
+		want   bool
+	}{
+		"unrelated change": {
+			change: func(plan *OllamaModelResource) { plan.VerifyLoad = types.BoolValue(true) },
+			want:   true,
+		},
+		"retention change": {
+			change: func(plan *OllamaModelResource) { plan.SnapshotRetention = types.Int64Value(1) },
+		},
+		"rename": {
+			change: func(plan *OllamaModelResource) { plan.Name = types.StringValue("mistral") },
+		},
+		"pulled again": {
+			change: func(plan *OllamaModelResource) { plan.Digest = NewDigestValue(strings.Repeat("b", 64)) },
+		},
+		"tag moved on the host": {
+			change: func(plan *OllamaModelResource) {},
+			moved:  true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			f.mu.Lock()
+			f.Digests["llama3:latest"] = "aaaaaaaaaaaa0000"
+			if tt.moved {
+				f.Digests["llama3:latest"] = "bbbbbbbbbbbb0000"
+			}
+			f.addModel("llama3")
+			f.mu.Unlock()
+
+			plan := state
+			tt.change(&plan)
+			if got := r.snapshotsUnchanged(context.Background(), &plan, &state); got != tt.want {
+				t.Errorf("snapshotsUnchanged() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,445 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/ollama/ollama/api"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ resource.Resource               = &ollamaModelfileDirectoryResource{}
+	_ resource.ResourceWithConfigure  = &ollamaModelfileDirectoryResource{}
+	_ resource.ResourceWithModifyPlan = &ollamaModelfileDirectoryResource{}
+)
+
+const modelfileSuffix = ".Modelfile"
+
+// NewOllamaModelfileDirectoryResource is a helper function to simplify the provider implementation.
+func NewOllamaModelfileDirectoryResource() resource.Resource {
+	return &ollamaModelfileDirectoryResource{}
+}
+
+// ollamaModelfileDirectoryResource keeps the models on the server in sync with
+// a directory of Modelfiles.
+type ollamaModelfileDirectoryResource struct {
+	client     *api.Client
+	uploader   *blobUploader
+	eol        *eolPolicy
+	lock       *hostLock
+	lineage    *lineageEmitter
+	advisories *advisoryDatabase
+}
+
+func (r *ollamaModelfileDirectoryResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*OllamaProviderData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *OllamaProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.client = data.Client
+	r.uploader = newBlobUploader(data.Client, data.Base, data.HTTP)
+	r.eol = data.EOL
+	r.lock = data.Lock
+	r.lineage = data.Lineage
+	r.advisories = data.Advisories
+}
+
+// Metadata returns the resource type name.
+func (r *ollamaModelfileDirectoryResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_modelfile_directory"
+}
+
+func (r *ollamaModelfileDirectoryResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Syncs a directory of Modelfiles to the server. Every `<name>.Modelfile` is created as model `<name>` " +
+			"and re-created when the file changes. Models whose file was removed are deleted. " +
+			"When a model cannot be created, the models created before it are kept in state and the rest are retried on the next apply.",
+
+		Attributes: map[string]schema.Attribute{
+			"directory": schema.StringAttribute{
+				Description: "The directory containing the `.Modelfile` files. Relative FROM and ADAPTER paths in the files are resolved against it.",
+				Required:    true,
+			},
+			"namespace": schema.StringAttribute{
+				Description: "Prefix for the model names, giving `<namespace>/<name>`. Only models this resource created are deleted, other models in the namespace are left alone.",
+				Optional:    true,
+			},
+			"files": schema.MapAttribute{
+				Description: "SHA-256 of each Modelfile by model name. A change re-creates the model.",
+				Computed:    true,
+				ElementType: types.StringType,
+			},
+			"models": schema.MapAttribute{
+				Description: "Digest of each created model by model name.",
+				Computed:    true,
+				ElementType: types.StringType,
+			},
+			"security_scan": securityScanAttribute(),
+		},
+	}
+}
+
+// ModifyPlan hashes the Modelfiles, so added, changed and removed files show up in the plan.
+func (r *ollamaModelfileDirectoryResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	if r.advisories.Check(ctx, &resp.Diagnostics); resp.Diagnostics.HasError() {
+		return
+	}
+
+	var plan OllamaModelfileDirectoryResource
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() || plan.Directory.IsUnknown() || plan.Namespace.IsUnknown() {
+		return
+	}
+
+	files, err := r.readDirectory(&plan)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("directory"), "Error reading Modelfile directory", err.Error())
+		return
+	}
+
+	hashes := map[string]string{}
+	for name, f := range files {
+		hashes[name] = f.sha256
+
+		// files that do not parse are reported on apply
+		if mf, err := parseModelfile(f.content); err == nil && !isLocalModelPath(mf.From, filepath.Dir(f.path)) {
+			r.eol.CheckName(path.Root("directory"), mf.From, &resp.Diagnostics)
+		}
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var diags diag.Diagnostics
+	plan.Files, diags = types.MapValueFrom(ctx, types.StringType, hashes)
+	resp.Diagnostics.Append(diags...)
+
+	plan.Models = types.MapUnknown(types.StringType)
+	if !req.State.Raw.IsNull() {
+		var state OllamaModelfileDirectoryResource
+		resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+
+		if plan.Files.Equal(state.Files) && plan.Namespace.Equal(state.Namespace) {
+			plan.Models = state.Models
+		}
+	}
+
+	resp.Diagnostics.Append(resp.Plan.Set(ctx, plan)...)
+}
+
+// Create creates the resource and sets the initial Terraform state.
+func (r *ollamaModelfileDirectoryResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	unlock, ok := lockHost(ctx, r.lock, &resp.Diagnostics)
+	if !ok {
+		return
+	}
+	defer unlock()
+
+	var plan OllamaModelfileDirectoryResource
+	diags := req.Plan.Get(ctx, &plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// the models created before an error are saved as well, so they are
+	// deleted with the resource
+	resp.Diagnostics.Append(r.sync(ctx, &plan, nil)...)
+
+	diags = resp.State.Set(ctx, plan)
+	resp.Diagnostics.Append(diags...)
+}
+
+// Read drops models that were deleted outside of Terraform, so they are created again.
+func (r *ollamaModelfileDirectoryResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state OllamaModelfileDirectoryResource
+	diags := req.State.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	list, err := r.client.List(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to read ollama models, got error: %s", err))
+		return
+	}
+
+	files := map[string]string{}
+	resp.Diagnostics.Append(state.Files.ElementsAs(ctx, &files, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	models := map[string]string{}
+	for name := range files {
+		if m := findModel(list, name); m != nil {
+			models[name] = m.Digest
+		} else {
+			tflog.Debug(ctx, fmt.Sprintf("model %s was deleted outside of terraform", name))
+			delete(files, name)
+		}
+	}
+
+	state.Files, diags = types.MapValueFrom(ctx, types.StringType, files)
+	resp.Diagnostics.Append(diags...)
+	state.Models, diags = types.MapValueFrom(ctx, types.StringType, models)
+	resp.Diagnostics.Append(diags...)
+
+	diags = resp.State.Set(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+}
+
+// Update creates changed models and deletes removed ones.
+func (r *ollamaModelfileDirectoryResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	unlock, ok := lockHost(ctx, r.lock, &resp.Diagnostics)
+	if !ok {
+		return
+	}
+	defer unlock()
+
+	var state OllamaModelfileDirectoryResource
+	diags := req.State.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var plan OllamaModelfileDirectoryResource
+	diags = req.Plan.Get(ctx, &plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	prior := map[string]string{}
+	resp.Diagnostics.Append(state.Files.ElementsAs(ctx, &prior, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(r.sync(ctx, &plan, prior)...)
+
+	diags = resp.State.Set(ctx, plan)
+	resp.Diagnostics.Append(diags...)
+}
+
+// Delete deletes all models created from the directory.
+func (r *ollamaModelfileDirectoryResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	unlock, ok := lockHost(ctx, r.lock, &resp.Diagnostics)
+	if !ok {
+		return
+	}
+	defer unlock()
+
+	var state OllamaModelfileDirectoryResource
+	diags := req.State.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	files := map[string]string{}
+	resp.Diagnostics.Append(state.Files.ElementsAs(ctx, &files, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	for name := range files {
+		r.deleteModel(ctx, name, &resp.Diagnostics)
+	}
+}
+
+// modelfileEntry is a Modelfile found in the directory.
+type modelfileEntry struct {
+	path    string
+	content string
+	sha256  string
+}
+
+// readDirectory returns the Modelfiles of the directory by model name.
+func (r *ollamaModelfileDirectoryResource) readDirectory(m *OllamaModelfileDirectoryResource) (map[string]modelfileEntry, error) {
+	entries, err := os.ReadDir(m.Directory.ValueString())
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[string]modelfileEntry{}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), modelfileSuffix) {
+			continue
+		}
+
+		p := filepath.Join(m.Directory.ValueString(), e.Name())
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(content)
+
+		files[modelfileModelName(m, strings.TrimSuffix(e.Name(), modelfileSuffix))] = modelfileEntry{path: p, content: string(content), sha256: hex.EncodeToString(sum[:])}
+	}
+
+	return files, nil
+}
+
+func modelfileModelName(m *OllamaModelfileDirectoryResource, name string) string {
+	if ns := strings.Trim(m.Namespace.ValueString(), "/"); ns != "" {
+		return ns + "/" + name
+	}
+	return name
+}
+
+// sync creates the models whose file is new or changed since prior, deletes
+// the models whose file was removed and records the resulting digests in plan.
+// It stops at the first model that cannot be created. plan then records the
+// models in effect, with prior hashes for those not synced yet, so the models
+// created so far stay tracked and the rest are retried on the next apply.
+func (r *ollamaModelfileDirectoryResource) sync(ctx context.Context, plan *OllamaModelfileDirectoryResource, prior map[string]string) (diags diag.Diagnostics) {
+	hashes := map[string]string{}
+	for name, sum := range prior {
+		hashes[name] = sum
+	}
+	models := map[string]string{}
+
+	defer func() {
+		var d diag.Diagnostics
+		plan.Files, d = types.MapValueFrom(ctx, types.StringType, hashes)
+		diags.Append(d...)
+		plan.Models, d = types.MapValueFrom(ctx, types.StringType, models)
+		diags.Append(d...)
+	}()
+
+	files, err := r.readDirectory(plan)
+	if err != nil {
+		diags.AddAttributeError(path.Root("directory"), "Error reading Modelfile directory", err.Error())
+		return diags
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f := files[name]
+		if prior[name] == f.sha256 {
+			continue
+		}
+
+		if r.createModel(ctx, name, f, plan.SecurityScan, &diags); diags.HasError() {
+			break
+		}
+		hashes[name] = f.sha256
+	}
+
+	// only models this resource created are deleted, others in the same
+	// namespace are left alone
+	if !diags.HasError() {
+		for name := range prior {
+			if _, ok := files[name]; ok {
+				continue
+			}
+			if r.deleteModel(ctx, name, &diags); !diags.HasError() {
+				delete(hashes, name)
+			}
+		}
+	}
+
+	list, err := r.client.List(ctx)
+	if err != nil {
+		diags.AddError("Client Error", fmt.Sprintf("Unable to read ollama models, got error: %s", err))
+		return diags
+	}
+
+	for name := range hashes {
+		if m := findModel(list, name); m != nil {
+			models[name] = m.Digest
+		}
+	}
+
+	return diags
+}
+
+func (r *ollamaModelfileDirectoryResource) createModel(ctx context.Context, name string, f modelfileEntry, scan *OllamaSecurityScanPolicy, diags *diag.Diagnostics) {
+	mf, err := parseModelfile(f.content)
+	if err != nil {
+		diags.AddError("Error parsing Modelfile", fmt.Sprintf("%s: %s", f.path, err))
+		return
+	}
+
+	if scan != nil {
+		if diags.Append(scanBeforeCreate(mf, filepath.Dir(f.path), scan)...); diags.HasError() {
+			return
+		}
+	}
+
+	if err := r.uploader.UploadModelfileFiles(ctx, mf, filepath.Dir(f.path)); err != nil {
+		diags.AddError("Error uploading model files", fmt.Sprintf("%s: %s", f.path, err))
+		return
+	}
+
+	tflog.Info(ctx, fmt.Sprintf("creating model %s from %s", name, f.path))
+
+	run := r.lineage.Start(ctx, lineageJobCreate, lineageBase(mf, filepath.Dir(f.path)), []lineageDataset{{Name: name}})
+	noStream := false
+	err = r.client.Create(ctx, &api.CreateRequest{
+		Model:     name,
+		Modelfile: mf.String(),
+		Stream:    &noStream,
+	}, PullResponseFn)
+	run.Finish(ctx, err)
+	if err != nil {
+		diags.AddError(
+			"Error creating model",
+			fmt.Sprintf("Could not create model %s from %s, unexpected error: %s", name, f.path, err.Error()),
+		)
+	}
+}
+
+func (r *ollamaModelfileDirectoryResource) deleteModel(ctx context.Context, name string, diags *diag.Diagnostics) {
+	tflog.Info(ctx, fmt.Sprintf("deleting model %s", name))
+
+	err := r.client.Delete(ctx, &api.DeleteRequest{Model: name})
+	if apiErr, ok := err.(api.StatusError); ok && apiErr.StatusCode == 404 {
+		return
+	}
+	if err != nil {
+		diags.AddError(
+			"Error deleting Ollama Model",
+			"Could not delete ollama model "+name+": "+err.Error(),
+		)
+	}
+}
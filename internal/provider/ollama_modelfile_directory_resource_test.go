@@ -0,0 +1,163 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// newTestModelfileDirectory returns a resource syncing a temporary directory
+// to a fake server that has llama3, and the directory.
+func newTestModelfileDirectory(t *testing.T) (*fakeOllama, *ollamaModelfileDirectoryResource, string) {
+	t.Helper()
+
+	f, srv, client := newFakeOllama(t)
+	f.AddModel("llama3")
+
+	base, err := parseHost(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &ollamaModelfileDirectoryResource{client: client, uploader: newBlobUploader(client, base, http.DefaultClient)}
+
+	return f, r, t.TempDir()
+}
+
+func testModelfileDirectory(dir, namespace string) *OllamaModelfileDirectoryResource {
+	return &OllamaModelfileDirectoryResource{
+		Directory: types.StringValue(dir),
+		Namespace: types.StringValue(namespace),
+		Files:     types.MapUnknown(types.StringType),
+		Models:    types.MapUnknown(types.StringType),
+	}
+}
+
+func writeModelfile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name+modelfileSuffix), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// stateModels returns the model names recorded in state, sorted.
+func stateModels(t *testing.T, state *OllamaModelfileDirectoryResource) []string {
+	t.Helper()
+
+	models := map[string]string{}
+	if diags := state.Models.ElementsAs(context.Background(), &models, false); diags.HasError() {
+		t.Fatal(diags)
+	}
+	files := map[string]string{}
+	if diags := state.Files.ElementsAs(context.Background(), &files, false); diags.HasError() {
+		t.Fatal(diags)
+	}
+
+	names := []string{}
+	for name := range files {
+		if models[name] == "" {
+			t.Errorf("model %s has a file hash but no digest in state", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestOllamaModelfileDirectoryResourceSync(t *testing.T) {
+	f, r, dir := newTestModelfileDirectory(t)
+	writeModelfile(t, dir, "reviewer", "FROM llama3\nSYSTEM Review the code.\n")
+	writeModelfile(t, dir, "summarizer", "FROM llama3\nSYSTEM Summarize.\n")
+
+	// a model pulled into the namespace by something else
+	f.AddModel("team/manual")
+
+	var state OllamaModelfileDirectoryResource
+	if diags := applyResource(t, r, nil, testModelfileDirectory(dir, "team"), &state); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if got, want := stateModels(t, &state), []string{"team/reviewer", "team/summarizer"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("models in state after create = %v, want %v", got, want)
+	}
+	if got := f.Calls("/api/create"); got != 2 {
+		t.Errorf("created %d models, want 2", got)
+	}
+
+	t.Run("change re-creates only the changed model", func(t *testing.T) {
+		digests := state.Models
+		writeModelfile(t, dir, "reviewer", "FROM llama3\nSYSTEM Review the code strictly.\n")
+
+		if diags := applyResource(t, r, &state, testModelfileDirectory(dir, "team"), &state); diags.HasError() {
+			t.Fatal(diags)
+		}
+		if got := f.Calls("/api/create"); got != 3 {
+			t.Errorf("created %d models in total, want 3", got)
+		}
+		if state.Models.Equal(digests) {
+			t.Error("digests in state did not change with the Modelfile")
+		}
+	})
+
+	t.Run("removed file deletes its model", func(t *testing.T) {
+		if err := os.Remove(filepath.Join(dir, "summarizer"+modelfileSuffix)); err != nil {
+			t.Fatal(err)
+		}
+
+		if diags := applyResource(t, r, &state, testModelfileDirectory(dir, "team"), &state); diags.HasError() {
+			t.Fatal(diags)
+		}
+		if got, want := stateModels(t, &state), []string{"team/reviewer"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("models in state = %v, want %v", got, want)
+		}
+		if f.Has("team/summarizer") {
+			t.Error("model of the removed file is still on the server")
+		}
+	})
+
+	t.Run("models of the namespace not created by the resource are kept", func(t *testing.T) {
+		if !f.Has("team/manual") {
+			t.Errorf("team/manual was deleted, models are %v", f.Models())
+		}
+	})
+
+	t.Run("delete removes the created models only", func(t *testing.T) {
+		if diags := applyResource(t, r, &state, nil, &state); diags.HasError() {
+			t.Fatal(diags)
+		}
+		if got, want := f.Models(), []string{"llama3:latest", "team/manual:latest"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("models after delete = %v, want %v", got, want)
+		}
+	})
+}
+
+func TestOllamaModelfileDirectoryResourcePartialFailure(t *testing.T) {
+	f, r, dir := newTestModelfileDirectory(t)
+	writeModelfile(t, dir, "a", "FROM llama3\n")
+	writeModelfile(t, dir, "b", "FROM missing\n")
+	writeModelfile(t, dir, "c", "FROM llama3\n")
+
+	var state OllamaModelfileDirectoryResource
+	if diags := applyResource(t, r, nil, testModelfileDirectory(dir, ""), &state); !diags.HasError() {
+		t.Fatal("create succeeded although b has a missing base model")
+	}
+	if got, want := stateModels(t, &state), []string{"a"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("models in state after the failed create = %v, want %v", got, want)
+	}
+
+	// the rest is created once the base model is there
+	f.AddModel("missing")
+	if diags := applyResource(t, r, &state, testModelfileDirectory(dir, ""), &state); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if got, want := stateModels(t, &state), []string{"a", "b", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("models in state = %v, want %v", got, want)
+	}
+	if got := f.Calls("/api/create"); got != 4 {
+		t.Errorf("created %d models in total, want 4 with a created once", got)
+	}
+}
@@ -0,0 +1,163 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &OllamaSecurityScanDataSource{}
+
+func NewOllamaSecurityScanDataSource() datasource.DataSource {
+	return &OllamaSecurityScanDataSource{}
+}
+
+// OllamaSecurityScanDataSource scans model artifacts before they are promoted.
+type OllamaSecurityScanDataSource struct{}
+
+// OllamaSecurityScanDataSourceModel describes the data source data model.
+type OllamaSecurityScanDataSourceModel struct {
+	Path              types.String        `tfsdk:"path"`
+	Template          types.String        `tfsdk:"template"`
+	SeverityThreshold types.String        `tfsdk:"severity_threshold"`
+	MetadataAllowlist []types.String      `tfsdk:"metadata_allowlist"`
+	Enforce           types.Bool          `tfsdk:"enforce"`
+	Findings          []OllamaScanFinding `tfsdk:"findings"`
+	MaxSeverity       types.String        `tfsdk:"max_severity"`
+	Passed            types.Bool          `tfsdk:"passed"`
+}
+
+func (d *OllamaSecurityScanDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_security_scan"
+}
+
+func (d *OllamaSecurityScanDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Scans a GGUF file or a safetensors directory and an optional prompt template for suspicious template constructs, " +
+			"metadata outside an allowlist and file types that can execute code.",
+
+		Attributes: map[string]schema.Attribute{
+			"path": schema.StringAttribute{
+				Description: "A GGUF file or a safetensors model directory.",
+				Optional:    true,
+			},
+			"template": schema.StringAttribute{
+				Description: "A prompt template to scan, e.g. the template of a custom model.",
+				Optional:    true,
+			},
+			"severity_threshold": schema.StringAttribute{
+				Description: "The lowest severity that fails the scan, one of `low`, `medium`, `high` or `critical`. Defaults to `high`.",
+				Optional:    true,
+			},
+			"metadata_allowlist": schema.ListAttribute{
+				Description: "Glob patterns of allowed metadata keys, replacing the default of `general.*`, `tokenizer.*`, `split.*`, `quantize.*`, " +
+					"`format` and, for GGUF files, `<architecture>.*`.",
+				Optional:    true,
+				ElementType: types.StringType,
+			},
+			"enforce": schema.BoolAttribute{
+				Description: "Fail the read when the scan does not pass, so nothing depending on the data source is applied.",
+				Optional:    true,
+			},
+			"findings": schema.ListNestedAttribute{
+				Description: "All findings, including those below the threshold.",
+				Computed:    true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"severity": schema.StringAttribute{
+							Description: "One of `low`, `medium`, `high` or `critical`.",
+							Computed:    true,
+						},
+						"rule": schema.StringAttribute{
+							Description: "The rule that matched, e.g. `unsafe-file` or `prompt-injection`.",
+							Computed:    true,
+						},
+						"location": schema.StringAttribute{
+							Description: "The file, metadata key or template the finding is about.",
+							Computed:    true,
+						},
+						"message": schema.StringAttribute{
+							Description: "What was found.",
+							Computed:    true,
+						},
+					},
+				},
+			},
+			"max_severity": schema.StringAttribute{
+				Description: "The highest severity found, empty if there are no findings.",
+				Computed:    true,
+			},
+			"passed": schema.BoolAttribute{
+				Description: "Whether no finding reaches the severity threshold.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+func (d *OllamaSecurityScanDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data OllamaSecurityScanDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if data.Path.IsNull() && data.Template.IsNull() {
+		resp.Diagnostics.AddError("Missing scan input", "At least one of path or template must be set.")
+		return
+	}
+
+	policy, err := newScanPolicy(&OllamaSecurityScanPolicy{
+		SeverityThreshold: data.SeverityThreshold,
+		MetadataAllowlist: data.MetadataAllowlist,
+	})
+	if err != nil {
+		resp.Diagnostics.AddError("Invalid scan policy", err.Error())
+		return
+	}
+
+	var findings []scanFinding
+	if !data.Path.IsNull() {
+		findings, err = scanPath(data.Path.ValueString(), policy)
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("path"), "Error scanning model artifacts", err.Error())
+			return
+		}
+	}
+	if !data.Template.IsNull() {
+		findings = append(findings, scanTemplate("template", data.Template.ValueString())...)
+	}
+
+	data.Findings = []OllamaScanFinding{}
+	passed := true
+	for _, f := range findings {
+		data.Findings = append(data.Findings, OllamaScanFinding{
+			Severity: types.StringValue(f.Severity),
+			Rule:     types.StringValue(f.Rule),
+			Location: types.StringValue(f.Location),
+			Message:  types.StringValue(f.Message),
+		})
+		if policy.Blocks(f) {
+			passed = false
+		}
+	}
+	data.MaxSeverity = types.StringValue(maxSeverity(findings))
+	data.Passed = types.BoolValue(passed)
+
+	if !passed && data.Enforce.ValueBool() {
+		reportScanFindings(findings, policy, &resp.Diagnostics)
+		resp.Diagnostics.AddError("Security scan failed", fmt.Sprintf("Findings reach the severity threshold %s.", policy.Threshold))
+		return
+	}
+
+	diags := resp.State.Set(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+}
@@ -0,0 +1,225 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/ollama/ollama/api"
+)
+
+// poolHost is one Ollama host of a hostPool. Its name is the host as
+// configured.
+type poolHost struct {
+	name   string
+	client *api.Client
+	// cacheHost is the host part of response cache keys: the sorted URLs of
+	// all hosts of the pool. Hosts of a pool serve the same digest and share
+	// cache entries, but pools of other hosts do not.
+	cacheHost string
+}
+
+// hostPool distributes inference work across hosts that serve the same model.
+// Every host runs concurrency items at a time. An item that fails on one host
+// is retried on the others and only fails once every host has failed it.
+type hostPool struct {
+	hosts       []poolHost
+	concurrency int
+}
+
+// newHostPool returns a pool of the hosts in a hosts attribute, or of the
+// provider host alone if the attribute is null. An empty list is an error.
+func newHostPool(ctx context.Context, hosts types.List, client *api.Client, host string, concurrency types.Int64) (*hostPool, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	pool := &hostPool{concurrency: max(int(concurrency.ValueInt64()), 1)}
+
+	if hosts.IsNull() {
+		// keyed like a hosts list with only the provider host
+		cacheHost := host
+		if base, err := parseHost(host); err == nil {
+			cacheHost = base.String()
+		}
+		pool.hosts = []poolHost{{name: host, client: client, cacheHost: cacheHost}}
+		return pool, diags
+	}
+
+	var names []string
+	if diags.Append(hosts.ElementsAs(ctx, &names, false)...); diags.HasError() {
+		return nil, diags
+	}
+	if len(names) == 0 {
+		diags.AddAttributeError(path.Root("hosts"), "Invalid hosts", "hosts must list at least one host. Leave it unset to use the provider host.")
+		return nil, diags
+	}
+
+	var urls []string
+	for _, name := range names {
+		if slices.ContainsFunc(pool.hosts, func(h poolHost) bool { return h.name == name }) {
+			continue
+		}
+
+		c, base, err := NewClient(name)
+		if err != nil {
+			diags.AddAttributeError(path.Root("hosts"), "Invalid host", err.Error())
+			return nil, diags
+		}
+		pool.hosts = append(pool.hosts, poolHost{name: name, client: c})
+		urls = append(urls, base.String())
+	}
+
+	slices.Sort(urls)
+	cacheHost := strings.Join(slices.Compact(urls), ",")
+	for i := range pool.hosts {
+		pool.hosts[i].cacheHost = cacheHost
+	}
+
+	return pool, diags
+}
+
+// CheckAdvisories runs the advisory check of db on every host of the pool.
+func (p *hostPool) CheckAdvisories(ctx context.Context, db *advisoryDatabase, diags *diag.Diagnostics) {
+	for _, h := range p.hosts {
+		db.CheckHost(ctx, h.client, h.name, diags)
+	}
+}
+
+// Primary returns the client of the first host, which is used for lookups
+// that need a single host, like planning the model digest.
+func (p *hostPool) Primary() *api.Client {
+	return p.hosts[0].client
+}
+
+// ModelDigest looks up model on every host and returns its digest. Hosts must
+// serve identical weights, otherwise results would depend on which host an
+// item was run on.
+func (p *hostPool) ModelDigest(ctx context.Context, model string) (string, error) {
+	var digest string
+	for _, h := range p.hosts {
+		listed, err := lookupModel(ctx, h.client, model)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", h.name, err)
+		}
+		if listed == nil {
+			return "", fmt.Errorf("model %s is not available on %s", model, h.name)
+		}
+
+		if digest == "" {
+			digest = listed.Digest
+		} else if normalizeDigest(listed.Digest) != normalizeDigest(digest) {
+			return "", fmt.Errorf("model %s has digest %s on %s but %s on %s", model, listed.Digest, h.name, digest, p.hosts[0].name)
+		}
+	}
+	return digest, nil
+}
+
+// Run calls fn for the items 0 to n-1 on the hosts of the pool and returns the
+// error of the first item that failed on every host, or that exceeded the
+// inference budget. fn stores its result by
+// item index, so results are merged in the same order however the items were
+// spread across hosts.
+func (p *hostPool) Run(ctx context.Context, n int, fn func(ctx context.Context, h poolHost, i int) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		cond     = sync.NewCond(&mu)
+		pending  = make([]int, n)
+		head     int
+		tried    = make(map[int][]int)
+		running  int
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	for i := range pending {
+		pending[i] = i
+	}
+
+	// wake up waiting workers when the run is cancelled
+	go func() {
+		<-ctx.Done()
+		mu.Lock()
+		cond.Broadcast()
+		mu.Unlock()
+	}()
+
+	// next takes the first pending item host h has not failed yet. It waits
+	// while other hosts may still return items, and returns false once there
+	// is nothing left for h.
+	next := func(h int) (int, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		for {
+			if firstErr != nil || ctx.Err() != nil {
+				return 0, false
+			}
+			for k := head; k < len(pending); k++ {
+				i := pending[k]
+				if slices.Contains(tried[i], h) {
+					continue
+				}
+				if k == head {
+					head++
+				} else {
+					pending = slices.Delete(pending, k, k+1)
+				}
+				running++
+				return i, true
+			}
+			if head == len(pending) && running == 0 {
+				return 0, false
+			}
+			cond.Wait()
+		}
+	}
+
+	for h, host := range p.hosts {
+		for w := 0; w < p.concurrency; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					i, ok := next(h)
+					if !ok {
+						return
+					}
+
+					err := fn(ctx, host, i)
+
+					mu.Lock()
+					running--
+					if err != nil && ctx.Err() == nil {
+						tried[i] = append(tried[i], h)
+						// the budget is shared by all hosts, so another host
+						// would fail the same way
+						retry := len(tried[i]) < len(p.hosts) && !errors.As(err, new(*errBudgetExceeded))
+						if retry {
+							tflog.Warn(ctx, fmt.Sprintf("%s failed, retrying on another host: %s", host.name, err))
+							pending = append(pending, i)
+						} else if firstErr == nil {
+							firstErr = err
+							cancel()
+						}
+					}
+					cond.Broadcast()
+					mu.Unlock()
+				}
+			}()
+		}
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
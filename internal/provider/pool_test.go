@@ -0,0 +1,200 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestNewHostPool(t *testing.T) {
+	const providerHost = "http://127.0.0.1:11434"
+	hostList := func(hosts ...string) types.List {
+		// a nil slice would be a null list
+		l, diags := types.ListValueFrom(context.Background(), types.StringType, append([]string{}, hosts...))
+		if diags.HasError() {
+			t.Fatal(diags)
+		}
+		return l
+	}
+
+	tests := map[string]struct {
+		hosts      types.List
+		provider   string
+		wantHosts  []string
+		wantCaches []string
+		wantError  bool
+	}{
+		"null uses the provider host": {
+			hosts:      types.ListNull(types.StringType),
+			wantHosts:  []string{providerHost},
+			wantCaches: []string{providerHost},
+		},
+		"empty list": {
+			hosts:     hostList(),
+			wantError: true,
+		},
+		"provider host without scheme": {
+			hosts:      types.ListNull(types.StringType),
+			provider:   "127.0.0.1",
+			wantHosts:  []string{"127.0.0.1"},
+			wantCaches: []string{providerHost},
+		},
+		"duplicates are dropped": {
+			hosts:      hostList("http://a:11434", "http://b:11434", "http://a:11434"),
+			wantHosts:  []string{"http://a:11434", "http://b:11434"},
+			wantCaches: []string{"http://a:11434,http://b:11434", "http://a:11434,http://b:11434"},
+		},
+		"order does not change the cache key": {
+			hosts:      hostList("http://b:11434", "a"),
+			wantHosts:  []string{"http://b:11434", "a"},
+			wantCaches: []string{"http://a:11434,http://b:11434", "http://a:11434,http://b:11434"},
+		},
+		"spellings of a host share the cache key": {
+			hosts:      hostList("a", "http://a:11434"),
+			wantHosts:  []string{"a", "http://a:11434"},
+			wantCaches: []string{"http://a:11434", "http://a:11434"},
+		},
+		// the same key as a null list
+		"provider host only": {
+			hosts:      hostList(providerHost),
+			wantHosts:  []string{providerHost},
+			wantCaches: []string{providerHost},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			provider := providerHost
+			if tt.provider != "" {
+				provider = tt.provider
+			}
+			pool, diags := newHostPool(context.Background(), tt.hosts, nil, provider, types.Int64Value(2))
+			if diags.HasError() != tt.wantError {
+				t.Fatalf("newHostPool() diagnostics = %v, want error %v", diags, tt.wantError)
+			}
+			if tt.wantError {
+				return
+			}
+
+			if len(pool.hosts) != len(tt.wantHosts) {
+				t.Fatalf("pool has %d hosts, want %v", len(pool.hosts), tt.wantHosts)
+			}
+			for i, h := range pool.hosts {
+				if h.name != tt.wantHosts[i] || h.cacheHost != tt.wantCaches[i] {
+					t.Errorf("host %d = %q with cache host %q, want %q with %q", i, h.name, h.cacheHost, tt.wantHosts[i], tt.wantCaches[i])
+				}
+			}
+		})
+	}
+}
+
+func TestHostPoolSharesCache(t *testing.T) {
+	a, srvA, _ := newFakeOllama(t)
+	b, srvB, _ := newFakeOllama(t)
+	a.AddModel("nomic-embed-text")
+	b.AddModel("nomic-embed-text")
+
+	hosts, diags := types.ListValueFrom(context.Background(), types.StringType, []string{srvA.URL, srvB.URL})
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	pool, diags := newHostPool(context.Background(), hosts, nil, "", types.Int64Value(1))
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	cache, err := newResponseCache(t.TempDir(), time.Hour, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := &embedder{pool: pool, cache: cache, model: "nomic-embed-text", digest: "sha256:1234"}
+	for _, h := range pool.hosts {
+		if _, err := e.embed(context.Background(), h, "cats"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := a.Calls("/api/embeddings") + b.Calls("/api/embeddings"); got != 1 {
+		t.Errorf("embedded %d times, want 1 with the second host served from the cache", got)
+	}
+
+	// a pool of other hosts keeps its own entries
+	c, srvC, _ := newFakeOllama(t)
+	c.AddModel("nomic-embed-text")
+	other, diags := newHostPool(context.Background(), types.ListValueMust(types.StringType, []attr.Value{types.StringValue(srvC.URL)}), nil, "", types.Int64Value(1))
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	e.pool = other
+	if _, err := e.embed(context.Background(), other.hosts[0], "cats"); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Calls("/api/embeddings"); got != 1 {
+		t.Errorf("embedded %d times on a pool of other hosts, want 1 instead of a cache hit", got)
+	}
+}
+
+func TestHostPoolCheckAdvisories(t *testing.T) {
+	_, provider, _ := newFakeOllama(t)
+	vulnerable, srvA, _ := newFakeOllama(t)
+	vulnerable.Version = "0.1.33"
+	_, srvB, _ := newFakeOllama(t)
+
+	db := writeAdvisories(t, testAdvisories, severityCritical, provider.URL)
+	ctx := context.Background()
+
+	hosts, diags := types.ListValueFrom(ctx, types.StringType, []string{srvA.URL, srvB.URL})
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	pool, diags := newHostPool(ctx, hosts, nil, provider.URL, types.Int64Value(1))
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+
+	diags = nil
+	db.Check(ctx, &diags)
+	if len(diags) > 0 {
+		t.Fatalf("Check() of the provider host = %v, want no diagnostics", diags)
+	}
+
+	pool.CheckAdvisories(ctx, db, &diags)
+	if diags.ErrorsCount() != 1 {
+		t.Errorf("CheckAdvisories() = %v, want one error for the vulnerable pool host", diags)
+	}
+
+	var nilDB *advisoryDatabase
+	diags = diag.Diagnostics{}
+	pool.CheckAdvisories(ctx, nilDB, &diags)
+	if len(diags) > 0 {
+		t.Errorf("CheckAdvisories() without a database = %v, want no diagnostics", diags)
+	}
+}
+
+func TestHostPoolRunBudgetExceeded(t *testing.T) {
+	pool := &hostPool{hosts: []poolHost{{name: "a"}, {name: "b"}, {name: "c"}}, concurrency: 1}
+
+	var (
+		mu    sync.Mutex
+		calls int
+	)
+	err := pool.Run(context.Background(), 1, func(ctx context.Context, h poolHost, i int) error {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		return &errBudgetExceeded{reason: "max_requests of 0 reached"}
+	})
+
+	if !errors.As(err, new(*errBudgetExceeded)) {
+		t.Errorf("Run() = %v, want errBudgetExceeded", err)
+	}
+	if calls != 1 {
+		t.Errorf("item ran %d times, want 1 without retrying on other hosts", calls)
+	}
+}
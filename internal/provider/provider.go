@@ -5,15 +5,23 @@ package provider
 
 import (
 	"context"
+	"fmt"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/ollama/ollama/api"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/hashicorp/terraform-plugin-framework-validators/float64validator"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
 	"github.com/hashicorp/terraform-plugin-framework/function"
 	"github.com/hashicorp/terraform-plugin-framework/provider"
 	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	_ "github.com/ollama/ollama/api"
 )
@@ -32,7 +40,92 @@ type OllamaProvider struct {
 
 // OllamaProviderModel describes the provider data model.
 type OllamaProviderModel struct {
-	Host types.String `tfsdk:"host"`
+	Host          types.String                      `tfsdk:"host"`
+	Cache         *OllamaProviderCacheModel         `tfsdk:"cache"`
+	EOLFile       types.String                      `tfsdk:"eol_file"`
+	Lock          *OllamaProviderLockModel          `tfsdk:"lock"`
+	Budget        *OllamaProviderBudgetModel        `tfsdk:"budget"`
+	Lineage       *OllamaProviderLineageModel       `tfsdk:"lineage"`
+	LicensePolicy *OllamaProviderLicensePolicyModel `tfsdk:"license_policy"`
+	Advisories    *OllamaProviderAdvisoriesModel    `tfsdk:"advisories"`
+}
+
+// OllamaProviderCacheModel describes the response cache configuration.
+type OllamaProviderCacheModel struct {
+	Directory types.String `tfsdk:"directory"`
+	TTL       types.String `tfsdk:"ttl"`
+	MaxSize   types.Int64  `tfsdk:"max_size"`
+}
+
+// OllamaProviderLockModel describes the advisory host lock configuration.
+type OllamaProviderLockModel struct {
+	Backend     types.String `tfsdk:"backend"`
+	Directory   types.String `tfsdk:"directory"`
+	MarkerModel types.String `tfsdk:"marker_model"`
+	MarkerBase  types.String `tfsdk:"marker_base"`
+	Holder      types.String `tfsdk:"holder"`
+	Timeout     types.String `tfsdk:"timeout"`
+	StaleAfter  types.String `tfsdk:"stale_after"`
+}
+
+// OllamaProviderBudgetModel describes the inference budget of a run.
+type OllamaProviderBudgetModel struct {
+	MaxRequests        types.Int64  `tfsdk:"max_requests"`
+	MaxPredictedTokens types.Int64  `tfsdk:"max_predicted_tokens"`
+	MaxWallTime        types.String `tfsdk:"max_wall_time"`
+}
+
+// OllamaProviderLineageModel describes where OpenLineage events are sent.
+type OllamaProviderLineageModel struct {
+	URL       types.String `tfsdk:"url"`
+	APIKey    types.String `tfsdk:"api_key"`
+	File      types.String `tfsdk:"file"`
+	Namespace types.String `tfsdk:"namespace"`
+}
+
+// OllamaProviderLicensePolicyModel describes the licenses models may be pulled under.
+type OllamaProviderLicensePolicyModel struct {
+	Allow         []types.String `tfsdk:"allow"`
+	Deny          []types.String `tfsdk:"deny"`
+	MinConfidence types.Float64  `tfsdk:"min_confidence"`
+}
+
+// OllamaProviderAdvisoriesModel describes the server advisory check.
+type OllamaProviderAdvisoriesModel struct {
+	File          types.String `tfsdk:"file"`
+	ErrorSeverity types.String `tfsdk:"error_severity"`
+}
+
+// OllamaProviderData is handed to resources and data sources on Configure.
+type OllamaProviderData struct {
+	Client *api.Client
+	Host   string
+
+	// Base and HTTP are what Client was built from, for endpoints the api
+	// package does not cover.
+	Base *url.URL
+	HTTP *http.Client
+
+	// Cache is nil unless response caching is configured.
+	Cache *responseCache
+
+	// EOL is nil unless an end-of-life metadata file is configured.
+	EOL *eolPolicy
+
+	// Lock is nil unless host locking is configured.
+	Lock *hostLock
+
+	// Budget is nil unless an inference budget is configured.
+	Budget *inferenceBudget
+
+	// Lineage is nil unless OpenLineage events are configured.
+	Lineage *lineageEmitter
+
+	// LicensePolicy is nil unless a license policy is configured.
+	LicensePolicy *licensePolicy
+
+	// Advisories is nil unless a server advisory database is configured.
+	Advisories *advisoryDatabase
 }
 
 func (p *OllamaProvider) Metadata(ctx context.Context, req provider.MetadataRequest, resp *provider.MetadataResponse) {
@@ -44,9 +137,152 @@ func (p *OllamaProvider) Schema(ctx context.Context, req provider.SchemaRequest,
 	resp.Schema = schema.Schema{
 		Attributes: map[string]schema.Attribute{
 			"host": schema.StringAttribute{
-				Description: "Ollama host",
+				Description: "Ollama host, such as `http://localhost:11434`. A path is kept, so Ollama can be served below a prefix such as `https://ai.example.com/ollama/` behind a reverse proxy.",
 				Required:    true,
 			},
+			"cache": schema.SingleNestedAttribute{
+				Description: "On-disk cache for the inference responses of `ollama_batch_inference` and `ollama_embedding_evaluation`. No other resource and no data source uses it: `ollama_model` with `verify_load` and `ollama_determinism_check` always send their requests to the server. Unchanged requests against the same model digest are served from the cache instead of the server. Entries are keyed by the host set: the `hosts` of a resource share them, whichever host answered, and a resource without `hosts` shares them with resources whose `hosts` list only the provider host. Resources with other host sets do not share entries.",
+				Optional:    true,
+				Attributes: map[string]schema.Attribute{
+					"directory": schema.StringAttribute{
+						Description: "Directory the cache entries are stored in.",
+						Required:    true,
+					},
+					"ttl": schema.StringAttribute{
+						Description: "How long a cached response stays valid, as a Go duration such as `24h`. Defaults to `24h`.",
+						Optional:    true,
+					},
+					"max_size": schema.Int64Attribute{
+						Description: "Maximum size of the cache in bytes. The oldest entries are evicted first. Defaults to 512 MiB.",
+						Optional:    true,
+					},
+				},
+			},
+			"eol_file": schema.StringAttribute{
+				Description: "JSON file mapping model name patterns to an `eol_date` (YYYY-MM-DD) and an optional `replacement` and `reason`. " +
+					"Resources referencing a matching model get a plan warning before that date and an error from that date on.",
+				Optional: true,
+			},
+			"lock": schema.SingleNestedAttribute{
+				Description: "Advisory lock on the host, held while models are pulled, created, deleted or exported and while batch inference runs, so concurrent Terraform runs against the same host do not work on it at the same time. " +
+					"The lock is released between operations, so operations of two runs can still alternate.",
+				Optional: true,
+				Attributes: map[string]schema.Attribute{
+					"backend": schema.StringAttribute{
+						Description: "Where the lock is kept: `file` for a local lock file, which covers runs on one machine, or `host` for a marker model on the Ollama host. " +
+							"The `host` backend is best effort only: the API cannot create the marker atomically, so runs starting at the same moment can both get the lock. Defaults to `file`.",
+						Optional: true,
+					},
+					"directory": schema.StringAttribute{
+						Description: "Directory of the lock files for the `file` backend. Defaults to a directory below the system temporary directory.",
+						Optional:    true,
+					},
+					"marker_model": schema.StringAttribute{
+						Description: "Name of the marker model for the `host` backend. Defaults to `terraform-provider-ollama-lock:latest`.",
+						Optional:    true,
+					},
+					"marker_base": schema.StringAttribute{
+						Description: "A model present on the host that the marker model is created from, required for the `host` backend. The marker shares its layers, so a small model costs no extra space.",
+						Optional:    true,
+					},
+					"holder": schema.StringAttribute{
+						Description: "How this run is named to runs waiting for the lock, e.g. a CI job URL. Defaults to user, hostname and process ID.",
+						Optional:    true,
+					},
+					"timeout": schema.StringAttribute{
+						Description: "How long to wait for the lock, as a Go duration. Defaults to `5m`.",
+						Optional:    true,
+					},
+					"stale_after": schema.StringAttribute{
+						Description: "How long a lock may go without being refreshed before it is considered abandoned and taken over, as a Go duration. The holder refreshes it every quarter of this while it holds it, so runs sharing a lock should use the same value. Defaults to `1h`.",
+						Optional:    true,
+					},
+				},
+			},
+			"budget": schema.SingleNestedAttribute{
+				Description: "Limits for the inference calls of a single plan or apply, across all resources and data sources. " +
+					"Calls made after a limit is reached fail. Responses served from the cache do not count. The usage of the run so far is logged at `INFO` level after each operation that made inference calls.",
+				Optional: true,
+				Attributes: map[string]schema.Attribute{
+					"max_requests": schema.Int64Attribute{
+						Description: "Maximum number of generate, chat and embedding requests.",
+						Optional:    true,
+					},
+					"max_predicted_tokens": schema.Int64Attribute{
+						Description: "Maximum number of tokens predicted over all requests. Requests already running when the limit is reached still finish.",
+						Optional:    true,
+					},
+					"max_wall_time": schema.StringAttribute{
+						Description: "Maximum time after which no new requests are started, as a Go duration such as `30m`.",
+						Optional:    true,
+					},
+				},
+			},
+			"lineage": schema.SingleNestedAttribute{
+				Description: "Emit OpenLineage run events when models are pulled, created or copied to snapshots. Models are datasets named after the model, " +
+					"in a namespace identifying the host such as `ollama://localhost:11434`, with the digest as dataset version. " +
+					"Events that cannot be sent are logged and do not fail the apply. Pushes are not reported, the provider does not push models.",
+				Optional: true,
+				Attributes: map[string]schema.Attribute{
+					"url": schema.StringAttribute{
+						Description: "OpenLineage HTTP endpoint the events are posted to, such as `http://marquez:5000/api/v1/lineage`. Conflicts with `file`.",
+						Optional:    true,
+					},
+					"api_key": schema.StringAttribute{
+						Description: "Bearer token sent to the HTTP endpoint.",
+						Optional:    true,
+						Sensitive:   true,
+					},
+					"file": schema.StringAttribute{
+						Description: "File the events are appended to, one JSON document per line. Conflicts with `url`.",
+						Optional:    true,
+					},
+					"namespace": schema.StringAttribute{
+						Description: "Namespace of the jobs. Defaults to `terraform-provider-ollama`.",
+						Optional:    true,
+					},
+				},
+			},
+			"license_policy": schema.SingleNestedAttribute{
+				Description: "Licenses models may be pulled under. The license of a pulled model is classified to an SPDX identifier, " +
+					"and a model whose license violates the policy fails the apply. It is deleted again unless it was on the host before the apply.",
+				Optional: true,
+				Attributes: map[string]schema.Attribute{
+					"allow": schema.ListAttribute{
+						Description: "SPDX identifiers of the allowed licenses, e.g. `Apache-2.0` or `LicenseRef-Llama-3.1-Community`. All licenses that are not denied are allowed if empty.",
+						Optional:    true,
+						ElementType: types.StringType,
+					},
+					"deny": schema.ListAttribute{
+						Description: "SPDX identifiers of denied licenses, e.g. `CC-BY-NC-4.0`. Include `NOASSERTION` to deny licenses that could not be classified.",
+						Optional:    true,
+						ElementType: types.StringType,
+					},
+					"min_confidence": schema.Float64Attribute{
+						Description: "The lowest classification confidence, from 0 to 1, that is accepted. Defaults to 0.",
+						Optional:    true,
+						Validators: []validator.Float64{
+							float64validator.Between(0, 1),
+						},
+					},
+				},
+			},
+			"advisories": schema.SingleNestedAttribute{
+				Description: "Check the version of the Ollama server against known vulnerabilities when resources on the host are planned or the `ollama_model` data source is read. " +
+					"The `hosts` of `ollama_batch_inference` and `ollama_embedding_evaluation`, the hosts that `ollama_determinism_check` runs on and the healthy hosts found by `ollama_hosts` are checked as well.",
+				Optional: true,
+				Attributes: map[string]schema.Attribute{
+					"file": schema.StringAttribute{
+						Description: "JSON advisory database with a list of `advisories`, each with an `id`, a `severity` of `low`, `medium`, `high` or `critical`, " +
+							"a `description` and the affected version range from `introduced` (inclusive) to `fixed` (exclusive). Either bound may be left out.",
+						Required: true,
+					},
+					"error_severity": schema.StringAttribute{
+						Description: "The lowest severity that fails the plan. Advisories below it are warnings. Defaults to `critical`.",
+						Optional:    true,
+					},
+				},
+			},
 		},
 	}
 }
@@ -89,18 +325,15 @@ func (p *OllamaProvider) Configure(ctx context.Context, req provider.ConfigureRe
 		return
 	}
 
-	os.Setenv("OLLAMA_HOST", host) // TODO change this when ollama sdk changes to not just use 'from env'
-
-	// Example client configuration for config sources and resources
-	client, err := api.ClientFromEnvironment()
+	client, base, err := NewClient(host)
 
 	if err != nil {
 		resp.Diagnostics.AddAttributeError(
 			path.Root("host"),
 			"Error creating ollama client",
-			"The provider cannot create the ollama API client as there is a missing or empty value for the OLLAMA_HOST or ollama host. "+
-				"Set the host value in the configuration or use the OLLAMA_HOST environment variable. "+
-				"If either is already set, ensure the value is not empty or broken.",
+			fmt.Sprintf("The provider cannot create the ollama API client: %s. "+
+				"Set the host value in the configuration or use the OLLAMA_HOST environment variable, "+
+				"such as http://localhost:11434.", err),
 		)
 	}
 
@@ -108,19 +341,128 @@ func (p *OllamaProvider) Configure(ctx context.Context, req provider.ConfigureRe
 		return
 	}
 
-	resp.DataSourceData = client
-	resp.ResourceData = client
+	data := &OllamaProviderData{
+		Client: client,
+		Host:   host,
+		Base:   base,
+		HTTP:   http.DefaultClient,
+	}
+
+	if config.Cache != nil {
+		ttl := defaultCacheTTL
+		if !config.Cache.TTL.IsNull() {
+			ttl, err = time.ParseDuration(config.Cache.TTL.ValueString())
+			if err != nil {
+				resp.Diagnostics.AddAttributeError(
+					path.Root("cache").AtName("ttl"),
+					"Invalid cache TTL",
+					fmt.Sprintf("The cache TTL must be a duration such as \"24h\": %s", err),
+				)
+				return
+			}
+		}
+
+		maxSize := int64(defaultCacheMaxSize)
+		if !config.Cache.MaxSize.IsNull() {
+			maxSize = config.Cache.MaxSize.ValueInt64()
+		}
+
+		data.Cache, err = newResponseCache(config.Cache.Directory.ValueString(), ttl, maxSize)
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("cache").AtName("directory"),
+				"Error creating response cache",
+				fmt.Sprintf("Could not create the response cache directory: %s", err),
+			)
+			return
+		}
+	}
+
+	if !config.EOLFile.IsNull() {
+		data.EOL, err = loadEOLPolicy(config.EOLFile.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("eol_file"),
+				"Error reading end-of-life metadata",
+				fmt.Sprintf("Could not read the end-of-life metadata file: %s", err),
+			)
+			return
+		}
+	}
+
+	if config.Budget != nil {
+		wallTime, err := optionalDuration(config.Budget.MaxWallTime.ValueString(), 0)
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("budget").AtName("max_wall_time"),
+				"Invalid inference budget",
+				fmt.Sprintf("The wall time must be a duration such as \"30m\": %s", err),
+			)
+			return
+		}
+		data.Budget = newInferenceBudget(config.Budget.MaxRequests.ValueInt64(), config.Budget.MaxPredictedTokens.ValueInt64(), wallTime)
+	}
+
+	if config.Lock != nil {
+		data.Lock, err = configureHostLock(config.Lock, client, host)
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("lock"), "Invalid lock configuration", err.Error())
+			return
+		}
+	}
+
+	if config.Lineage != nil {
+		data.Lineage, err = configureLineage(config.Lineage, client, base, data.HTTP, p.version)
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("lineage"), "Invalid lineage configuration", err.Error())
+			return
+		}
+	}
+
+	if config.LicensePolicy != nil {
+		data.LicensePolicy = &licensePolicy{MinConfidence: config.LicensePolicy.MinConfidence.ValueFloat64()}
+		for _, id := range config.LicensePolicy.Allow {
+			data.LicensePolicy.Allow = append(data.LicensePolicy.Allow, id.ValueString())
+		}
+		for _, id := range config.LicensePolicy.Deny {
+			data.LicensePolicy.Deny = append(data.LicensePolicy.Deny, id.ValueString())
+		}
+	}
+
+	if config.Advisories != nil {
+		data.Advisories, err = loadAdvisoryDatabase(config.Advisories.File.ValueString(), config.Advisories.ErrorSeverity.ValueString(), client, host)
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("advisories"),
+				"Error reading advisory database",
+				fmt.Sprintf("Could not read the advisory database: %s", err),
+			)
+			return
+		}
+	}
+
+	resp.DataSourceData = data
+	resp.ResourceData = data
 }
 
 func (p *OllamaProvider) Resources(ctx context.Context) []func() resource.Resource {
 	return []func() resource.Resource{
 		NewOllamaModelResource,
+		NewOllamaCustomModelResource,
+		NewOllamaBatchInferenceResource,
+		NewOllamaEmbeddingEvaluationResource,
+		NewOllamaModelfileDirectoryResource,
+		NewOllamaGGUFExportResource,
 	}
 }
 
 func (p *OllamaProvider) DataSources(ctx context.Context) []func() datasource.DataSource {
 	return []func() datasource.DataSource{
 		NewOllamaModelDataSource,
+		NewOllamaDeterminismCheckDataSource,
+		NewOllamaSecurityScanDataSource,
+		NewOllamaCatalogDataSource,
+		NewOllamaHostsDataSource,
 	}
 }
 
@@ -128,6 +470,61 @@ func (p *OllamaProvider) Functions(ctx context.Context) []func() function.Functi
 	return []func() function.Function{}
 }
 
+// NewClient creates an Ollama API client for host and returns the base URL it
+// resolved host to, for requests made outside the api package.
+//
+// Unlike api.ClientFromEnvironment, a path in host is kept, so Ollama can be
+// served below a prefix such as https://ai.example.com/ollama/ behind a
+// reverse proxy. Every API path, including blob uploads and streaming
+// endpoints, is joined onto the base URL.
+//
+// Requests go through keepAliveTransport, so keep_alive values set on
+// requests reach the server.
+func NewClient(host string) (*api.Client, *url.URL, error) {
+	base, err := parseHost(host)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return api.NewClient(base, keepAliveClient), base, nil
+}
+
+// parseHost parses host like api.GetOllamaHost does, defaulting to http,
+// 127.0.0.1 and port 11434 when no scheme is given, but keeps its path.
+func parseHost(host string) (*url.URL, error) {
+	host = strings.TrimSpace(strings.Trim(strings.TrimSpace(host), "\"'"))
+
+	if !strings.Contains(host, "://") {
+		host = "http://" + host
+		if u, err := url.Parse(host); err == nil {
+			hostname, port := u.Hostname(), u.Port()
+			if hostname == "" {
+				hostname = "127.0.0.1"
+			}
+			if port == "" {
+				port = "11434"
+			}
+			u.Host = net.JoinHostPort(hostname, port)
+			host = u.String()
+		}
+	}
+
+	base, err := url.Parse(host)
+	if err != nil {
+		return nil, err
+	}
+	if base.Hostname() == "" {
+		return nil, fmt.Errorf("invalid ollama host %q: missing hostname", host)
+	}
+
+	base.Path = strings.TrimRight(base.Path, "/")
+	base.RawPath = ""
+	base.RawQuery = ""
+	base.Fragment = ""
+
+	return base, nil
+}
+
 func New(version string) func() provider.Provider {
 	return func() provider.Provider {
 		return &OllamaProvider{
@@ -0,0 +1,71 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestParseHost(t *testing.T) {
+	tests := map[string]struct {
+		host    string
+		want    string
+		wantErr bool
+	}{
+		"hostname":            {host: "gpu-1", want: "http://gpu-1:11434"},
+		"hostname and port":   {host: "gpu-1:8080", want: "http://gpu-1:8080"},
+		"port only":           {host: ":11434", want: "http://127.0.0.1:11434"},
+		"other port only":     {host: ":8080", want: "http://127.0.0.1:8080"},
+		"empty":               {host: "", want: "http://127.0.0.1:11434"},
+		"ipv4":                {host: "10.0.0.5", want: "http://10.0.0.5:11434"},
+		"ipv6":                {host: "[::1]", want: "http://[::1]:11434"},
+		"ipv6 and port":       {host: "[::1]:8080", want: "http://[::1]:8080"},
+		"quoted":              {host: ` "gpu-1:8080" `, want: "http://gpu-1:8080"},
+		"http scheme":         {host: "http://gpu-1", want: "http://gpu-1"},
+		"https scheme":        {host: "https://ollama.example.com", want: "https://ollama.example.com"},
+		"path prefix":         {host: "https://proxy.example.com/ollama/", want: "https://proxy.example.com/ollama"},
+		"path without scheme": {host: "proxy:8080/ollama", want: "http://proxy:8080/ollama"},
+		"query is dropped":    {host: "https://proxy.example.com/ollama?x=1#y", want: "https://proxy.example.com/ollama"},
+		"scheme without host": {host: "http://:11434", wantErr: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := parseHost(tt.host)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseHost(%q) error = %v, wantErr %v", tt.host, err, tt.wantErr)
+			}
+			if err == nil && got.String() != tt.want {
+				t.Errorf("parseHost(%q) = %s, want %s", tt.host, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigureInvalidHost(t *testing.T) {
+	ctx := context.Background()
+	p := New("test")()
+
+	var schemaResp provider.SchemaResponse
+	p.Schema(ctx, provider.SchemaRequest{}, &schemaResp)
+
+	// tfsdk.Config cannot be set from a struct, so go through a state
+	raw := tfsdk.State{Schema: schemaResp.Schema}
+	if diags := raw.Set(ctx, &OllamaProviderModel{Host: types.StringValue("http://:11434")}); diags.HasError() {
+		t.Fatal(diags)
+	}
+
+	var resp provider.ConfigureResponse
+	p.Configure(ctx, provider.ConfigureRequest{Config: tfsdk.Config{Schema: schemaResp.Schema, Raw: raw.Raw}}, &resp)
+
+	if !resp.Diagnostics.HasError() {
+		t.Fatal("Configure() succeeded with a host without hostname")
+	}
+	if detail := resp.Diagnostics.Errors()[0].Detail(); !strings.Contains(detail, "missing hostname") {
+		t.Errorf("Configure() error detail = %q, want the parse error", detail)
+	}
+}
@@ -0,0 +1,147 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+)
+
+// retrievalDataset maps document IDs to their text and lists queries with the
+// IDs of the documents relevant to them.
+type retrievalDataset struct {
+	Documents map[string]string `json:"documents"`
+	Queries   []retrievalQuery  `json:"queries"`
+}
+
+type retrievalQuery struct {
+	Query    string   `json:"query"`
+	Relevant []string `json:"relevant"`
+}
+
+// retrievalMetrics are averaged over all queries of a dataset.
+type retrievalMetrics struct {
+	RecallAtK float64
+	MRR       float64
+	NDCG      float64
+}
+
+func readRetrievalDataset(path string) (*retrievalDataset, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var ds retrievalDataset
+	if err := json.Unmarshal(data, &ds); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if len(ds.Documents) == 0 || len(ds.Queries) == 0 {
+		return nil, fmt.Errorf("%s: dataset needs at least one document and one query", path)
+	}
+	for i, q := range ds.Queries {
+		if len(q.Relevant) == 0 {
+			return nil, fmt.Errorf("%s: query %d has no relevant documents", path, i)
+		}
+		for _, id := range q.Relevant {
+			if _, ok := ds.Documents[id]; !ok {
+				return nil, fmt.Errorf("%s: query %d references unknown document %q", path, i, id)
+			}
+		}
+	}
+
+	return &ds, nil
+}
+
+// evaluateRetrieval ranks all documents by cosine similarity to each query
+// and computes recall@k, MRR and nDCG@k with binary relevance.
+func evaluateRetrieval(ctx context.Context, e *embedder, ds *retrievalDataset, k int) (retrievalMetrics, error) {
+	ids := make([]string, 0, len(ds.Documents))
+	for id := range ds.Documents {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	texts := make([]string, 0, len(ids)+len(ds.Queries))
+	for _, id := range ids {
+		texts = append(texts, ds.Documents[id])
+	}
+	for _, q := range ds.Queries {
+		texts = append(texts, q.Query)
+	}
+
+	vecs, err := e.Embed(ctx, texts)
+	if err != nil {
+		return retrievalMetrics{}, err
+	}
+	docVecs, queryVecs := vecs[:len(ids)], vecs[len(ids):]
+
+	var m retrievalMetrics
+	for qi, q := range ds.Queries {
+		relevant := make(map[string]bool, len(q.Relevant))
+		for _, id := range q.Relevant {
+			relevant[id] = true
+		}
+
+		scores := make([]float64, len(ids))
+		ranking := make([]int, len(ids))
+		for i := range ids {
+			scores[i] = cosineSimilarity(queryVecs[qi], docVecs[i])
+			ranking[i] = i
+		}
+		sort.SliceStable(ranking, func(a, b int) bool { return scores[ranking[a]] > scores[ranking[b]] })
+
+		ranked := make([]string, len(ranking))
+		for rank, i := range ranking {
+			ranked[rank] = ids[i]
+		}
+
+		recall, reciprocal, ndcg := scoreRanking(ranked, relevant, k)
+		m.RecallAtK += recall
+		m.MRR += reciprocal
+		m.NDCG += ndcg
+	}
+
+	n := float64(len(ds.Queries))
+	m.RecallAtK /= n
+	m.MRR /= n
+	m.NDCG /= n
+
+	return m, nil
+}
+
+// scoreRanking returns recall@k, the reciprocal rank of the first relevant
+// document and nDCG@k of ranked, a list of document IDs ordered by score.
+// Without relevant documents all three are 0.
+func scoreRanking(ranked []string, relevant map[string]bool, k int) (recall, reciprocal, ndcg float64) {
+	var hits int
+	var dcg float64
+	for rank, id := range ranked {
+		if !relevant[id] {
+			continue
+		}
+		if reciprocal == 0 {
+			reciprocal = 1 / float64(rank+1)
+		}
+		if rank < k {
+			hits++
+			dcg += 1 / math.Log2(float64(rank+2))
+		}
+	}
+
+	var idcg float64
+	for rank := 0; rank < min(len(relevant), k); rank++ {
+		idcg += 1 / math.Log2(float64(rank+2))
+	}
+
+	if len(relevant) > 0 {
+		recall = float64(hits) / float64(len(relevant))
+	}
+	if idcg > 0 {
+		ndcg = dcg / idcg
+	}
+	return recall, reciprocal, ndcg
+}
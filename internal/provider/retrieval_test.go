@@ -0,0 +1,150 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ollama/ollama/api"
+)
+
+func TestScoreRanking(t *testing.T) {
+	// discounted gain of the first ranks, 1/log2(rank+1)
+	g1, g2, g3 := 1.0, 1/math.Log2(3), 1/math.Log2(4)
+
+	tests := map[string]struct {
+		ranked   []string
+		relevant []string
+		k        int
+
+		wantRecall, wantReciprocal, wantNDCG float64
+	}{
+		"perfect ranking": {
+			ranked:         []string{"a", "b", "c", "d"},
+			relevant:       []string{"a", "b"},
+			k:              2,
+			wantRecall:     1,
+			wantReciprocal: 1,
+			wantNDCG:       1,
+		},
+		"relevant document second": {
+			ranked:         []string{"x", "a", "y"},
+			relevant:       []string{"a"},
+			k:              3,
+			wantRecall:     1,
+			wantReciprocal: 0.5,
+			wantNDCG:       g2 / g1,
+		},
+		"relevant document beyond k": {
+			ranked:         []string{"x", "y", "a"},
+			relevant:       []string{"a"},
+			k:              2,
+			wantRecall:     0,
+			wantReciprocal: 1.0 / 3,
+			wantNDCG:       0,
+		},
+		"half found": {
+			ranked:         []string{"a", "x", "y", "b"},
+			relevant:       []string{"a", "b"},
+			k:              3,
+			wantRecall:     0.5,
+			wantReciprocal: 1,
+			wantNDCG:       g1 / (g1 + g2),
+		},
+		"more relevant documents than k": {
+			ranked:         []string{"x", "a", "b", "c"},
+			relevant:       []string{"a", "b", "c"},
+			k:              3,
+			wantRecall:     2.0 / 3,
+			wantReciprocal: 0.5,
+			wantNDCG:       (g2 + g3) / (g1 + g2 + g3),
+		},
+		"k of zero": {
+			ranked:         []string{"a"},
+			relevant:       []string{"a"},
+			k:              0,
+			wantRecall:     0,
+			wantReciprocal: 1,
+			wantNDCG:       0,
+		},
+		"no relevant documents": {
+			ranked: []string{"a", "b"},
+			k:      2,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			relevant := map[string]bool{}
+			for _, id := range tt.relevant {
+				relevant[id] = true
+			}
+
+			recall, reciprocal, ndcg := scoreRanking(tt.ranked, relevant, tt.k)
+			for _, c := range []struct {
+				name      string
+				got, want float64
+			}{
+				{"recall", recall, tt.wantRecall},
+				{"reciprocal rank", reciprocal, tt.wantReciprocal},
+				{"nDCG", ndcg, tt.wantNDCG},
+			} {
+				if math.IsNaN(c.got) || math.Abs(c.got-c.want) > 1e-9 {
+					t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+				}
+			}
+		})
+	}
+}
+
+func TestEvaluateRetrieval(t *testing.T) {
+	vectors := map[string][]float64{
+		"cats":             {1, 0, 0},
+		"dogs":             {0, 1, 0},
+		"fish":             {0, 0, 1},
+		"feline pets":      {0.9, 0.1, 0},
+		"pets that bark":   {0.1, 0.9, 0},
+		"aquarium animals": {0, 0.6, 0.4}, // ranks dogs above fish
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req api.EmbeddingRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(api.EmbeddingResponse{Embedding: vectors[req.Prompt]})
+	}))
+	defer srv.Close()
+
+	client, _, err := NewClient(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := &embedder{
+		pool:  &hostPool{hosts: []poolHost{{name: srv.URL, client: client}}, concurrency: 2},
+		model: "nomic-embed-text",
+	}
+
+	ds := &retrievalDataset{
+		Documents: map[string]string{"1": "cats", "2": "dogs", "3": "fish"},
+		Queries: []retrievalQuery{
+			{Query: "feline pets", Relevant: []string{"1"}},
+			{Query: "pets that bark", Relevant: []string{"2"}},
+			{Query: "aquarium animals", Relevant: []string{"3"}},
+		},
+	}
+
+	got, err := evaluateRetrieval(context.Background(), e, ds, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := retrievalMetrics{RecallAtK: 2.0 / 3, MRR: (1 + 1 + 0.5) / 3, NDCG: 2.0 / 3}
+	if math.Abs(got.RecallAtK-want.RecallAtK) > 1e-9 || math.Abs(got.MRR-want.MRR) > 1e-9 || math.Abs(got.NDCG-want.NDCG) > 1e-9 {
+		t.Errorf("evaluateRetrieval() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,452 @@
+package provider
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	pathpkg "path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Scan finding severities, in increasing order.
+const (
+	severityLow      = "low"
+	severityMedium   = "medium"
+	severityHigh     = "high"
+	severityCritical = "critical"
+
+	defaultScanThreshold = severityHigh
+
+	// scanMaxSafetensorsHeader bounds the JSON header read from a safetensors file.
+	scanMaxSafetensorsHeader = 100 << 20
+)
+
+var severityRank = map[string]int{
+	severityLow:      1,
+	severityMedium:   2,
+	severityHigh:     3,
+	severityCritical: 4,
+}
+
+// defaultMetadataAllowlist are the metadata keys expected in GGUF and
+// safetensors files. "<architecture>.*" is added for GGUF files.
+var defaultMetadataAllowlist = []string{
+	"general.*",
+	"tokenizer.*",
+	"split.*",
+	"quantize.*",
+	"format",
+}
+
+// scanFinding is a single result of a security scan.
+type scanFinding struct {
+	Severity string
+	Rule     string
+	Location string
+	Message  string
+}
+
+// scanPolicy configures a security scan.
+type scanPolicy struct {
+	// Threshold is the lowest severity that fails the scan.
+	Threshold string
+	// Allowlist holds pathpkg.Match patterns of allowed metadata keys. Nil
+	// means defaultMetadataAllowlist.
+	Allowlist []string
+}
+
+// Blocks reports whether f is at or above the threshold of the policy.
+func (p scanPolicy) Blocks(f scanFinding) bool {
+	threshold := p.Threshold
+	if threshold == "" {
+		threshold = defaultScanThreshold
+	}
+	return severityRank[f.Severity] >= severityRank[threshold]
+}
+
+// maxSeverity returns the highest severity among findings, or "" if there are none.
+func maxSeverity(findings []scanFinding) string {
+	var s string
+	for _, f := range findings {
+		if severityRank[f.Severity] > severityRank[s] {
+			s = f.Severity
+		}
+	}
+	return s
+}
+
+// templateRules are checked against prompt templates, both Go templates as
+// used by Ollama and Jinja chat templates embedded in GGUF metadata.
+var templateRules = []struct {
+	severity string
+	rule     string
+	pattern  *regexp.Regexp
+	message  string
+}{
+	{severityCritical, "jinja-sandbox-escape", regexp.MustCompile(`__(class|globals|builtins|subclasses|mro|import|init|base)__`), "accesses Python internals, a known sandbox escape for Jinja templates"},
+	{severityCritical, "code-execution", regexp.MustCompile(`\b(os\.system|os\.popen|subprocess\.\w+|eval|exec|__import__)\s*\(`), "references code execution"},
+	{severityCritical, "hidden-characters", regexp.MustCompile(`[\x{200B}-\x{200F}\x{202A}-\x{202E}\x{2060}-\x{2064}\x{2066}-\x{2069}\x{FEFF}]`), "contains invisible or bidirectional control characters that can hide instructions"},
+	{severityHigh, "prompt-injection", regexp.MustCompile(`(?i)(ignore|disregard|forget)\s+(all\s+)?(previous|prior|above)\s+(instructions|messages|rules)`), "contains an instruction override phrase"},
+	{severityHigh, "exfiltration", regexp.MustCompile(`(?i)!\[[^\]]*\]\(https?://|<img[^>]+src=["']?https?://`), "renders a remote image, which can leak conversation content through the URL"},
+	{severityMedium, "url", regexp.MustCompile(`(?i)https?://[^\s"'<>]+`), "contains a URL"},
+	{severityLow, "template-definition", regexp.MustCompile(`\{\{-?\s*(define|template|block)\b`), "defines or includes sub-templates"},
+}
+
+// scanTemplate checks a prompt template for suspicious constructs.
+func scanTemplate(location, tmpl string) []scanFinding {
+	var findings []scanFinding
+	for _, r := range templateRules {
+		if m := r.pattern.FindString(tmpl); m != "" {
+			findings = append(findings, scanFinding{
+				Severity: r.severity,
+				Rule:     r.rule,
+				Location: location,
+				Message:  fmt.Sprintf("Template %s: %q", r.message, m),
+			})
+		}
+	}
+	return findings
+}
+
+// scanMetadata flags metadata keys that match none of the allowlist patterns.
+func scanMetadata(location string, meta map[string]any, allowlist []string) []scanFinding {
+	keys := make([]string, 0, len(meta))
+	for k := range meta {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var findings []scanFinding
+	for _, k := range keys {
+		if !matchesAny(allowlist, k) {
+			findings = append(findings, scanFinding{
+				Severity: severityMedium,
+				Rule:     "unexpected-metadata",
+				Location: location,
+				Message:  fmt.Sprintf("Metadata key %q is not in the allowlist.", k),
+			})
+		}
+	}
+	return findings
+}
+
+func matchesAny(patterns []string, s string) bool {
+	for _, p := range patterns {
+		if ok, _ := pathpkg.Match(p, s); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// scanGGUF checks the metadata and the embedded chat template of a GGUF file.
+func scanGGUF(path string, policy scanPolicy) ([]scanFinding, error) {
+	meta, err := readGGUFMetadata(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %w", path, err)
+	}
+
+	allowlist := policy.Allowlist
+	if allowlist == nil {
+		allowlist = defaultMetadataAllowlist
+		if arch, ok := meta["general.architecture"].(string); ok {
+			allowlist = append([]string{arch + ".*"}, allowlist...)
+		}
+	}
+
+	keys := make([]string, 0, len(meta))
+	for k := range meta {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	findings := scanMetadata(path, meta, allowlist)
+	for _, k := range keys {
+		if s, ok := meta[k].(string); ok && strings.HasPrefix(k, "tokenizer.chat_template") {
+			findings = append(findings, scanTemplate(path+":"+k, s)...)
+		}
+	}
+
+	return findings, nil
+}
+
+// safetensorsFileSeverity rates the files expected next to safetensors
+// weights. Files not listed are reported as low.
+var safetensorsFileSeverity = map[string]string{
+	".safetensors":   "",
+	".json":          "",
+	".model":         "",
+	".txt":           "",
+	".md":            "",
+	".tiktoken":      "",
+	".gitattributes": "",
+
+	// pickle based formats execute code on load
+	".bin":  severityHigh,
+	".pt":   severityHigh,
+	".pth":  severityHigh,
+	".pkl":  severityHigh,
+	".ckpt": severityHigh,
+
+	// code shipped for trust_remote_code
+	".py": severityHigh,
+
+	".so":    severityCritical,
+	".dll":   severityCritical,
+	".dylib": severityCritical,
+	".exe":   severityCritical,
+	".sh":    severityCritical,
+}
+
+// scanSafetensorsDir checks the file types in a safetensors model directory,
+// the metadata of each safetensors file and templates in tokenizer_config.json.
+func scanSafetensorsDir(dir string, policy scanPolicy) ([]scanFinding, error) {
+	allowlist := policy.Allowlist
+	if allowlist == nil {
+		allowlist = defaultMetadataAllowlist
+	}
+
+	var findings []scanFinding
+	err := filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		ext := strings.ToLower(filepath.Ext(d.Name()))
+
+		if severity, ok := safetensorsFileSeverity[ext]; !ok {
+			findings = append(findings, scanFinding{severityLow, "unexpected-file", p, "Unexpected file type in model directory."})
+		} else if severity != "" {
+			findings = append(findings, scanFinding{severity, "unsafe-file", p, fmt.Sprintf("Files of type %s can execute code when loaded.", ext)})
+		}
+
+		switch {
+		case ext == ".safetensors":
+			meta, err := readSafetensorsMetadata(p)
+			if err != nil {
+				findings = append(findings, scanFinding{severityHigh, "invalid-safetensors", p, err.Error()})
+				return nil
+			}
+			findings = append(findings, scanMetadata(p, meta, allowlist)...)
+		case d.Name() == "tokenizer_config.json":
+			tmpl, err := readChatTemplate(p)
+			if err != nil {
+				findings = append(findings, scanFinding{severityMedium, "invalid-tokenizer-config", p, err.Error()})
+				return nil
+			}
+			findings = append(findings, scanTemplate(p+":chat_template", tmpl)...)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return findings, nil
+}
+
+// readSafetensorsMetadata returns the __metadata__ map of the JSON header
+// that starts every safetensors file.
+func readSafetensorsMetadata(path string) (map[string]any, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var n uint64
+	if err := binary.Read(f, binary.LittleEndian, &n); err != nil {
+		return nil, fmt.Errorf("could not read safetensors header: %w", err)
+	}
+	if n > scanMaxSafetensorsHeader {
+		return nil, fmt.Errorf("safetensors header of %d bytes exceeds limit", n)
+	}
+
+	var header struct {
+		Metadata map[string]any `json:"__metadata__"`
+	}
+	if err := json.NewDecoder(io.LimitReader(f, int64(n))).Decode(&header); err != nil {
+		return nil, fmt.Errorf("could not parse safetensors header: %w", err)
+	}
+
+	return header.Metadata, nil
+}
+
+// readChatTemplate returns the chat templates of a tokenizer_config.json,
+// which is either a string or a list of named templates.
+func readChatTemplate(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	var config struct {
+		ChatTemplate json.RawMessage `json:"chat_template"`
+	}
+	if err := json.Unmarshal(b, &config); err != nil || len(config.ChatTemplate) == 0 {
+		return "", err
+	}
+
+	var tmpl string
+	if err := json.Unmarshal(config.ChatTemplate, &tmpl); err == nil {
+		return tmpl, nil
+	}
+
+	var named []struct {
+		Template string `json:"template"`
+	}
+	if err := json.Unmarshal(config.ChatTemplate, &named); err != nil {
+		return "", fmt.Errorf("could not parse chat_template: %w", err)
+	}
+	var templates []string
+	for _, t := range named {
+		templates = append(templates, t.Template)
+	}
+	return strings.Join(templates, "\n"), nil
+}
+
+// scanPath scans a GGUF file or a safetensors directory.
+func scanPath(path string, policy scanPolicy) ([]scanFinding, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return scanSafetensorsDir(path, policy)
+	}
+	return scanGGUF(path, policy)
+}
+
+// scanModelfile scans the local FROM and ADAPTER files or directories of mf,
+// with relative paths resolved against dir, and its template. Like Ollama, an
+// argument is a local path whenever the resolved path exists, so "FROM
+// model.gguf" is scanned as well as "FROM ./model.gguf". Paths starting with
+// "." or "/" must exist.
+func scanModelfile(mf *modelfile, dir string, policy scanPolicy) ([]scanFinding, error) {
+	var findings []scanFinding
+	for _, p := range append([]string{mf.From}, mf.Adapters...) {
+		if p == "" || strings.HasPrefix(p, "@") {
+			continue
+		}
+		resolved := resolveModelPath(p, dir)
+		explicit := filepath.IsAbs(p) || strings.HasPrefix(p, ".")
+		if _, err := os.Stat(resolved); err != nil && !explicit {
+			// a model name such as llama3
+			continue
+		}
+		f, err := scanPath(resolved, policy)
+		if err != nil {
+			return nil, err
+		}
+		findings = append(findings, f...)
+	}
+
+	if mf.Template != "" {
+		findings = append(findings, scanTemplate("TEMPLATE", mf.Template)...)
+	}
+	if mf.System != "" {
+		findings = append(findings, scanTemplate("SYSTEM", mf.System)...)
+	}
+
+	return findings, nil
+}
+
+// securityScanAttribute is the security_scan attribute of resources that
+// create models from local files.
+func securityScanAttribute() schema.SingleNestedAttribute {
+	return schema.SingleNestedAttribute{
+		Description: "Scan local model files and templates before the model is created, and block creation on findings at or above the threshold. " +
+			"Findings below the threshold are reported as warnings.",
+		Optional: true,
+		Attributes: map[string]schema.Attribute{
+			"severity_threshold": schema.StringAttribute{
+				Description: "The lowest severity that blocks creation, one of `low`, `medium`, `high` or `critical`. Defaults to `high`.",
+				Optional:    true,
+			},
+			"metadata_allowlist": schema.ListAttribute{
+				Description: "Glob patterns of allowed metadata keys, replacing the defaults.",
+				Optional:    true,
+				ElementType: types.StringType,
+			},
+		},
+	}
+}
+
+// newScanPolicy converts the configured policy. A nil config gives the defaults.
+func newScanPolicy(config *OllamaSecurityScanPolicy) (scanPolicy, error) {
+	policy := scanPolicy{Threshold: defaultScanThreshold}
+	if config == nil {
+		return policy, nil
+	}
+
+	if !config.SeverityThreshold.IsNull() {
+		policy.Threshold = config.SeverityThreshold.ValueString()
+		if _, ok := severityRank[policy.Threshold]; !ok {
+			return policy, fmt.Errorf("severity_threshold must be one of %q, %q, %q or %q, got %q",
+				severityLow, severityMedium, severityHigh, severityCritical, policy.Threshold)
+		}
+	}
+
+	if config.MetadataAllowlist != nil {
+		policy.Allowlist = []string{}
+		for _, p := range config.MetadataAllowlist {
+			if _, err := pathpkg.Match(p.ValueString(), ""); err != nil {
+				return policy, fmt.Errorf("invalid metadata_allowlist pattern %q: %w", p.ValueString(), err)
+			}
+			policy.Allowlist = append(policy.Allowlist, p.ValueString())
+		}
+	}
+
+	return policy, nil
+}
+
+// reportScanFindings adds an error for every finding the policy blocks on and
+// a warning for the others.
+func reportScanFindings(findings []scanFinding, policy scanPolicy, diags *diag.Diagnostics) {
+	for _, f := range findings {
+		summary := fmt.Sprintf("Security scan: %s (%s)", f.Rule, f.Severity)
+		detail := fmt.Sprintf("%s\n\nLocation: %s", f.Message, f.Location)
+		if policy.Blocks(f) {
+			diags.AddError(summary, detail+fmt.Sprintf("\n\nFindings of severity %s or higher fail the scan.", policy.Threshold))
+		} else {
+			diags.AddWarning(summary, detail)
+		}
+	}
+}
+
+// scanBeforeCreate scans mf according to config and reports the findings.
+func scanBeforeCreate(mf *modelfile, dir string, config *OllamaSecurityScanPolicy) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	policy, err := newScanPolicy(config)
+	if err != nil {
+		diags.AddAttributeError(path.Root("security_scan"), "Invalid scan policy", err.Error())
+		return diags
+	}
+
+	findings, err := scanModelfile(mf, dir, policy)
+	if err != nil {
+		diags.AddError("Error scanning model files", err.Error())
+		return diags
+	}
+
+	reportScanFindings(findings, policy, &diags)
+	return diags
+}
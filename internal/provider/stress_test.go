@@ -0,0 +1,551 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// checkGoroutines fails t if more goroutines are running once the test is
+// done than before it started. Goroutines of closed connections and servers
+// take a moment to exit, so it waits for them to settle.
+func checkGoroutines(t *testing.T) {
+	t.Helper()
+
+	before := runtime.NumGoroutine()
+	t.Cleanup(func() {
+		keepAliveClient.CloseIdleConnections()
+
+		var after int
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+			if after = runtime.NumGoroutine(); after <= before {
+				return
+			}
+		}
+
+		buf := make([]byte, 1<<20)
+		buf = buf[:runtime.Stack(buf, true)]
+		t.Errorf("%d goroutines leaked:\n%s", after-before, buf)
+	})
+}
+
+func TestStressModelResources(t *testing.T) {
+	if testing.Short() {
+		t.Skip("stress test")
+	}
+	checkGoroutines(t)
+
+	const workers = 32
+
+	f, srv, client := newFakeOllama(t)
+	// registered after checkGoroutines, so it runs before the check
+	t.Cleanup(srv.Close)
+
+	lockBackend, err := newFileLockBackend(t.TempDir(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lock, err := newHostLock(lockBackend, srv.URL, "stress", time.Minute, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &ollamaModelResource{
+		client: client,
+		budget: newInferenceBudget(0, 0, 0),
+		lock:   lock,
+	}
+	d := &OllamaModelDataSource{client: client}
+
+	f.AddModel("preexisting")
+
+	// worker i creates model-i, reads and updates it in place with
+	// verify_load, then renames every second model and deletes every
+	// third one.
+	want := map[string]bool{"preexisting:latest": true}
+	for i := 0; i < workers; i++ {
+		name := fmt.Sprintf("model-%d", i)
+		if i%2 == 1 {
+			name = fmt.Sprintf("renamed-%d", i)
+		}
+		if i%3 != 2 {
+			want[name+":latest"] = true
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var state OllamaModelResource
+			if diags := applyResource(t, r, nil, testModel(fmt.Sprintf("model-%d", i)), &state); diags.HasError() {
+				t.Errorf("create model-%d: %v", i, diags)
+				return
+			}
+
+			if exists, diags := readResource(t, r, &state, &state); !exists || diags.HasError() {
+				t.Errorf("read model-%d: exists %v, %v", i, exists, diags)
+				return
+			}
+
+			plan := testModel(state.Name.ValueString())
+			plan.VerifyLoad = types.BoolValue(true)
+			if diags := applyResource(t, r, &state, plan, &state); diags.HasError() {
+				t.Errorf("update model-%d: %v", i, diags)
+				return
+			}
+
+			if i%2 == 1 {
+				if diags := applyResource(t, r, &state, testModel(fmt.Sprintf("renamed-%d", i)), &state); diags.HasError() {
+					t.Errorf("rename model-%d: %v", i, diags)
+					return
+				}
+			}
+
+			if i%3 == 2 {
+				if diags := applyResource[OllamaModelResource](t, r, &state, nil, nil); diags.HasError() {
+					t.Errorf("delete %s: %v", state.Name.ValueString(), diags)
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < workers/2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			config := OllamaModelDataSourceModel{ClassifyLicenses: types.BoolValue(i%2 == 0)}
+			var data OllamaModelDataSourceModel
+			if diags := readDataSource(t, d, &config, &data); diags.HasError() {
+				t.Errorf("read data source: %v", diags)
+				return
+			}
+			for _, m := range data.Models {
+				if m.Digest.IsNull() || m.Digest.ValueString() == "" {
+					t.Errorf("data source returned model %s without digest", m.Name.ValueString())
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	got := f.Models()
+	if len(got) != len(want) {
+		t.Errorf("inventory = %v, want %d models", got, len(want))
+	}
+	for _, name := range got {
+		if !want[name] {
+			t.Errorf("unexpected model %s in inventory", name)
+		}
+	}
+
+	if current, err := lockBackend.Current(context.Background()); err != nil || current != nil {
+		t.Errorf("lock held by %v, %v after all operations finished", current, err)
+	}
+	if r.budget.requests != workers {
+		t.Errorf("budget counted %d requests, want one verify_load per model (%d)", r.budget.requests, workers)
+	}
+}
+
+func TestStressResponseCache(t *testing.T) {
+	if testing.Short() {
+		t.Skip("stress test")
+	}
+	checkGoroutines(t)
+
+	const (
+		workers = 16
+		keys    = 64
+		rounds  = 50
+		maxSize = 4 << 10
+	)
+
+	// two provider instances sharing one directory
+	dir := t.TempDir()
+	caches := make([]*responseCache, 2)
+	for i := range caches {
+		c, err := newResponseCache(dir, time.Hour, maxSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		caches[i] = c
+	}
+
+	value := func(k int) string { return strings.Repeat(fmt.Sprint(k), 100) }
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			c := caches[w%len(caches)]
+			for n := 0; n < rounds; n++ {
+				k := (w*rounds + n) % keys
+				key := testCacheKey(t, fmt.Sprint(k))
+
+				var got string
+				hit, err := c.Get(key, &got)
+				if err != nil {
+					t.Errorf("Get(%d) = %v", k, err)
+					return
+				}
+				if hit && got != value(k) {
+					t.Errorf("Get(%d) = %q, want %q", k, got, value(k))
+					return
+				}
+
+				if err := c.Put(key, value(k)); err != nil {
+					t.Errorf("Put(%d) = %v", k, err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	var total int64
+	err := filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if strings.HasPrefix(d.Name(), ".tmp-") {
+			t.Errorf("temporary file %s left behind", p)
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		total += info.Size()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// each instance evicts down to maxSize, but only counts the entries
+	// of the other one on its next walk
+	if total > 2*maxSize {
+		t.Errorf("cache holds %d bytes, want at most %d", total, 2*maxSize)
+	}
+	for _, c := range caches {
+		if err := c.evict(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, size, err := caches[0].entries(); err != nil || size > maxSize {
+		t.Errorf("cache holds %d bytes after eviction, %v, want at most %d", size, err, maxSize)
+	}
+}
+
+// newStressPool returns a pool of n fake hosts serving llama3 and
+// nomic-embed-text, of which the last one fails every generate request.
+func newStressPool(t *testing.T, n, concurrency int) (*hostPool, []*fakeOllama) {
+	t.Helper()
+
+	fakes := make([]*fakeOllama, n)
+	urls := make([]string, n)
+	for i := range fakes {
+		f, srv, _ := newFakeOllama(t)
+		// registered after checkGoroutines, so it runs before the check
+		t.Cleanup(srv.Close)
+		f.AddModel("llama3")
+		f.AddModel("nomic-embed-text")
+		fakes[i], urls[i] = f, srv.URL
+	}
+	fakes[n-1].FailLoad = true
+
+	hosts, diags := types.ListValueFrom(context.Background(), types.StringType, urls)
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	pool, diags := newHostPool(context.Background(), hosts, nil, "", types.Int64Value(int64(concurrency)))
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	return pool, fakes
+}
+
+func TestStressBatchPool(t *testing.T) {
+	if testing.Short() {
+		t.Skip("stress test")
+	}
+	checkGoroutines(t)
+
+	const (
+		jobs = 4
+		rows = 100
+	)
+
+	pool, fakes := newStressPool(t, 3, 4)
+	cache, err := newResponseCache(t.TempDir(), time.Hour, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	budget := newInferenceBudget(0, 0, 0)
+
+	// jobs with overlapping prompts share the pool, the cache and the budget
+	var wg sync.WaitGroup
+	for j := 0; j < jobs; j++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			job := &batchJob{
+				pool:       pool,
+				cache:      cache,
+				budget:     budget,
+				model:      "llama3",
+				digest:     "sha256:1111",
+				mode:       batchModeGenerate,
+				checkpoint: filepath.Join(t.TempDir(), "checkpoint.jsonl"),
+			}
+			input, prompts := testBatchRows(rows)
+			for i := range prompts {
+				prompts[i] = fmt.Sprintf("question %d", (i+j*rows/2)%(2*rows))
+			}
+
+			results, err := job.Run(context.Background(), input, prompts)
+			if err != nil {
+				t.Errorf("job %d: %v", j, err)
+				return
+			}
+			for i, r := range results {
+				if r.Index != i || r.Output != "re: "+prompts[i] {
+					t.Errorf("job %d result %d = %d %q, want %d %q", j, i, r.Index, r.Output, i, "re: "+prompts[i])
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	var calls int64
+	for _, f := range fakes {
+		calls += int64(f.Calls("/api/generate"))
+	}
+	if budget.requests != calls {
+		t.Errorf("budget counted %d requests, the hosts got %d", budget.requests, calls)
+	}
+	if fakes[len(fakes)-1].Calls("/api/generate") == 0 {
+		t.Error("the failing host got no requests, nothing was retried")
+	}
+}
+
+func TestStressEmbeddingPool(t *testing.T) {
+	if testing.Short() {
+		t.Skip("stress test")
+	}
+	checkGoroutines(t)
+
+	const (
+		workers = 8
+		texts   = 64
+	)
+
+	pool, fakes := newStressPool(t, 3, defaultEmbeddingConcurrency)
+	cache, err := newResponseCache(t.TempDir(), time.Hour, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := &embedder{pool: pool, cache: cache, budget: newInferenceBudget(0, 0, 0), model: "nomic-embed-text", digest: "sha256:1234"}
+
+	input := make([]string, texts)
+	for i := range input {
+		input[i] = fmt.Sprintf("text %d", i)
+	}
+	want, err := e.Embed(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			// the same texts in another order, mostly from the cache
+			shifted := append(slices.Clone(input[w:]), input[:w]...)
+			got, err := e.Embed(context.Background(), shifted)
+			if err != nil {
+				t.Errorf("worker %d: %v", w, err)
+				return
+			}
+			for i := range shifted {
+				if !slices.Equal(got[i], want[(i+w)%texts]) {
+					t.Errorf("worker %d: embedding of %q = %v, want %v", w, shifted[i], got[i], want[(i+w)%texts])
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	var calls int
+	for _, f := range fakes {
+		calls += f.Calls("/api/embeddings")
+	}
+	if calls < texts {
+		t.Errorf("hosts embedded %d texts, want at least %d", calls, texts)
+	}
+	if e.budget.requests != int64(calls) {
+		t.Errorf("budget counted %d requests, the hosts got %d", e.budget.requests, calls)
+	}
+}
+
+func TestStressSharedBudget(t *testing.T) {
+	if testing.Short() {
+		t.Skip("stress test")
+	}
+	checkGoroutines(t)
+
+	const (
+		maxRequests = 150
+		jobs        = 4
+		rows        = 100
+	)
+
+	pool, fakes := newStressPool(t, 3, 4)
+	budget := newInferenceBudget(maxRequests, 0, 0)
+
+	// batch jobs and embedders run out of the same budget at once
+	var wg sync.WaitGroup
+	for j := 0; j < jobs; j++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+
+			job := &batchJob{
+				pool:       pool,
+				budget:     budget,
+				model:      "llama3",
+				digest:     "sha256:1111",
+				mode:       batchModeGenerate,
+				checkpoint: filepath.Join(t.TempDir(), "checkpoint.jsonl"),
+			}
+			input, prompts := testBatchRows(rows)
+			if _, err := job.Run(context.Background(), input, prompts); !isBudgetExceeded(err) {
+				t.Errorf("batch job %d: %v, want the budget to be exceeded", j, err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+
+			e := &embedder{pool: pool, budget: budget, model: "nomic-embed-text", digest: "sha256:1234"}
+			input := make([]string, rows)
+			for i := range input {
+				input[i] = fmt.Sprintf("text %d of %d", i, j)
+			}
+			if _, err := e.Embed(context.Background(), input); !isBudgetExceeded(err) {
+				t.Errorf("embedder %d: %v, want the budget to be exceeded", j, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if budget.requests != maxRequests {
+		t.Errorf("budget counted %d requests, want exactly %d", budget.requests, maxRequests)
+	}
+	var calls int64
+	for _, f := range fakes {
+		calls += int64(f.Calls("/api/generate") + f.Calls("/api/embeddings"))
+	}
+	// requests in flight when a job gives up are cancelled after they were
+	// counted, so the hosts may see fewer
+	if calls > maxRequests {
+		t.Errorf("hosts got %d requests, want at most the %d the budget allowed", calls, maxRequests)
+	}
+}
+
+func isBudgetExceeded(err error) bool {
+	var exceeded *errBudgetExceeded
+	return errors.As(err, &exceeded)
+}
+
+func TestStressHostLock(t *testing.T) {
+	if testing.Short() {
+		t.Skip("stress test")
+	}
+	checkGoroutines(t)
+
+	const (
+		runs       = 4
+		operations = 8
+		rounds     = 5
+	)
+
+	interval := lockPollInterval
+	lockPollInterval = time.Millisecond
+	t.Cleanup(func() { lockPollInterval = interval })
+
+	dir := t.TempDir()
+
+	var (
+		mu     sync.Mutex
+		owner  = -1
+		active int
+	)
+
+	// runs share the lock file, operations of a run share its lock
+	var wg sync.WaitGroup
+	for r := 0; r < runs; r++ {
+		l := newTestFileLock(t, dir, fmt.Sprintf("run %d", r), time.Minute, time.Hour)
+		// refresh the record all the time while other runs read it
+		l.heartbeat = time.Millisecond
+
+		for o := 0; o < operations; o++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				for n := 0; n < rounds; n++ {
+					unlock, err := l.Acquire(context.Background())
+					if err != nil {
+						t.Errorf("run %d: %v", r, err)
+						return
+					}
+
+					mu.Lock()
+					if active > 0 && owner != r {
+						t.Errorf("run %d operates while run %d holds the lock", r, owner)
+					}
+					owner = r
+					active++
+					mu.Unlock()
+
+					time.Sleep(time.Millisecond)
+
+					mu.Lock()
+					active--
+					mu.Unlock()
+
+					unlock()
+				}
+			}()
+		}
+	}
+	wg.Wait()
+
+	b, err := newFileLockBackend(dir, "http://127.0.0.1:11434")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current, err := b.Current(context.Background()); err != nil || current != nil {
+		t.Errorf("lock held by %v, %v after all runs finished", current, err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("files left in the lock directory: %v", entries)
+	}
+}
@@ -7,8 +7,10 @@ import (
 	"context"
 	"flag"
 	"log"
+	"os"
 
 	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-provider-ollama/internal/debugbundle"
 	"github.com/hashicorp/terraform-provider-ollama/internal/provider"
 )
 
@@ -32,6 +34,13 @@ var (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "debug-bundle" {
+		if err := debugbundle.Run(context.Background(), version, os.Args[2:]); err != nil {
+			log.Fatal(err.Error())
+		}
+		return
+	}
+
 	var debug bool
 
 	flag.BoolVar(&debug, "debug", false, "set to true to run the provider with support for debuggers like delve")